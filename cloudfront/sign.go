@@ -0,0 +1,177 @@
+// Package cloudfront signs URLs and cookies for Amazon CloudFront,
+// which is often put in front of S3 buckets.
+//
+// See https://docs.aws.amazon.com/AmazonCloudFront/latest/DeveloperGuide/PrivateContent.html.
+package cloudfront
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Signer signs CloudFront URLs and cookies with the private
+// key of a CloudFront key pair or public key.
+type Signer struct {
+	KeyPairId string // ID of the key pair or public key
+	Key       *rsa.PrivateKey
+}
+
+// A Policy grants access to a resource for a limited time.
+// Resource is a URL, which may contain * wildcards.
+// Start and IPAddress are optional; a policy with either
+// is a custom policy.
+type Policy struct {
+	Resource  string
+	Expires   time.Time
+	Start     time.Time // not valid before
+	IPAddress string    // client address or CIDR range, such as "192.0.2.0/24"
+}
+
+func (p *Policy) canned() bool {
+	return p.Start.IsZero() && p.IPAddress == ""
+}
+
+// JSON returns the policy statement that is signed.
+func (p *Policy) JSON() ([]byte, error) {
+	type epoch struct {
+		T int64 `json:"AWS:EpochTime"`
+	}
+	type ip struct {
+		IP string `json:"AWS:SourceIp"`
+	}
+	var v struct {
+		Statement [1]struct {
+			Resource  string
+			Condition struct {
+				DateLessThan    epoch
+				DateGreaterThan *epoch `json:",omitempty"`
+				IpAddress       *ip    `json:",omitempty"`
+			}
+		}
+	}
+	st := &v.Statement[0]
+	st.Resource = p.Resource
+	st.Condition.DateLessThan.T = p.Expires.Unix()
+	if !p.Start.IsZero() {
+		st.Condition.DateGreaterThan = &epoch{p.Start.Unix()}
+	}
+	if p.IPAddress != "" {
+		st.Condition.IpAddress = &ip{p.IPAddress}
+	}
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// SignURL returns rawurl signed with a canned policy that
+// allows access to it until expires.
+func (s *Signer) SignURL(rawurl string, expires time.Time) (string, error) {
+	return s.SignURLWithPolicy(rawurl, &Policy{Resource: rawurl, Expires: expires})
+}
+
+// SignURLWithPolicy returns rawurl signed with policy p.
+// A canned policy must have rawurl as its Resource.
+func (s *Signer) SignURLWithPolicy(rawurl string, p *Policy) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	params, err := s.params(p)
+	if err != nil {
+		return "", err
+	}
+	q := u.RawQuery
+	for _, kv := range params {
+		if q != "" {
+			q += "&"
+		}
+		q += kv[0] + "=" + kv[1]
+	}
+	u.RawQuery = q
+	return u.String(), nil
+}
+
+// Cookies returns the signed cookies that grant access
+// according to p.
+func (s *Signer) Cookies(p *Policy) ([]*http.Cookie, error) {
+	params, err := s.params(p)
+	if err != nil {
+		return nil, err
+	}
+	var a []*http.Cookie
+	for _, kv := range params {
+		a = append(a, &http.Cookie{Name: "CloudFront-" + kv[0], Value: kv[1]})
+	}
+	return a, nil
+}
+
+// params returns the names and values of the signing parameters,
+// in the form used in URLs; cookies add a "CloudFront-" prefix.
+func (s *Signer) params(p *Policy) ([][2]string, error) {
+	if s.Key == nil || s.KeyPairId == "" {
+		return nil, errors.New("cloudfront: signer has no key")
+	}
+	b, err := p.JSON()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(b)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.Key, crypto.SHA1, sum[:])
+	if err != nil {
+		return nil, err
+	}
+	var a [][2]string
+	if p.canned() {
+		a = append(a, [2]string{"Expires", strconv.FormatInt(p.Expires.Unix(), 10)})
+	} else {
+		a = append(a, [2]string{"Policy", encode(b)})
+	}
+	a = append(a,
+		[2]string{"Signature", encode(sig)},
+		[2]string{"Key-Pair-Id", s.KeyPairId},
+	)
+	return a, nil
+}
+
+// encode returns b in CloudFront's URL-safe variant of base64.
+func encode(b []byte) string {
+	return strings.NewReplacer("+", "-", "=", "_", "/", "~").Replace(base64.StdEncoding.EncodeToString(b))
+}
+
+// ParsePrivateKey parses a PEM-encoded RSA private key, in either
+// PKCS #1 or PKCS #8 form, as downloaded from the AWS console.
+func ParsePrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("cloudfront: no PEM data found")
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rk, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("cloudfront: not an RSA private key")
+	}
+	return rk, nil
+}
@@ -0,0 +1,115 @@
+package cloudfront
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decode(s string) ([]byte, error) {
+	s = strings.NewReplacer("-", "+", "_", "=", "~", "/").Replace(s)
+	return base64.StdEncoding.DecodeString(s)
+}
+
+func TestSignURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Signer{KeyPairId: "APKAEXAMPLE", Key: key}
+	const raw = "https://d111111abcdef8.cloudfront.net/image.jpg?size=large&x=1"
+	exp := time.Unix(1357034400, 0)
+	g, err := s.SignURL(raw, exp)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	u, err := url.Parse(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("size") != "large" || q.Get("Expires") != "1357034400" || q.Get("Key-Pair-Id") != "APKAEXAMPLE" {
+		t.Errorf("query = %v", q)
+	}
+	const policy = `{"Statement":[{"Resource":"https://d111111abcdef8.cloudfront.net/image.jpg?size=large&x=1","Condition":{"DateLessThan":{"AWS:EpochTime":1357034400}}}]}`
+	sig, err := decode(q.Get("Signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte(policy))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, sum[:], sig); err != nil {
+		t.Error("bad signature:", err)
+	}
+}
+
+func TestCookiesCustomPolicy(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Signer{KeyPairId: "K2JCJMDEHXQW5F", Key: key}
+	p := &Policy{
+		Resource:  "https://d111111abcdef8.cloudfront.net/videos/*",
+		Expires:   time.Unix(1357034400, 0),
+		Start:     time.Unix(1357030000, 0),
+		IPAddress: "192.0.2.0/24",
+	}
+	cookies, err := s.Cookies(p)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	m := make(map[string]string)
+	for _, c := range cookies {
+		m[c.Name] = c.Value
+	}
+	b, err := decode(m["CloudFront-Policy"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	const w = `{"Statement":[{"Resource":"https://d111111abcdef8.cloudfront.net/videos/*","Condition":{"DateLessThan":{"AWS:EpochTime":1357034400},"DateGreaterThan":{"AWS:EpochTime":1357030000},"IpAddress":{"AWS:SourceIp":"192.0.2.0/24"}}}]}`
+	if string(b) != w {
+		t.Errorf("policy = %s want %s", b, w)
+	}
+	sig, err := decode(m["CloudFront-Signature"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(b)
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, sum[:], sig); err != nil {
+		t.Error("bad signature:", err)
+	}
+	if m["CloudFront-Key-Pair-Id"] != "K2JCJMDEHXQW5F" || m["CloudFront-Expires"] != "" {
+		t.Errorf("cookies = %v", m)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	for _, b := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		k, err := ParsePrivateKey(pem.EncodeToMemory(b))
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if !k.Equal(key) {
+			t.Errorf("%s: key mismatch", b.Type)
+		}
+	}
+	if _, err := ParsePrivateKey([]byte("junk")); err == nil {
+		t.Error("expected error")
+	}
+}
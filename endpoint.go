@@ -0,0 +1,64 @@
+package s3
+
+import "strings"
+
+// BucketURL returns the base URL of the named bucket in the given
+// region on service s, ending in a slash, to which object keys are
+// appended. If s.Endpoint is set, it is expanded; otherwise the URL
+// is virtual-hosted-style, https://bucket.s3.amazonaws.com/ for
+// Amazon S3 (Domain amazonaws.com) or https://bucket.domain/ for
+// other services, and region is not used.
+func (s *Service) BucketURL(bucket, region string) string {
+	if s.Endpoint == "" {
+		if s.Domain == "amazonaws.com" {
+			return "https://" + bucket + ".s3.amazonaws.com/"
+		}
+		return "https://" + bucket + "." + s.Domain + "/"
+	}
+	u := strings.NewReplacer("{bucket}", bucket, "{region}", region).Replace(s.Endpoint)
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	return u
+}
+
+// endpointBucket matches host against the host in s.Endpoint
+// and returns the bucket name it holds, or "" if the template
+// puts the bucket in the path.
+func (s *Service) endpointBucket(host string) (bucket string, ok bool) {
+	t := s.Endpoint
+	if i := strings.Index(t, "://"); i != -1 {
+		t = t[i+3:]
+	}
+	if i := strings.IndexByte(t, '/'); i != -1 {
+		t = t[:i]
+	}
+	return matchHost(strings.ToLower(hostname(t)), host)
+}
+
+// matchHost matches host against template t, in which {bucket}
+// stands for a nonempty string and {region} for a nonempty
+// string without dots, and returns the text matched by {bucket}.
+func matchHost(t, host string) (bucket string, ok bool) {
+	switch {
+	case t == "":
+		return "", host == ""
+	case strings.HasPrefix(t, "{bucket}"):
+		for i := len(host); i > 0; i-- {
+			if _, ok := matchHost(t[len("{bucket}"):], host[i:]); ok {
+				return host[:i], true
+			}
+		}
+		return "", false
+	case strings.HasPrefix(t, "{region}"):
+		for i := 1; i <= len(host) && host[i-1] != '.'; i++ {
+			if b, ok := matchHost(t[len("{region}"):], host[i:]); ok {
+				return b, true
+			}
+		}
+		return "", false
+	case host != "" && t[0] == host[0]:
+		return matchHost(t[1:], host[1:])
+	}
+	return "", false
+}
@@ -0,0 +1,42 @@
+package s3
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBucketURL(t *testing.T) {
+	for _, ts := range []struct {
+		svc *Service
+		w   string
+	}{
+		{DefaultService, "https://b.s3.amazonaws.com/"},
+		{&Service{Domain: "storage.io"}, "https://b.storage.io/"},
+		{&Service{Endpoint: "https://{bucket}.{region}.example.com"}, "https://b.eu-1.example.com/"},
+		{&Service{Endpoint: "http://gw.example.com:9000/{bucket}/"}, "http://gw.example.com:9000/b/"},
+	} {
+		if g := ts.svc.BucketURL("b", "eu-1"); g != ts.w {
+			t.Errorf("BucketURL(%+v) = %q want %q", ts.svc, g, ts.w)
+		}
+	}
+}
+
+func TestEndpointObjectPath(t *testing.T) {
+	for _, ts := range []struct {
+		endpoint, url, w string
+	}{
+		{"https://{bucket}.{region}.example.com", "https://my.bucket.eu-1.example.com/k", "/my.bucket/k"},
+		{"https://{region}-store.example.com/{bucket}", "https://eu-1-store.example.com/b/k", "/b/k"},
+		{"https://{bucket}.{region}.example.com", "https://b.other.net/k", "/b.other.net/k"},
+		{"https://obj-{bucket}.example.com:8443", "https://obj-b.example.com:8443/k", "/b/k"},
+	} {
+		s := &Service{Domain: "example.com", Endpoint: ts.endpoint}
+		u, err := url.Parse(ts.url)
+		if err != nil {
+			panic(err)
+		}
+		if g := s.ObjectPath(u); g != ts.w {
+			t.Errorf("ObjectPath(%q) with endpoint %q = %q want %q", ts.url, ts.endpoint, g, ts.w)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Command s3 streams Amazon S3 objects to and from shell pipelines.
+//
+// Usage:
+//
+//	s3 cat url...
+//	s3 put file url
+//	s3 presign url [--expires duration] [--method method]
+//
+// Cat writes the objects at the given URLs to standard output, in
+// order. Each object is fetched in ranges, several at once, so a
+// large object downloads faster than over a single connection;
+// see s3util.GetParallel.
+//
+// Put uploads file to url with a multipart upload, sending parts
+// as the data arrives. A file named "-" means standard input, so
+// data of unknown length, such as a backup being made, can be
+// uploaded without first being stored on disk.
+//
+// Presign prints a pre-signed URL for the object at url. Anyone
+// holding the printed URL can perform the given request (GET by
+// default) on the object until it expires, after an hour by default.
+//
+// Examples:
+//
+//	$ tar cz dir | s3 put - https://mybucket.s3.amazonaws.com/backup.tgz
+//	$ s3 cat https://mybucket.s3.amazonaws.com/backup.tgz | tar xz
+//	$ s3 presign https://mybucket.s3.amazonaws.com/upload.bin --expires 15m --method PUT
+//
+// Environment:
+//
+// S3_ACCESS_KEY – an AWS Access Key Id (required)
+//
+// S3_SECRET_KEY – an AWS Secret Access Key (required)
+//
+// S3_PROFILE – the name of a profile to use instead of the keys above;
+// see s3util.Config.Profile
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/kr/s3/s3util"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: s3 cat url...")
+	fmt.Fprintln(os.Stderr, "       s3 put file url")
+	fmt.Fprintln(os.Stderr, "       s3 presign url [--expires duration] [--method method]")
+	os.Exit(1)
+}
+
+func main() {
+	c := s3util.DefaultConfig
+	c.AccessKey = os.Getenv("S3_ACCESS_KEY")
+	c.SecretKey = os.Getenv("S3_SECRET_KEY")
+	if name := os.Getenv("S3_PROFILE"); name != "" {
+		var err error
+		c, err = c.Profile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	args := os.Args[1:]
+	if len(args) < 1 {
+		usage()
+	}
+	var err error
+	switch args[0] {
+	case "cat":
+		if len(args) < 2 {
+			usage()
+		}
+		err = cat(args[1:], c)
+	case "put":
+		if len(args) != 3 {
+			usage()
+		}
+		err = put(args[1], args[2], c)
+	case "presign":
+		err = presign(args[1:], c)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "s3:", err)
+		os.Exit(1)
+	}
+}
+
+func cat(urls []string, c *s3util.Config) error {
+	w := bufio.NewWriterSize(os.Stdout, 1<<20)
+	for _, url := range urls {
+		if _, err := s3util.GetParallel(url, w, c); err != nil {
+			w.Flush()
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func put(file, url string, c *s3util.Config) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	w, err := s3util.Create(url, nil, c)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		s3util.AsUploader(w).Abort()
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func presign(args []string, c *s3util.Config) error {
+	fs := flag.NewFlagSet("presign", flag.ExitOnError)
+	fs.Usage = usage
+	expires := fs.Duration("expires", time.Hour, "how long the URL remains valid")
+	method := fs.String("method", "GET", "HTTP method the URL is valid for")
+	// The flags may come before or after the URL.
+	var urls []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			break
+		}
+		urls = append(urls, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(urls) != 1 {
+		usage()
+	}
+	r, err := http.NewRequest(*method, urls[0], nil)
+	if err != nil {
+		return err
+	}
+	c.Presign(r, *c.Keys, time.Now().Add(*expires))
+	fmt.Println(r.URL)
+	return nil
+}
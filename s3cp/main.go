@@ -8,16 +8,25 @@
 // The file does not need to be seekable or stat-able. You can use s3cp to
 // upload data of indeterminate length, such as from a pipe.
 //
+// A file named "-" means standard input when it is the source and
+// standard output when it is the destination, so s3cp can sit at
+// either end of a shell pipeline.
+//
 // Examples:
 //   $ s3cp file.txt https://mybucket.s3.amazonaws.com/file.txt
 //   $ gendata | s3cp /dev/stdin https://mybucket.s3.amazonaws.com/log
 //   $ s3cp https://mybucket.s3.amazonaws.com/image.jpg pic.jpg
+//   $ tar cz dir | s3cp - https://mybucket.s3.amazonaws.com/backup.tgz
+//   $ s3cp https://mybucket.s3.amazonaws.com/backup.tgz - | tar xz
 //
 // Environment:
 //
 // S3_ACCESS_KEY – an AWS Access Key Id (required)
 //
 // S3_SECRET_KEY – an AWS Secret Access Key (required)
+//
+// S3_PROFILE – the name of a profile to use instead of the keys above;
+// see s3util.Config.Profile
 package main
 
 import (
@@ -31,6 +40,14 @@ import (
 func main() {
 	s3util.DefaultConfig.AccessKey = os.Getenv("S3_ACCESS_KEY")
 	s3util.DefaultConfig.SecretKey = os.Getenv("S3_SECRET_KEY")
+	if name := os.Getenv("S3_PROFILE"); name != "" {
+		c, err := s3util.DefaultConfig.Profile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		s3util.DefaultConfig = c
+	}
 	args := os.Args[1:]
 	if len(args) != 2 {
 		fmt.Fprintln(os.Stderr, "Usage: s3cp file url")
@@ -67,6 +84,9 @@ func open(s string) (io.ReadCloser, error) {
 	if isURL(s) {
 		return s3util.Open(s, nil)
 	}
+	if s == "-" {
+		return os.Stdin, nil
+	}
 	return os.Open(s)
 }
 
@@ -74,6 +94,9 @@ func create(s string) (io.WriteCloser, error) {
 	if isURL(s) {
 		return s3util.Create(s, nil, nil)
 	}
+	if s == "-" {
+		return os.Stdout, nil
+	}
 	return os.Create(s)
 }
 
@@ -0,0 +1,177 @@
+package s3util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An AccessLogRecord is one request from an S3 server access log.
+// Fields logged as "-" are left empty, or -1 for numbers.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/LogFormat.html.
+type AccessLogRecord struct {
+	BucketOwner    string
+	Bucket         string
+	Time           time.Time
+	RemoteIP       string
+	Requester      string
+	RequestId      string
+	Operation      string // such as "REST.GET.OBJECT"
+	Key            string
+	RequestURI     string
+	HTTPStatus     int
+	ErrorCode      string
+	BytesSent      int64
+	ObjectSize     int64
+	TotalTime      time.Duration
+	TurnaroundTime time.Duration
+	Referer        string
+	UserAgent      string
+	VersionId      string
+	HostId         string // x-amz-id-2, the second request ID
+	SigVersion     string
+	CipherSuite    string
+	AuthType       string
+	HostHeader     string
+	TLSVersion     string
+
+	// Extra holds any fields after those above,
+	// which S3 may add to the format over time.
+	Extra []string
+}
+
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// ParseAccessLogLine parses one line of an S3 server access log.
+// Older logs that lack the later fields are accepted.
+func ParseAccessLogLine(line string) (*AccessLogRecord, error) {
+	f, err := splitAccessLog(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(f) < 18 {
+		return nil, fmt.Errorf("s3util: access log line has %d fields", len(f))
+	}
+	for len(f) < 24 {
+		f = append(f, "")
+	}
+	r := &AccessLogRecord{
+		BucketOwner: f[0],
+		Bucket:      f[1],
+		RemoteIP:    f[3],
+		Requester:   f[4],
+		RequestId:   f[5],
+		Operation:   f[6],
+		Key:         f[7],
+		RequestURI:  f[8],
+		ErrorCode:   f[10],
+		Referer:     f[15],
+		UserAgent:   f[16],
+		VersionId:   f[17],
+		HostId:      f[18],
+		SigVersion:  f[19],
+		CipherSuite: f[20],
+		AuthType:    f[21],
+		HostHeader:  f[22],
+		TLSVersion:  f[23],
+		Extra:       f[24:],
+	}
+	if len(r.Extra) == 0 {
+		r.Extra = nil
+	}
+	if r.Time, err = time.Parse(accessLogTime, f[2]); err != nil {
+		return nil, fmt.Errorf("s3util: access log time %q: %v", f[2], err)
+	}
+	var n [5]int64
+	for i, s := range []string{f[9], f[11], f[12], f[13], f[14]} {
+		if n[i], err = accessLogInt(s); err != nil {
+			return nil, err
+		}
+	}
+	r.HTTPStatus = int(n[0])
+	r.BytesSent, r.ObjectSize = n[1], n[2]
+	r.TotalTime = ms(n[3])
+	r.TurnaroundTime = ms(n[4])
+	return r, nil
+}
+
+// ReadAccessLog returns an iterator over the records in an
+// access log file read from r. Blank lines are skipped.
+// Iteration stops after the first error.
+func ReadAccessLog(r io.Reader) iter.Seq2[*AccessLogRecord, error] {
+	return func(yield func(*AccessLogRecord, error) bool) {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			if strings.TrimSpace(sc.Text()) == "" {
+				continue
+			}
+			rec, err := ParseAccessLogLine(sc.Text())
+			if !yield(rec, err) || err != nil {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// splitAccessLog splits a log line into fields separated by
+// spaces, where a field may be enclosed in double quotes or,
+// for the time, square brackets. A lone "-" becomes "".
+func splitAccessLog(line string) ([]string, error) {
+	var f []string
+	for {
+		line = strings.TrimLeft(line, " ")
+		if line == "" {
+			return f, nil
+		}
+		var s string
+		switch line[0] {
+		case '"', '[':
+			end := byte('"')
+			if line[0] == '[' {
+				end = ']'
+			}
+			i := strings.IndexByte(line[1:], end)
+			if i < 0 {
+				return nil, errors.New("s3util: unterminated field in access log line")
+			}
+			s, line = line[1:1+i], line[2+i:]
+		default:
+			i := strings.IndexByte(line, ' ')
+			if i < 0 {
+				i = len(line)
+			}
+			s, line = line[:i], line[i:]
+		}
+		if s == "-" {
+			s = ""
+		}
+		f = append(f, s)
+	}
+}
+
+func accessLogInt(s string) (int64, error) {
+	if s == "" {
+		return -1, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("s3util: bad number %q in access log line", s)
+	}
+	return n, nil
+}
+
+func ms(n int64) time.Duration {
+	if n < 0 {
+		return -1
+	}
+	return time.Duration(n) * time.Millisecond
+}
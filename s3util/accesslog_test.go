@@ -0,0 +1,65 @@
+package s3util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAccessLog = `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be 3E57427F3EXAMPLE REST.GET.VERSIONING - "GET /awsexamplebucket1?versioning HTTP/1.1" 200 - 113 - 7 - "-" "S3Console/0.4" - s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader awsexamplebucket1.s3.us-west-1.amazonaws.com TLSV1.2 arn:aws:s3:us-west-1:123456789012:accesspoint/example-AP Yes
+
+79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:39 +0000] 192.0.2.3 - 891CE47D2EXAMPLE REST.GET.OBJECT photos/a%20b.jpg "GET /awsexamplebucket1/photos/a%20b.jpg HTTP/1.1" 404 NoSuchKey 243 - 12 10 "-" "curl/8.0" -
+`
+
+func TestReadAccessLog(t *testing.T) {
+	var recs []*AccessLogRecord
+	for r, err := range ReadAccessLog(strings.NewReader(testAccessLog)) {
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		recs = append(recs, r)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records want 2", len(recs))
+	}
+	r := recs[0]
+	if w := time.Date(2019, 2, 6, 0, 0, 38, 0, time.UTC); !r.Time.Equal(w) {
+		t.Errorf("Time = %v want %v", r.Time, w)
+	}
+	if r.RequestId != "3E57427F3EXAMPLE" || !strings.HasPrefix(r.HostId, "s9lzHYrFp76") {
+		t.Errorf("RequestId, HostId = %q, %q", r.RequestId, r.HostId)
+	}
+	if r.RequestURI != "GET /awsexamplebucket1?versioning HTTP/1.1" || r.UserAgent != "S3Console/0.4" {
+		t.Errorf("RequestURI, UserAgent = %q, %q", r.RequestURI, r.UserAgent)
+	}
+	if r.HTTPStatus != 200 || r.BytesSent != 113 || r.ObjectSize != -1 {
+		t.Errorf("HTTPStatus, BytesSent, ObjectSize = %d, %d, %d", r.HTTPStatus, r.BytesSent, r.ObjectSize)
+	}
+	if r.TotalTime != 7*time.Millisecond || r.TurnaroundTime != -1 {
+		t.Errorf("TotalTime, TurnaroundTime = %v, %v", r.TotalTime, r.TurnaroundTime)
+	}
+	if r.TLSVersion != "TLSV1.2" || len(r.Extra) != 2 || r.Extra[1] != "Yes" {
+		t.Errorf("TLSVersion, Extra = %q, %q", r.TLSVersion, r.Extra)
+	}
+
+	r = recs[1]
+	if r.Requester != "" || r.Key != "photos/a%20b.jpg" || r.ErrorCode != "NoSuchKey" || r.HTTPStatus != 404 {
+		t.Errorf("record 2 = %+v", r)
+	}
+	if r.TurnaroundTime != 10*time.Millisecond || r.HostId != "" || r.Extra != nil {
+		t.Errorf("record 2 = %+v", r)
+	}
+}
+
+func TestParseAccessLogLineBad(t *testing.T) {
+	for _, line := range []string{
+		"too few fields",
+		`a b [bad time] c d e f g "h" 200 - 1 - 1 1 "-" "-" -`,
+		`a b [06/Feb/2019:00:00:38 +0000] c d e f g "h 200 - 1 - 1 1 "-" "-" -`,
+		`a b [06/Feb/2019:00:00:38 +0000] c d e f g "h" OK - 1 - 1 1 "-" "-" -`,
+	} {
+		if _, err := ParseAccessLogLine(line); err == nil {
+			t.Errorf("ParseAccessLogLine(%q): expected error", line)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package s3util
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrSigV4Required is returned in place of sending a request to
+// an access point or Object Lambda access point, which accept only
+// Signature Version 4, not the version 2 signatures of this package.
+var ErrSigV4Required = errors.New("s3util: access points require Signature Version 4, which is not supported")
+
+// sigV4Only reports whether host is that of an access point
+// or Object Lambda access point.
+func sigV4Only(host string) bool {
+	return strings.Contains(host, ".s3-accesspoint.") || strings.Contains(host, ".s3-object-lambda.")
+}
+
+// AccessPointURL returns the base URL of the S3 access point or
+// Object Lambda access point with the given ARN, such as
+// "arn:aws:s3:us-west-2:123456789012:accesspoint/myap", which
+// becomes "https://myap-123456789012.s3-accesspoint.us-west-2.amazonaws.com/".
+// Object keys are appended to the URL as to a bucket URL.
+//
+// An access point alias, such as "myap-abc123def-s3alias", is used
+// as a bucket name and needs no conversion.
+//
+// Access points and Object Lambda access points accept only
+// Signature Version 4, which this package does not implement, so
+// requests made with this package to the returned URL fail with
+// ErrSigV4Required without being sent. The URL is for use with
+// other clients, or with an alias in its place.
+func AccessPointURL(arn string) (string, error) {
+	f := strings.SplitN(arn, ":", 6)
+	if len(f) != 6 || f[0] != "arn" || f[3] == "" || f[4] == "" {
+		return "", errors.New("s3util: malformed access point ARN " + arn)
+	}
+	var endpoint string
+	switch f[2] {
+	case "s3":
+		endpoint = "s3-accesspoint"
+	case "s3-object-lambda":
+		endpoint = "s3-object-lambda"
+	default:
+		return "", errors.New("s3util: not an access point ARN " + arn)
+	}
+	name := strings.TrimPrefix(f[5], "accesspoint/")
+	if name == f[5] || name == "" || strings.Contains(name, "/") {
+		return "", errors.New("s3util: not an access point ARN " + arn)
+	}
+	domain := "amazonaws.com"
+	if strings.HasPrefix(f[3], "cn-") {
+		domain = "amazonaws.com.cn"
+	}
+	return "https://" + name + "-" + f[4] + "." + endpoint + "." + f[3] + "." + domain + "/", nil
+}
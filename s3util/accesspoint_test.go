@@ -0,0 +1,63 @@
+package s3util
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestAccessPointURL(t *testing.T) {
+	cases := []struct{ arn, w string }{
+		{
+			"arn:aws:s3:us-west-2:123456789012:accesspoint/myap",
+			"https://myap-123456789012.s3-accesspoint.us-west-2.amazonaws.com/",
+		},
+		{
+			"arn:aws:s3-object-lambda:us-east-1:123456789012:accesspoint/myol",
+			"https://myol-123456789012.s3-object-lambda.us-east-1.amazonaws.com/",
+		},
+		{
+			"arn:aws-cn:s3:cn-north-1:123456789012:accesspoint/myap",
+			"https://myap-123456789012.s3-accesspoint.cn-north-1.amazonaws.com.cn/",
+		},
+	}
+	for _, c := range cases {
+		g, err := AccessPointURL(c.arn)
+		if err != nil {
+			t.Errorf("AccessPointURL(%q): unexpected err %v", c.arn, err)
+			continue
+		}
+		if g != c.w {
+			t.Errorf("AccessPointURL(%q) = %q want %q", c.arn, g, c.w)
+		}
+	}
+	for _, arn := range []string{
+		"",
+		"myap-abc123def-s3alias",
+		"arn:aws:s3:::mybucket",
+		"arn:aws:s3:us-west-2:123456789012:accesspoint/",
+		"arn:aws:sqs:us-west-2:123456789012:accesspoint/myap",
+	} {
+		if _, err := AccessPointURL(arn); err == nil {
+			t.Errorf("AccessPointURL(%q): expected error", arn)
+		}
+	}
+}
+
+func TestAccessPointRejected(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			t.Fatal("unexpected request", req.URL)
+			return nil, nil
+		}),
+	}
+	u, err := AccessPointURL("arn:aws:s3:us-west-2:123456789012:accesspoint/myap")
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if _, err := Get(u+"k", ioutil.Discard, &c); !errors.Is(err, ErrSigV4Required) {
+		t.Errorf("err = %v want %v", err, ErrSigV4Required)
+	}
+}
@@ -0,0 +1,140 @@
+package s3util
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// Append appends the contents of r to the S3 object at url,
+// creating the object if it does not exist.
+//
+// If the existing object is at least 5 MiB, Append builds the new
+// object with a multipart upload whose first parts are copied on
+// the server from the old object, so the existing data never
+// transits the client. Smaller objects are below S3's minimum part
+// size and are instead downloaded and written back, extended,
+// with a single PUT or, if r holds more than 5 MiB, a multipart
+// upload. Either way, if the object changes in the meantime,
+// Append fails with an error satisfying
+// errors.Is(err, ErrPreconditionFailed).
+//
+// If the object does not exist, Append creates it on condition
+// that no other writer does so first, and fails the same way if
+// one does.
+//
+// The new object keeps the old object's Content-Type but not its
+// other metadata. If c is nil, Append uses DefaultConfig.
+// Its Compressor, DetectContentType, Hashes, and WrapWriters
+// settings are ignored when an existing object is extended.
+func Append(url string, r io.Reader, c *Config) error {
+	if c == nil {
+		c = DefaultConfig
+	}
+	resp, err := head(url, c)
+	if os.IsNotExist(err) {
+		// Fail, rather than overwrite, if another
+		// writer creates the object first.
+		h := http.Header{"If-None-Match": {"*"}}
+		_, err = Put(url, r, h, c)
+		return err
+	} else if err != nil {
+		return err
+	}
+	etag := resp.Header.Get("Etag")
+	h := make(http.Header)
+	if t := resp.Header.Get("Content-Type"); t != "" {
+		h.Set("Content-Type", t)
+	}
+	if resp.ContentLength < minPartSize {
+		return appendSmall(url, etag, h, r, c)
+	}
+
+	u, err := newUploader(context.Background(), url, h, appendConfig(c))
+	if err != nil {
+		return err
+	}
+	u.ifMatch = etag
+	err = u.copyObject(url, etag, resp.ContentLength)
+	if err == nil {
+		_, err = io.Copy(u, r)
+	}
+	if err != nil {
+		u.setErr(err) // makes Close abort the upload
+	}
+	return u.Close()
+}
+
+// appendSmall appends r to the object at url, which has the
+// given ETag and is too small to copy as a part. Only the old
+// object and up to a part's worth of r are held in memory.
+func appendSmall(url, etag string, h http.Header, r io.Reader, c *Config) error {
+	old, err := Open(url, c)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+	b, err := ioutil.ReadAll(io.MultiReader(old, io.LimitReader(r, minPartSize)))
+	if err != nil {
+		return err
+	}
+	var one [1]byte
+	n, err := io.ReadFull(r, one[:])
+	if err == io.EOF {
+		h.Set("If-Match", etag)
+		_, err = Put(url, bytes.NewReader(b), h, c)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	// r has more data than is worth holding; stream it.
+	u, err := newUploader(context.Background(), url, h, appendConfig(c))
+	if err != nil {
+		return err
+	}
+	u.ifMatch = etag
+	_, err = io.Copy(u, io.MultiReader(bytes.NewReader(b), bytes.NewReader(one[:n]), r))
+	if err != nil {
+		u.setErr(err) // makes Close abort the upload
+	}
+	return u.Close()
+}
+
+// appendConfig returns a copy of c for the uploads of Append,
+// which write the old object's data unchanged alongside the new.
+// Compressing or otherwise transforming only the new data would
+// corrupt the object, and hashes would cover only part of it.
+func appendConfig(c *Config) *Config {
+	cc := *c
+	cc.Compressor = nil
+	cc.DetectContentType = false
+	cc.WrapWriters = nil
+	cc.Hashes = nil
+	return &cc
+}
+
+// head sends a HEAD request for the object at url.
+// It returns an error satisfying os.IsNotExist if
+// the object does not exist.
+func head(url string, c *Config) (*http.Response, error) {
+	r, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	closeBody(resp.Body)
+	switch resp.StatusCode {
+	case 200, 206: // 206 for HEAD ?partNumber=N
+		return resp, nil
+	case 404:
+		return nil, &os.PathError{Op: "head", Path: url, Err: os.ErrNotExist}
+	}
+	return nil, newRespError(resp)
+}
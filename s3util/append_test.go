@@ -0,0 +1,236 @@
+package s3util
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// appendClient returns a client for an S3 bucket holding an
+// object with ETag "data" and the given size, or no object if
+// size is negative, which records the requests sent to it in *reqs.
+func appendClient(t *testing.T, size int64, reqs *[]string) *http.Client {
+	var mu sync.Mutex // parts are sent concurrently
+	return &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			resp := &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Etag": {`"data"`}},
+			}
+			var s string
+			q := req.URL.Query()
+			switch {
+			case req.Method == "HEAD":
+				*reqs = append(*reqs, "head")
+				resp.ContentLength = size
+				if size < 0 {
+					resp.StatusCode = 404
+				}
+			case req.Method == "GET":
+				*reqs = append(*reqs, "get")
+				s = "old"
+			case req.Method == "POST" && q["uploads"] != nil:
+				*reqs = append(*reqs, words("initiate", req.Header.Get("Content-Encoding")))
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case req.Method == "PUT" && req.Header.Get("X-Amz-Copy-Source") != "":
+				*reqs = append(*reqs, words("copy", q.Get("partNumber"),
+					req.Header.Get("X-Amz-Copy-Source"),
+					req.Header.Get("X-Amz-Copy-Source-If-Match"),
+					req.Header.Get("X-Amz-Copy-Source-Range")))
+				s = `<CopyPartResult><ETag>"copied"</ETag></CopyPartResult>`
+			case req.Method == "PUT":
+				*reqs = append(*reqs, words("put", q.Get("partNumber"),
+					req.Header.Get("If-Match"), req.Header.Get("If-None-Match")))
+			case req.Method == "POST":
+				*reqs = append(*reqs, words("complete", req.Header.Get("If-Match")))
+				b, _ := ioutil.ReadAll(req.Body)
+				if !strings.Contains(string(b), "<PartNumber>1</PartNumber>") {
+					t.Errorf("complete body %q has no part 1", b)
+				}
+			case req.Method == "DELETE":
+				*reqs = append(*reqs, "abort")
+			default:
+				t.Fatal("unexpected request", req)
+			}
+			resp.Body = ioutil.NopCloser(strings.NewReader(s))
+			return resp, nil
+		}),
+	}
+}
+
+// words joins the non-empty strings in a with spaces.
+func words(a ...string) string {
+	return strings.Join(strings.Fields(strings.Join(a, " ")), " ")
+}
+
+func TestAppendLarge(t *testing.T) {
+	half := int64(maxCopySize/2 + 1)
+	for _, test := range []struct {
+		size int64
+		w    []string
+	}{
+		{minPartSize, []string{
+			"head", "initiate",
+			`copy 1 /mybucket/log "data"`,
+			"put 2", `complete "data"`,
+		}},
+		// Too large to copy at once.
+		{maxCopySize + 2, []string{
+			"head", "initiate",
+			`copy 1 /mybucket/log "data" bytes=0-` + strconv.FormatInt(half-1, 10),
+			`copy 2 /mybucket/log "data" bytes=` + strconv.FormatInt(half, 10) + "-" + strconv.FormatInt(maxCopySize+1, 10),
+			"put 3", `complete "data"`,
+		}},
+	} {
+		var reqs []string
+		c := *DefaultConfig
+		c.Client = appendClient(t, test.size, &reqs)
+		err := Append("https://mybucket.s3.amazonaws.com/log", strings.NewReader("more"), &c)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if strings.Join(reqs, ",") != strings.Join(test.w, ",") {
+			t.Errorf("size %d: requests = %q want %q", test.size, reqs, test.w)
+		}
+	}
+}
+
+func TestAppendCompressor(t *testing.T) {
+	var reqs []string
+	c := *DefaultConfig
+	c.Compressor = Gzip
+	c.Client = appendClient(t, minPartSize, &reqs)
+	err := Append("https://mybucket.s3.amazonaws.com/log", strings.NewReader("more"), &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	// The initiate request would set Content-Encoding: gzip.
+	w := []string{"head", "initiate", `copy 1 /mybucket/log "data"`, "put 2", `complete "data"`}
+	if strings.Join(reqs, ",") != strings.Join(w, ",") {
+		t.Errorf("requests = %q want %q", reqs, w)
+	}
+}
+
+func TestAppendCreate(t *testing.T) {
+	var reqs []string
+	c := *DefaultConfig
+	c.Client = appendClient(t, -1, &reqs)
+	err := Append("https://mybucket.s3.amazonaws.com/log", strings.NewReader("new"), &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := []string{"head", "put *"}
+	if strings.Join(reqs, ",") != strings.Join(w, ",") {
+		t.Errorf("requests = %q want %q", reqs, w)
+	}
+
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			status := 404
+			if req.Method == "PUT" {
+				status = 412 // created by another writer
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	err = Append("https://mybucket.s3.amazonaws.com/log", strings.NewReader("new"), &c)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("err = %v want %v", err, ErrPreconditionFailed)
+	}
+}
+
+func TestAppendSmall(t *testing.T) {
+	for _, test := range []struct {
+		n int64 // bytes appended
+		w []string
+	}{
+		{4, []string{"head", "get", `put "data"`}},
+		// Too much to hold in memory.
+		// The parts may be sent in either order.
+		{minPartSize + 1, []string{"head", "get", "initiate", "put *", "put *", `complete "data"`}},
+	} {
+		var reqs []string
+		c := *DefaultConfig
+		c.Client = appendClient(t, 3, &reqs)
+		err := Append("https://mybucket.s3.amazonaws.com/log", io.LimitReader(devZero, test.n), &c)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		for i, s := range reqs {
+			if strings.HasPrefix(s, "put ") && s[4] != '"' {
+				reqs[i] = "put *"
+			}
+		}
+		if strings.Join(reqs, ",") != strings.Join(test.w, ",") {
+			t.Errorf("append %d: requests = %q want %q", test.n, reqs, test.w)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	var srcs []string
+	var complete string
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{StatusCode: 200, Header: http.Header{}}
+			var s string
+			q := req.URL.Query()
+			switch {
+			case req.Method == "HEAD":
+				resp.ContentLength = minPartSize
+				if req.URL.Path == "/part-1" {
+					resp.ContentLength = maxCopySize + 2
+				}
+				resp.Header.Set("Etag", `"`+req.URL.Path+`"`)
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<UploadId>foo</UploadId>`
+			case req.Method == "PUT":
+				src := words(req.Header.Get("X-Amz-Copy-Source"),
+					req.Header.Get("X-Amz-Copy-Source-If-Match"),
+					req.Header.Get("X-Amz-Copy-Source-Range"))
+				srcs = append(srcs, src)
+				s = `<CopyPartResult><ETag>"` + q.Get("partNumber") + `"</ETag></CopyPartResult>`
+			case req.Method == "POST":
+				b, _ := ioutil.ReadAll(req.Body)
+				complete = string(b)
+			default:
+				t.Fatal("unexpected request", req)
+			}
+			resp.Body = ioutil.NopCloser(strings.NewReader(s))
+			return resp, nil
+		}),
+	}
+	err := Concat("https://b.s3.amazonaws.com/all", []string{
+		"https://b.s3.amazonaws.com/part-0",
+		"https://b.s3.amazonaws.com/part-1",
+	}, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	half := int64(maxCopySize/2 + 1)
+	w := []string{
+		`/b/part-0 "/part-0"`,
+		`/b/part-1 "/part-1" bytes=0-` + strconv.FormatInt(half-1, 10),
+		`/b/part-1 "/part-1" bytes=` + strconv.FormatInt(half, 10) + "-" + strconv.FormatInt(maxCopySize+1, 10),
+	}
+	if g, w := strings.Join(srcs, ","), strings.Join(w, ","); g != w {
+		t.Errorf("sources = %q want %q", g, w)
+	}
+	wc := "<Part><PartNumber>1</PartNumber><ETag>1</ETag></Part>" +
+		"<Part><PartNumber>2</PartNumber><ETag>2</ETag></Part>" +
+		"<Part><PartNumber>3</PartNumber><ETag>3</ETag></Part>"
+	if !strings.Contains(complete, wc) {
+		t.Errorf("complete body = %q want %q", complete, wc)
+	}
+}
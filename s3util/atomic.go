@@ -0,0 +1,95 @@
+package s3util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+)
+
+type atomicWriter struct {
+	url string
+	tmp string
+	c   *Config
+	w   io.WriteCloser
+	err error
+
+	closed   bool
+	closeErr error
+}
+
+// AtomicWrite returns a WriteCloser that uploads data to a temporary
+// object next to url and, on Close, copies it to url and deletes the
+// temporary object. Readers of url never observe a partially written
+// object, even if the process dies mid-upload. The temporary object's
+// key is url's key followed by ".tmp-" and a random suffix.
+//
+// Errors creating the upload are reported by Write and Close.
+// Objects over 5 GiB, too large to copy in one request, are copied
+// in parts. Calling Close again returns the first call's error.
+//
+// If c is nil, AtomicWrite uses DefaultConfig.
+func AtomicWrite(url string, c *Config) io.WriteCloser {
+	if c == nil {
+		c = DefaultConfig
+	}
+	a := &atomicWriter{url: url, c: c}
+	var b [8]byte
+	if _, a.err = rand.Read(b[:]); a.err != nil {
+		return a
+	}
+	a.tmp = url + ".tmp-" + hex.EncodeToString(b[:])
+	a.w, a.err = Create(a.tmp, nil, c)
+	return a
+}
+
+func (a *atomicWriter) Write(p []byte) (int, error) {
+	if a.err != nil {
+		return 0, a.err
+	}
+	return a.w.Write(p)
+}
+
+func (a *atomicWriter) Close() error {
+	if !a.closed {
+		a.closed = true
+		a.closeErr = a.close()
+	}
+	return a.closeErr
+}
+
+func (a *atomicWriter) close() error {
+	if a.err != nil {
+		return a.err
+	}
+	if err := a.w.Close(); err != nil {
+		return err
+	}
+	err := a.copy()
+	if derr := Delete(a.tmp, a.c); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// copy copies the temporary object to a.url.
+func (a *atomicWriter) copy() error {
+	tu := AsUploader(a.w)
+	if tu.size <= maxCopySize {
+		return Copy(a.url, a.tmp, nil, a.c)
+	}
+	// The data is already compressed, hashed, and typed.
+	cc := *a.c
+	cc.Compressor = nil
+	cc.DetectContentType = false
+	cc.WrapWriters = nil
+	cc.Hashes = nil
+	u, err := newUploader(context.Background(), a.url, tu.h, &cc)
+	if err != nil {
+		return err
+	}
+	if err = u.copyObject(a.tmp, `"`+tu.Result().ETag+`"`, tu.size); err != nil {
+		u.setErr(err) // makes Close abort the upload
+	}
+	return u.Close()
+}
@@ -0,0 +1,107 @@
+package s3util
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAtomicWrite(t *testing.T) {
+	var reqs []string
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			s, status := "", 200
+			switch {
+			case req.Method == "POST" && req.URL.Query()["uploads"] != nil:
+				s = `<UploadId>foo</UploadId>`
+			case req.Method == "PUT" && req.Header.Get("X-Amz-Copy-Source") != "":
+				reqs = append(reqs, words("copy", req.Header.Get("X-Amz-Copy-Source"), req.URL.Path,
+					req.Header.Get("X-Amz-Copy-Source-Range")))
+				s = `<CopyPartResult><ETag>"bar"</ETag></CopyPartResult>`
+			case req.Method == "DELETE":
+				reqs = append(reqs, "delete "+req.URL.Path)
+				status = 204
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+				Header:     http.Header{"Etag": {`"foo"`}},
+			}, nil
+		}),
+	}
+	w := AtomicWrite("https://mybucket.s3.amazonaws.com/log.txt", &c)
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %q", reqs)
+	}
+	tmp := strings.Fields(reqs[0])[1]
+	if !strings.HasPrefix(tmp, "/mybucket/log.txt.tmp-") {
+		t.Errorf("copy source = %q", tmp)
+	}
+	if w := "copy " + tmp + " /log.txt"; reqs[0] != w {
+		t.Errorf("request = %q want %q", reqs[0], w)
+	}
+	if w := "delete " + strings.TrimPrefix(tmp, "/mybucket"); reqs[1] != w {
+		t.Errorf("request = %q want %q", reqs[1], w)
+	}
+}
+
+func TestAtomicWriteLarge(t *testing.T) {
+	var reqs []string
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			s, status := "", 200
+			switch {
+			case req.Method == "POST" && req.URL.Query()["uploads"] != nil:
+				reqs = append(reqs, "initiate "+req.URL.Path)
+				s = `<UploadId>foo</UploadId>`
+			case req.Method == "PUT" && req.Header.Get("X-Amz-Copy-Source") != "":
+				reqs = append(reqs, words("copy", req.Header.Get("X-Amz-Copy-Source-Range")))
+				s = `<CopyPartResult><ETag>"bar"</ETag></CopyPartResult>`
+			case req.Method == "DELETE":
+				reqs = append(reqs, "delete")
+				status = 204
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+				Header:     http.Header{"Etag": {`"foo"`}},
+			}, nil
+		}),
+	}
+	w := AtomicWrite("https://mybucket.s3.amazonaws.com/log.txt", &c)
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	// Pretend the rest of a large object was sent already.
+	AsUploader(w.(*atomicWriter).w).size = maxCopySize
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	half := int64(maxCopySize/2 + 3)
+	want := []string{
+		"initiate /log.txt",
+		"copy bytes=0-" + strconv.FormatInt(half-1, 10),
+		"copy bytes=" + strconv.FormatInt(half, 10) + "-" + strconv.FormatInt(maxCopySize+4, 10),
+		"delete",
+	}
+	if len(reqs) < 1 || !strings.HasPrefix(reqs[0], "initiate /log.txt.tmp-") {
+		t.Fatalf("requests = %q", reqs)
+	}
+	if g, w := strings.Join(reqs[1:], ","), strings.Join(want, ","); g != w {
+		t.Errorf("requests = %q want %q", g, w)
+	}
+}
@@ -0,0 +1,143 @@
+package s3util
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is returned in place of sending a request
+// while the Config's Breaker is open.
+var ErrBreakerOpen = errors.New("s3util: circuit breaker open")
+
+// A Breaker is a circuit breaker for the requests made with one or
+// more Configs, by Open, Create, Put, File listings, and every other
+// function of this package. It counts failed requests, those that
+// get a network error or a 5xx response, and when too many of them
+// fail it opens: requests, including the retries of uploads and
+// downloads in progress, fail at once with ErrBreakerOpen instead
+// of adding load to a struggling service. After a cool-down it lets
+// one request through; if that request succeeds the breaker closes,
+// otherwise it stays open for another cool-down. A request canceled
+// by its caller counts as neither a success nor a failure.
+//
+// A Breaker may be shared by several Configs and is safe for
+// concurrent use. The zero value is a breaker with default settings.
+type Breaker struct {
+	// Threshold is the fraction of failed requests in a window
+	// at which the breaker opens. If zero, 0.5 is used.
+	Threshold float64
+
+	// MinRequests is the fewest requests in a window for which
+	// the breaker may open. If zero, 20 is used.
+	MinRequests int
+
+	// Window is the period over which requests are counted.
+	// If zero, 10 seconds is used.
+	Window time.Duration
+
+	// CoolDown is how long the breaker stays open before letting
+	// a request through to test the service. If zero, 30 seconds
+	// is used.
+	CoolDown time.Duration
+
+	now func() time.Time // for testing
+
+	mu        sync.Mutex
+	start     time.Time // beginning of the current window
+	n, failed int
+	openUntil time.Time // zero while closed
+	probing   bool
+}
+
+func (b *Breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// allow reports whether a request may be sent, and whether
+// that request is the probe of a breaker whose cool-down has ended.
+func (b *Breaker) allow() (probe bool, err error) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return false, nil
+	}
+	if b.probing || b.clock().Before(b.openUntil) {
+		return false, ErrBreakerOpen
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record counts the outcome of a request allowed by allow.
+func (b *Breaker) record(probe, ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock()
+	if probe {
+		b.probing = false
+		if ok {
+			b.openUntil = time.Time{}
+			b.start, b.n, b.failed = now, 0, 0
+		} else {
+			b.openUntil = now.Add(durationOr(b.CoolDown, 30*time.Second))
+		}
+		return
+	}
+	if !b.openUntil.IsZero() {
+		return // sent before the breaker opened
+	}
+	if now.Sub(b.start) >= durationOr(b.Window, 10*time.Second) {
+		b.start, b.n, b.failed = now, 0, 0
+	}
+	b.n++
+	if !ok {
+		b.failed++
+	}
+	threshold := b.Threshold
+	if threshold == 0 {
+		threshold = 0.5
+	}
+	min := b.MinRequests
+	if min == 0 {
+		min = 20
+	}
+	if b.n >= min && float64(b.failed) >= threshold*float64(b.n) {
+		b.openUntil = now.Add(durationOr(b.CoolDown, 30*time.Second))
+	}
+}
+
+// release ends a request allowed by allow without counting it,
+// as for a request canceled by its caller. If the request was
+// the probe, the next request is let through instead.
+func (b *Breaker) release(probe bool) {
+	if b == nil || !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+// requestOK reports whether a request that got resp and err
+// counts as a success for a Breaker.
+func requestOK(resp *http.Response, err error) bool {
+	return err == nil && resp.StatusCode < 500
+}
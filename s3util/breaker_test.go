@@ -0,0 +1,170 @@
+package s3util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := &Breaker{MinRequests: 4, CoolDown: time.Minute, now: func() time.Time { return now }}
+	status, sent := 503, 0
+	c := *DefaultConfig
+	c.Breaker = b
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	get := func() error {
+		r, _ := http.NewRequest("GET", "https://foo.s3.amazonaws.com/x", nil)
+		resp, err := c.do(r)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("request %d: unexpected err %v", i, err)
+		}
+	}
+	if err := get(); err != ErrBreakerOpen {
+		t.Fatalf("err = %v want ErrBreakerOpen", err)
+	}
+	if sent != 4 {
+		t.Errorf("sent = %d want 4", sent)
+	}
+
+	// After the cool-down, one failed probe reopens the breaker.
+	now = now.Add(time.Minute)
+	if err := get(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if err := get(); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("err = %v want ErrBreakerOpen", err)
+	}
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	status = 200
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("request %d: unexpected err %v", i, err)
+		}
+	}
+	if sent != 8 {
+		t.Errorf("sent = %d want 8", sent)
+	}
+}
+
+func TestBreakerCanceled(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := &Breaker{MinRequests: 2, CoolDown: time.Minute, now: func() time.Time { return now }}
+	c := *DefaultConfig
+	c.Breaker = b
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: 503,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	get := func(ctx context.Context) error {
+		r, _ := http.NewRequestWithContext(ctx, "GET", "https://foo.s3.amazonaws.com/x", nil)
+		resp, err := c.do(r)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Canceled requests count as neither success nor failure.
+	get(context.Background())
+	get(canceled)
+	get(canceled)
+	if b.n != 1 || b.failed != 1 {
+		t.Errorf("n, failed = %d, %d want 1, 1", b.n, b.failed)
+	}
+	get(context.Background())
+	if err := get(context.Background()); err != ErrBreakerOpen {
+		t.Fatalf("err = %v want ErrBreakerOpen", err)
+	}
+
+	// A canceled probe neither closes the breaker
+	// nor keeps the next request from probing.
+	now = now.Add(time.Minute)
+	get(canceled)
+	if b.openUntil.IsZero() {
+		t.Error("canceled probe closed the breaker")
+	}
+	if _, err := b.allow(); err != nil {
+		t.Errorf("allow() after canceled probe = %v want nil", err)
+	}
+}
+
+func TestBreakerCoverage(t *testing.T) {
+	sent := 0
+	c := *DefaultConfig
+	c.Breaker = &Breaker{openUntil: time.Now().Add(time.Hour)}
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return nil, errors.New("sent")
+		}),
+	}
+	const u = "https://foo.s3.amazonaws.com/x"
+	if _, err := Open(u, &c); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Open: err = %v want ErrBreakerOpen", err)
+	}
+	w, err := Create(u, nil, &c)
+	if err == nil {
+		io.WriteString(w, "hello")
+		err = w.Close()
+	}
+	if !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Create: err = %v want ErrBreakerOpen", err)
+	}
+	f, err := NewFile("https://foo.s3.amazonaws.com/", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if _, err := f.Readdir(0); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Readdir: err = %v want ErrBreakerOpen", err)
+	}
+	if sent != 0 {
+		t.Errorf("sent %d requests want 0", sent)
+	}
+}
+
+func TestBreakerMixed(t *testing.T) {
+	b := new(Breaker)
+	for i := 0; i < 100; i++ {
+		b.record(false, i%3 != 0) // a third fail
+	}
+	if _, err := b.allow(); err != nil {
+		t.Errorf("allow() = %v, want nil below threshold", err)
+	}
+	var nilb *Breaker
+	if _, err := nilb.allow(); err != nil {
+		t.Errorf("nil allow() = %v", err)
+	}
+	nilb.record(false, false)
+}
@@ -0,0 +1,234 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// xmlns is the namespace of S3 request and response documents.
+const xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// subresourceURL returns the URL of subresource sub of the
+// bucket at url, such as "https://bucket.s3.amazonaws.com/".
+func subresourceURL(url, sub string) string {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	return url + "?" + sub
+}
+
+// getSubresource fetches subresource sub of the bucket at url
+// and decodes the XML document into v.
+func getSubresource(url, sub string, v interface{}, c *Config) error {
+	if c == nil {
+		c = DefaultConfig
+	}
+	r, err := http.NewRequest("GET", subresourceURL(url, sub), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	defer closeBody(resp.Body)
+	return c.decodeXML(resp.Body, v)
+}
+
+// putSubresource stores v, encoded as an XML document with root
+// element root, as subresource sub of the bucket at url.
+func putSubresource(url, sub, root string, v interface{}, c *Config) error {
+	if c == nil {
+		c = DefaultConfig
+	}
+	var buf bytes.Buffer
+	start := xml.StartElement{
+		Name: xml.Name{Local: root},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: xmlns}},
+	}
+	if err := xml.NewEncoder(&buf).EncodeElement(v, start); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	r, err := http.NewRequest("PUT", subresourceURL(url, sub), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(b)
+	r.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := c.do(r)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 && resp.StatusCode != 204 {
+		return newRespError(resp)
+	}
+	closeBody(resp.Body)
+	return nil
+}
+
+// deleteSubresource deletes subresource sub of the bucket at url.
+func deleteSubresource(url, sub string, c *Config) error {
+	return Delete(subresourceURL(url, sub), c)
+}
+
+// GetBucketAccelerate returns the Transfer Acceleration status of
+// the bucket at url, "Enabled" or "Suspended", or "" if it has
+// never been set. If c is nil, GetBucketAccelerate uses DefaultConfig.
+func GetBucketAccelerate(url string, c *Config) (string, error) {
+	var v struct{ Status string }
+	err := getSubresource(url, "accelerate", &v, c)
+	return v.Status, err
+}
+
+// PutBucketAccelerate sets the Transfer Acceleration status of
+// the bucket at url to status, "Enabled" or "Suspended".
+// If c is nil, PutBucketAccelerate uses DefaultConfig.
+func PutBucketAccelerate(url, status string, c *Config) error {
+	v := struct{ Status string }{status}
+	return putSubresource(url, "accelerate", "AccelerateConfiguration", &v, c)
+}
+
+// GetBucketRequestPayment returns who pays for requests to the
+// bucket at url, "BucketOwner" or "Requester".
+// If c is nil, GetBucketRequestPayment uses DefaultConfig.
+func GetBucketRequestPayment(url string, c *Config) (string, error) {
+	var v struct{ Payer string }
+	err := getSubresource(url, "requestPayment", &v, c)
+	return v.Payer, err
+}
+
+// PutBucketRequestPayment sets who pays for requests to the bucket
+// at url to payer, "BucketOwner" or "Requester".
+// If c is nil, PutBucketRequestPayment uses DefaultConfig.
+func PutBucketRequestPayment(url, payer string, c *Config) error {
+	v := struct{ Payer string }{payer}
+	return putSubresource(url, "requestPayment", "RequestPaymentConfiguration", &v, c)
+}
+
+// PublicAccessBlock holds a bucket's public access block settings.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_PublicAccessBlockConfiguration.html.
+type PublicAccessBlock struct {
+	BlockPublicAcls       bool
+	IgnorePublicAcls      bool
+	BlockPublicPolicy     bool
+	RestrictPublicBuckets bool
+}
+
+// GetPublicAccessBlock returns the public access block settings
+// of the bucket at url. If none are set, the error satisfies
+// errors.Is(err, fs.ErrNotExist).
+// If c is nil, GetPublicAccessBlock uses DefaultConfig.
+func GetPublicAccessBlock(url string, c *Config) (*PublicAccessBlock, error) {
+	p := new(PublicAccessBlock)
+	if err := getSubresource(url, "publicAccessBlock", p, c); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PutPublicAccessBlock sets the public access block settings
+// of the bucket at url to p.
+// If c is nil, PutPublicAccessBlock uses DefaultConfig.
+func PutPublicAccessBlock(url string, p *PublicAccessBlock, c *Config) error {
+	return putSubresource(url, "publicAccessBlock", "PublicAccessBlockConfiguration", p, c)
+}
+
+// DeletePublicAccessBlock removes the public access block settings
+// of the bucket at url.
+// If c is nil, DeletePublicAccessBlock uses DefaultConfig.
+func DeletePublicAccessBlock(url string, c *Config) error {
+	return deleteSubresource(url, "publicAccessBlock", c)
+}
+
+// BucketEncryption holds a bucket's default server-side encryption.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_ServerSideEncryptionConfiguration.html.
+type BucketEncryption struct {
+	Rules []EncryptionRule `xml:"Rule"`
+}
+
+// EncryptionRule is a rule of a BucketEncryption.
+type EncryptionRule struct {
+	// SSEAlgorithm is "AES256" or "aws:kms". KMSMasterKeyID names
+	// the KMS key, if not the default.
+	SSEAlgorithm   string `xml:"ApplyServerSideEncryptionByDefault>SSEAlgorithm"`
+	KMSMasterKeyID string `xml:"ApplyServerSideEncryptionByDefault>KMSMasterKeyID,omitempty"`
+
+	BucketKeyEnabled bool `xml:",omitempty"`
+}
+
+// GetBucketEncryption returns the default encryption of the bucket
+// at url. If none is set, the error satisfies
+// errors.Is(err, fs.ErrNotExist).
+// If c is nil, GetBucketEncryption uses DefaultConfig.
+func GetBucketEncryption(url string, c *Config) (*BucketEncryption, error) {
+	e := new(BucketEncryption)
+	if err := getSubresource(url, "encryption", e, c); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// PutBucketEncryption sets the default encryption of the bucket
+// at url to e.
+// If c is nil, PutBucketEncryption uses DefaultConfig.
+func PutBucketEncryption(url string, e *BucketEncryption, c *Config) error {
+	return putSubresource(url, "encryption", "ServerSideEncryptionConfiguration", e, c)
+}
+
+// DeleteBucketEncryption removes the default encryption of the
+// bucket at url.
+// If c is nil, DeleteBucketEncryption uses DefaultConfig.
+func DeleteBucketEncryption(url string, c *Config) error {
+	return deleteSubresource(url, "encryption", c)
+}
+
+type tagSet struct {
+	Tags []tag `xml:"TagSet>Tag"`
+}
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+// GetBucketTagging returns the tags of the bucket at url.
+// If the bucket has no tags, the error satisfies
+// errors.Is(err, fs.ErrNotExist).
+// If c is nil, GetBucketTagging uses DefaultConfig.
+func GetBucketTagging(url string, c *Config) (map[string]string, error) {
+	var v tagSet
+	if err := getSubresource(url, "tagging", &v, c); err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(v.Tags))
+	for _, t := range v.Tags {
+		m[t.Key] = t.Value
+	}
+	return m, nil
+}
+
+// PutBucketTagging replaces the tags of the bucket at url with m.
+// If c is nil, PutBucketTagging uses DefaultConfig.
+func PutBucketTagging(url string, m map[string]string, c *Config) error {
+	var v tagSet
+	for k, s := range m {
+		v.Tags = append(v.Tags, tag{k, s})
+	}
+	sort.Slice(v.Tags, func(i, j int) bool { return v.Tags[i].Key < v.Tags[j].Key })
+	return putSubresource(url, "tagging", "Tagging", &v, c)
+}
+
+// DeleteBucketTagging removes all tags from the bucket at url.
+// If c is nil, DeleteBucketTagging uses DefaultConfig.
+func DeleteBucketTagging(url string, c *Config) error {
+	return deleteSubresource(url, "tagging", c)
+}
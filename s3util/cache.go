@@ -0,0 +1,259 @@
+package s3util
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A CachingOpener opens S3 objects through a cache of their
+// contents on local disk. It is safe for concurrent use.
+type CachingOpener struct {
+	dir string
+	max int64
+	c   *Config
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*cacheEntry // by URL hash
+	lru     list.List              // of *cacheEntry, most recently used first
+}
+
+type cacheEntry struct {
+	key  string // hash of the object URL
+	etag string
+	size int64
+	elem *list.Element
+}
+
+func (e *cacheEntry) name() string {
+	return e.key + "." + base64.RawURLEncoding.EncodeToString([]byte(e.etag))
+}
+
+// NewCachingOpener returns a CachingOpener that keeps up to maxBytes
+// of downloaded objects in dir, evicting the least recently used
+// objects when it is full. Objects are stored by URL and ETag.
+// The directory is created if necessary, and objects cached there
+// by an earlier CachingOpener are reused. The directory should not
+// be used for anything else. It is an error for maxBytes to be
+// negative.
+//
+// If c is nil, the CachingOpener uses DefaultConfig.
+func NewCachingOpener(dir string, maxBytes int64, c *Config) (*CachingOpener, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	if maxBytes < 0 {
+		return nil, fmt.Errorf("s3util: negative cache size %d", maxBytes)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	o := &CachingOpener{
+		dir:     dir,
+		max:     maxBytes,
+		c:       c,
+		entries: make(map[string]*cacheEntry),
+	}
+	fis, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type found struct {
+		e   *cacheEntry
+		mod time.Time
+	}
+	var a []found
+	for _, de := range fis {
+		name := de.Name()
+		if strings.HasPrefix(name, "tmp-") {
+			os.Remove(filepath.Join(dir, name)) // left by an interrupted download
+			continue
+		}
+		key, enc, ok := strings.Cut(name, ".")
+		etag, err := base64.RawURLEncoding.DecodeString(enc)
+		if !ok || err != nil || len(key) != 2*sha256.Size {
+			continue
+		}
+		fi, err := de.Info()
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		a = append(a, found{&cacheEntry{key: key, etag: string(etag), size: fi.Size()}, fi.ModTime()})
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].mod.After(a[j].mod) })
+	for _, f := range a {
+		if o.entries[f.e.key] != nil {
+			os.Remove(filepath.Join(dir, f.e.name())) // older version
+			continue
+		}
+		f.e.elem = o.lru.PushBack(f.e)
+		o.entries[f.e.key] = f.e
+		o.size += f.e.size
+	}
+	o.mu.Lock()
+	o.evict()
+	o.mu.Unlock()
+	return o, nil
+}
+
+// Open returns the contents of the S3 object at url. If the object
+// is in the cache, Open sends a conditional GET request and reads
+// the cached copy if it is still current. Otherwise, Open downloads
+// the object into the cache, unless it is larger than the cache,
+// and returns its contents.
+func (o *CachingOpener) Open(url string) (io.ReadCloser, error) {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+	o.mu.Lock()
+	var etag string
+	if e := o.entries[key]; e != nil {
+		etag = e.etag
+	}
+	o.mu.Unlock()
+
+	r, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	resp, err := o.c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == 304 && etag != "":
+		closeBody(resp.Body)
+		if f, err := o.openCached(key, etag); err == nil {
+			return f, nil
+		}
+		// The cached copy went away; fetch the object again.
+		o.mu.Lock()
+		if e := o.entries[key]; e != nil && e.etag == etag {
+			o.remove(e)
+		}
+		o.mu.Unlock()
+		return o.Open(url)
+	case resp.StatusCode != 200:
+		return nil, newRespError(resp)
+	}
+	etag = resp.Header.Get("Etag")
+	if etag == "" || resp.ContentLength > o.max {
+		return resp.Body, nil
+	}
+	defer closeBody(resp.Body)
+	return o.store(key, etag, resp.Body)
+}
+
+// store copies r into the cache as the object with the given key
+// and etag, and returns the cached file, open for reading.
+func (o *CachingOpener) store(key, etag string, r io.Reader) (io.ReadCloser, error) {
+	tmp, err := os.CreateTemp(o.dir, "tmp-")
+	if err != nil {
+		return nil, err
+	}
+	// Close the file before renaming or removing it,
+	// which some systems, such as Windows, require.
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if n > o.max {
+		// Too big to keep; it is removed once read.
+		f, err := os.Open(tmp.Name())
+		if err != nil {
+			os.Remove(tmp.Name())
+			return nil, err
+		}
+		return removeOnClose{f}, nil
+	}
+
+	e := &cacheEntry{key: key, etag: etag, size: n}
+	name := filepath.Join(o.dir, e.name())
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+	if old := o.entries[key]; old != nil {
+		if old.etag == etag {
+			o.size -= old.size
+			o.lru.Remove(old.elem)
+		} else {
+			o.remove(old)
+		}
+	}
+	e.elem = o.lru.PushFront(e)
+	o.entries[key] = e
+	o.size += n
+	o.evict()
+	return f, nil
+}
+
+// A removeOnClose is a file that is removed when it is closed.
+type removeOnClose struct {
+	*os.File
+}
+
+func (f removeOnClose) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// openCached opens the cached copy of an object
+// and marks it as recently used.
+func (o *CachingOpener) openCached(key, etag string) (*os.File, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e := o.entries[key]
+	if e == nil || e.etag != etag {
+		return nil, os.ErrNotExist
+	}
+	f, err := os.Open(filepath.Join(o.dir, e.name()))
+	if err != nil {
+		return nil, err
+	}
+	o.lru.MoveToFront(e.elem)
+	now := time.Now()
+	os.Chtimes(f.Name(), now, now) // keep LRU order across restarts
+	return f, nil
+}
+
+// evict removes least recently used objects until the cache
+// fits in o.max bytes. o.mu must be held.
+func (o *CachingOpener) evict() {
+	for o.size > o.max {
+		o.remove(o.lru.Back().Value.(*cacheEntry))
+	}
+}
+
+// remove removes e from the cache. o.mu must be held.
+func (o *CachingOpener) remove(e *cacheEntry) {
+	os.Remove(filepath.Join(o.dir, e.name()))
+	o.lru.Remove(e.elem)
+	delete(o.entries, e.key)
+	o.size -= e.size
+}
@@ -0,0 +1,111 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCachingOpener(t *testing.T) {
+	dir := t.TempDir()
+	objects := map[string]string{"/foo/a": "aaaaa", "/foo/b": "bbbbb"}
+	etags := map[string]string{"/foo/a": `"1"`, "/foo/b": `"1"`}
+	sizes := map[string]int64{} // if not the length of the object
+	var n304 int
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			etag := etags[req.URL.Path]
+			if req.Header.Get("If-None-Match") == etag {
+				n304++
+				return &http.Response{
+					StatusCode: 304,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}, nil
+			}
+			s := objects[req.URL.Path]
+			n, ok := sizes[req.URL.Path]
+			if !ok {
+				n = int64(len(s))
+			}
+			return &http.Response{
+				StatusCode:    200,
+				ContentLength: n,
+				Header:        http.Header{"Etag": {etag}},
+				Body:          ioutil.NopCloser(strings.NewReader(s)),
+			}, nil
+		}),
+	}
+	const base = "https://s3.amazonaws.com"
+	o, err := NewCachingOpener(dir, 8, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(o *CachingOpener, path string) string {
+		r, err := o.Open(base + path)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		return string(b)
+	}
+
+	for i := 0; i < 2; i++ {
+		if g := read(o, "/foo/a"); g != "aaaaa" {
+			t.Errorf("read %d = %q want %q", i, g, "aaaaa")
+		}
+	}
+	if n304 != 1 {
+		t.Errorf("got %d cache hits want 1", n304)
+	}
+
+	objects["/foo/a"], etags["/foo/a"] = "AAAAA", `"2"`
+	if g := read(o, "/foo/a"); g != "AAAAA" {
+		t.Errorf("read after change = %q want %q", g, "AAAAA")
+	}
+
+	// Only one object fits; b evicts a.
+	read(o, "/foo/b")
+	n304 = 0
+	read(o, "/foo/a")
+	if n304 != 0 {
+		t.Error("evicted object served from cache")
+	}
+
+	// A new CachingOpener finds the cached copy of a.
+	o, err = NewCachingOpener(dir, 8, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n304 = 0
+	if g := read(o, "/foo/a"); g != "AAAAA" || n304 != 1 {
+		t.Errorf("read after restart = %q, %d cache hits want %q, 1", g, n304, "AAAAA")
+	}
+	fis, _ := ioutil.ReadDir(dir)
+	if len(fis) != 1 {
+		t.Errorf("cache holds %d files want 1", len(fis))
+	}
+
+	// An object of unknown size that turns out to be too big
+	// is read from a temporary file, removed when closed.
+	objects["/foo/big"], etags["/foo/big"] = strings.Repeat("x", 9), `"1"`
+	sizes["/foo/big"] = -1
+	if g := read(o, "/foo/big"); g != objects["/foo/big"] {
+		t.Errorf("read big = %q want %q", g, objects["/foo/big"])
+	}
+	fis, _ = ioutil.ReadDir(dir)
+	if len(fis) != 1 {
+		t.Errorf("cache holds %d files after big read want 1", len(fis))
+	}
+}
+
+func TestCachingOpenerNegativeSize(t *testing.T) {
+	if _, err := NewCachingOpener(t.TempDir(), -1, nil); err == nil {
+		t.Error("expected error for negative size")
+	}
+}
@@ -0,0 +1,133 @@
+// Package cas stores content-addressed objects in S3.
+//
+// Each object is stored under the hex SHA-256 digest of its
+// contents, so storing the same data twice uploads it only once,
+// and data read back can be checked against its name.
+package cas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/kr/s3/s3util"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// ErrCorrupt is returned when an object's contents
+// do not match its digest.
+var ErrCorrupt = errors.New("cas: content does not match digest")
+
+// Objects up to this size are sent with a single PUT request.
+const smallSize = 16 << 20
+
+// A Store is a collection of content-addressed objects in S3.
+type Store struct {
+	// URL is the location of the store, such as
+	// "https://bucket.s3.amazonaws.com/cas/". An object is
+	// stored at URL + "sha256/" + its hex digest.
+	URL string
+
+	Config *s3util.Config // if nil, uses s3util.DefaultConfig
+}
+
+func (s *Store) url(digest string) string {
+	return s.URL + "sha256/" + digest
+}
+
+// Put stores the data read from r and returns its hex SHA-256
+// digest. If the store already holds an object with that
+// digest, the data is not uploaded again. The data is staged
+// in a temporary file to compute its digest before it is sent.
+func (s *Store) Put(r io.Reader) (digest string, err error) {
+	f, err := ioutil.TempFile("", "cas-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return "", err
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+	url := s.url(digest)
+	if _, err := s3util.Head(url, s.Config); err == nil {
+		return digest, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	if n <= smallSize {
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+		// Another writer may store the same object first;
+		// either copy will do.
+		hdr := http.Header{"If-None-Match": {"*"}}
+		_, err = s3util.Put(url, bytes.NewReader(b), hdr, s.Config)
+		if err != nil && err != s3util.ErrPreconditionFailed {
+			return "", err
+		}
+		return digest, nil
+	}
+	w, err := s3util.Create(url, nil, s.Config)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		s3util.AsUploader(w).Abort()
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// Open returns the contents of the object with the given hex
+// digest. The data is checked as it is read; if it does not
+// match the digest, the final Read returns ErrCorrupt instead
+// of io.EOF. If there is no such object, the error satisfies
+// os.IsNotExist.
+func (s *Store) Open(digest string) (io.ReadCloser, error) {
+	if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+		return nil, errors.New("cas: invalid digest " + digest)
+	}
+	url := s.url(digest)
+	rc, err := s3util.Open(url, s.Config)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &os.PathError{Op: "open", Path: url, Err: os.ErrNotExist}
+	} else if err != nil {
+		return nil, err
+	}
+	return &verifier{rc, sha256.New(), digest}, nil
+}
+
+type verifier struct {
+	rc   io.ReadCloser
+	h    hash.Hash
+	want string
+}
+
+func (v *verifier) Read(p []byte) (int, error) {
+	n, err := v.rc.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(v.h.Sum(nil)) != v.want {
+		err = ErrCorrupt
+	}
+	return n, err
+}
+
+func (v *verifier) Close() error {
+	return v.rc.Close()
+}
@@ -0,0 +1,86 @@
+package cas
+
+import (
+	"github.com/kr/s3/s3util"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestStore(t *testing.T) {
+	objects := make(map[string]string)
+	var nput int
+	c := *s3util.DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}
+			s, ok := objects[req.URL.Path]
+			switch req.Method {
+			case "PUT":
+				nput++
+				b, _ := ioutil.ReadAll(req.Body)
+				objects[req.URL.Path] = string(b)
+			case "HEAD", "GET":
+				if !ok {
+					resp.StatusCode = 404
+				} else if req.Method == "GET" {
+					resp.Body = ioutil.NopCloser(strings.NewReader(s))
+				}
+			}
+			return resp, nil
+		}),
+	}
+	st := &Store{URL: "https://s3.amazonaws.com/bucket/cas/", Config: &c}
+
+	const data = "hello, world"
+	const sum = "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"
+	for i := 0; i < 2; i++ {
+		d, err := st.Put(strings.NewReader(data))
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if d != sum {
+			t.Errorf("Put = %q want %q", d, sum)
+		}
+	}
+	if nput != 1 {
+		t.Errorf("uploaded %d times want 1", nput)
+	}
+	if g := objects["/bucket/cas/sha256/"+sum]; g != data {
+		t.Errorf("stored %q want %q", g, data)
+	}
+
+	r, err := st.Open(sum)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil || string(b) != data {
+		t.Errorf("Open read %q, %v want %q", b, err, data)
+	}
+
+	objects["/bucket/cas/sha256/"+sum] = "tampered"
+	r, _ = st.Open(sum)
+	if _, err := ioutil.ReadAll(r); err != ErrCorrupt {
+		t.Errorf("read of corrupt object err = %v want %v", err, ErrCorrupt)
+	}
+
+	_, err = st.Open(strings.Repeat("0", 64))
+	if !os.IsNotExist(err) {
+		t.Errorf("Open of missing object err = %v want not exist", err)
+	}
+	if _, err := st.Open("xyz"); err == nil {
+		t.Error("Open of invalid digest succeeded")
+	}
+}
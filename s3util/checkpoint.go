@@ -0,0 +1,125 @@
+package s3util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// A checkpoint is the serialized progress of a multipart upload,
+// as returned by Uploader.Checkpoint. It is JSON, such as
+//
+//	{
+//	  "url": "https://mybucket.s3.amazonaws.com/big.tar",
+//	  "uploadId": "VXBsb2FkIElE",
+//	  "partSize": 5242880,
+//	  "fixedPartSize": false,
+//	  "parts": [
+//	    {"number": 1, "offset": 0, "size": 5242880, "etag": "b54357faf0632cce46e942fa68356b38"},
+//	    {"number": 2, "offset": 5242880, "size": 5248122, "etag": "0c78aef83f66abc1fa1e8477f296d394"}
+//	  ]
+//	}
+type checkpoint struct {
+	URL           string           `json:"url"`
+	UploadId      string           `json:"uploadId"`
+	PartSize      int64            `json:"partSize"`
+	FixedPartSize bool             `json:"fixedPartSize"`
+	Parts         []checkpointPart `json:"parts"`
+}
+
+type checkpointPart struct {
+	Number int    `json:"number"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag"`
+}
+
+// Checkpoint returns a record of the parts of the upload that S3
+// has acknowledged, from the first up to the first that it hasn't,
+// for ResumeFromCheckpoint to continue the upload later, perhaps in
+// another process or on another machine. The record is JSON and
+// may be stored anywhere; it holds no credentials.
+//
+// Data written after the recorded parts, whether buffered or being
+// sent, is not in the record and must be written again when the
+// upload is resumed. Uploads whose data is compressed or hashed,
+// as set by Config.Compressor and Config.Hashes, can't be resumed,
+// since the state of the compressor or hash would be lost.
+func (u *Uploader) Checkpoint() ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.zw != nil || u.hashes != nil {
+		return nil, errors.New("s3util: can't checkpoint a compressed or hashed upload")
+	}
+	if !u.started {
+		return nil, errors.New("s3util: upload not initiated")
+	}
+	if u.closed {
+		return nil, errors.New("s3util: upload closed")
+	}
+	cp := checkpoint{
+		URL:           u.url,
+		UploadId:      u.UploadId,
+		PartSize:      u.bufsz,
+		FixedPartSize: u.fixed,
+		Parts:         []checkpointPart{},
+	}
+	var off int64
+	u.errMu.Lock()
+	for _, p := range u.xml.Part {
+		if p.ETag == "" {
+			break
+		}
+		cp.Parts = append(cp.Parts, checkpointPart{p.PartNumber, off, p.len, p.ETag})
+		off += p.len
+	}
+	u.errMu.Unlock()
+	return json.Marshal(cp)
+}
+
+// ResumeFromCheckpoint continues the multipart upload recorded in
+// data by Uploader.Checkpoint. It returns an Uploader and the offset
+// in the object's data at which writing must resume; the caller
+// writes the rest of the data from there and closes the Uploader
+// to complete the upload as usual.
+//
+// The upload's headers were sent when it was initiated, so only
+// c's credentials and request settings apply. If c is nil,
+// ResumeFromCheckpoint uses DefaultConfig.
+func ResumeFromCheckpoint(data []byte, c *Config) (*Uploader, int64, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	if c.Compressor != nil || len(c.Hashes) > 0 {
+		return nil, 0, errors.New("s3util: can't resume a compressed or hashed upload")
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, 0, err
+	}
+	if cp.URL == "" || cp.UploadId == "" {
+		return nil, 0, errors.New("s3util: checkpoint has no upload")
+	}
+	u := new(Uploader)
+	u.ctx, u.cancel = context.WithCancelCause(context.Background())
+	u.s3 = *c.Service
+	u.url = cp.URL
+	u.keys = *c.Keys
+	u.c = u.config(c)
+	u.pool = c.BufferPool
+	u.UploadId = cp.UploadId
+	u.started = true
+	u.bufsz = max(cp.PartSize, minPartSize)
+	u.fixed = cp.FixedPartSize
+	for i, p := range cp.Parts {
+		if p.Number != i+1 || p.Offset != u.size || p.ETag == "" {
+			return nil, 0, fmt.Errorf("s3util: bad checkpoint part %d", i+1)
+		}
+		u.xml.Part = append(u.xml.Part, &part{len: p.Size, PartNumber: p.Number, ETag: p.ETag})
+		u.size += p.Size
+	}
+	u.part = len(cp.Parts)
+	u.start(c)
+	return u, u.size, nil
+}
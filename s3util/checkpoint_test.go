@@ -0,0 +1,103 @@
+package s3util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		complete string
+	)
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			h := http.Header{}
+			switch q := req.URL.Query(); {
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case req.Method == "PUT":
+				h.Set("Etag", `"e`+q.Get("partNumber")+`"`)
+			case req.Method == "POST":
+				b, _ := ioutil.ReadAll(req.Body)
+				mu.Lock()
+				complete = string(b)
+				mu.Unlock()
+				s = `<CompleteMultipartUploadResult><ETag>"x-2"</ETag></CompleteMultipartUploadResult>`
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+				Header:     h,
+			}, nil
+		}),
+	}
+	w, err := Create("https://b.s3.amazonaws.com/k", nil, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	u := w.(*Uploader)
+	u.Write(make([]byte, minPartSize))
+	u.Write([]byte("lost"))
+	var data []byte
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		data, err = u.Checkpoint()
+		if err != nil {
+			t.Fatal("Checkpoint:", err)
+		}
+		if bytes.Contains(data, []byte(`"etag":"e1"`)) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	want := checkpoint{
+		URL:      "https://b.s3.amazonaws.com/k",
+		UploadId: "foo",
+		PartSize: u.bufsz,
+		Parts:    []checkpointPart{{Number: 1, Offset: 0, Size: minPartSize, ETag: "e1"}},
+	}
+	if g, _ := json.Marshal(cp); !bytes.Equal(g, mustJSON(want)) {
+		t.Errorf("checkpoint = %s want %s", g, mustJSON(want))
+	}
+
+	r, off, err := ResumeFromCheckpoint(data, &c)
+	if err != nil {
+		t.Fatal("ResumeFromCheckpoint:", err)
+	}
+	if off != minPartSize {
+		t.Errorf("offset = %d want %d", off, minPartSize)
+	}
+	r.Write([]byte("rest"))
+	if err := r.Close(); err != nil {
+		t.Fatal("Close:", err)
+	}
+	const wantBody = "<CompleteMultipartUpload>" +
+		"<Part><PartNumber>1</PartNumber><ETag>e1</ETag></Part>" +
+		"<Part><PartNumber>2</PartNumber><ETag>e2</ETag></Part>" +
+		"</CompleteMultipartUpload>"
+	mu.Lock()
+	defer mu.Unlock()
+	if complete != wantBody {
+		t.Errorf("complete body = %s want %s", complete, wantBody)
+	}
+}
+
+func mustJSON(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,121 @@
+package s3util
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// chunkSize is the size of the chunks of an aws-chunked body.
+const chunkSize = 64 * 1024
+
+// newChecksum returns a hash for the S3 checksum algorithm
+// alg, such as "CRC32", or nil if alg is unknown.
+func newChecksum(alg string) hash.Hash {
+	switch alg {
+	case "CRC32":
+		return crc32.NewIEEE()
+	case "CRC32C":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "SHA1":
+		return sha1.New()
+	case "SHA256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// setChunkedBody replaces the body of req, which has a known
+// length, with the same data in aws-chunked encoding followed by
+// a trailer holding its checksum, computed with algorithm alg,
+// and sets the header fields that announce the encoding.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html.
+func setChunkedBody(req *http.Request, alg string) error {
+	h := newChecksum(alg)
+	if h == nil {
+		return fmt.Errorf("s3util: unknown checksum algorithm %q", alg)
+	}
+	name := "x-amz-checksum-" + strings.ToLower(alg)
+	n := req.ContentLength
+	trailerLen := len(name) + 1 + base64.StdEncoding.EncodedLen(h.Size()) + 2
+
+	var body int64
+	for left := n; left > 0; left -= chunkSize {
+		k := int64(chunkSize)
+		if left < k {
+			k = left
+		}
+		body += int64(len(strconv.FormatInt(k, 16))) + 2 + k + 2
+	}
+	body += int64(len("0\r\n")) + int64(trailerLen) + 2
+
+	if enc := req.Header.Get("Content-Encoding"); enc != "" {
+		req.Header.Set("Content-Encoding", "aws-chunked,"+enc)
+	} else {
+		req.Header.Set("Content-Encoding", "aws-chunked")
+	}
+	req.Header.Set("X-Amz-Content-Sha256", "STREAMING-UNSIGNED-PAYLOAD-TRAILER")
+	req.Header.Set("X-Amz-Decoded-Content-Length", strconv.FormatInt(n, 10))
+	req.Header.Set("X-Amz-Trailer", name)
+	req.Body = &chunkedReader{r: req.Body, h: h, name: name}
+	req.ContentLength = body
+	req.GetBody = nil
+	return nil
+}
+
+// chunkedReader reads from r and returns the data in aws-chunked
+// encoding, ending with a trailer holding its checksum.
+type chunkedReader struct {
+	r    io.ReadCloser
+	h    hash.Hash
+	name string // of the trailer
+	buf  []byte // encoded data not yet returned
+	done bool
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if err := c.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// fill encodes the next chunk, or the final chunk and trailer.
+func (c *chunkedReader) fill() error {
+	chunk := make([]byte, chunkSize)
+	n, err := io.ReadFull(c.r, chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if n > 0 {
+		c.h.Write(chunk[:n])
+		c.buf = append(c.buf, strconv.FormatInt(int64(n), 16)...)
+		c.buf = append(c.buf, "\r\n"...)
+		c.buf = append(c.buf, chunk[:n]...)
+		c.buf = append(c.buf, "\r\n"...)
+	}
+	if n < chunkSize {
+		sum := base64.StdEncoding.EncodeToString(c.h.Sum(nil))
+		c.buf = append(c.buf, "0\r\n"+c.name+":"+sum+"\r\n\r\n"...)
+		c.done = true
+	}
+	return nil
+}
+
+func (c *chunkedReader) Close() error {
+	return c.r.Close()
+}
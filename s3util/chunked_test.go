@@ -0,0 +1,84 @@
+package s3util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// decodeChunked decodes an aws-chunked body,
+// returning the data and the trailer line.
+func decodeChunked(t *testing.T, b []byte) (data []byte, trailer string) {
+	r := bufio.NewReader(bytes.NewReader(b))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal("reading chunk size:", err)
+		}
+		n, err := strconv.ParseInt(strings.TrimSuffix(line, "\r\n"), 16, 64)
+		if err != nil {
+			t.Fatal("bad chunk size:", err)
+		}
+		if n == 0 {
+			break
+		}
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			t.Fatal("reading chunk:", err)
+		}
+		data = append(data, chunk[:n]...)
+	}
+	rest, _ := ioutil.ReadAll(r)
+	if !strings.HasSuffix(string(rest), "\r\n\r\n") {
+		t.Errorf("trailer %q not terminated", rest)
+	}
+	return data, strings.TrimSuffix(string(rest), "\r\n\r\n")
+}
+
+func TestPutChecksumTrailer(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 9000) // over two chunks
+	c := *DefaultConfig
+	c.ChecksumTrailer = "CRC32"
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			b, _ := ioutil.ReadAll(req.Body)
+			if int64(len(b)) != req.ContentLength {
+				t.Errorf("body is %d bytes, Content-Length %d", len(b), req.ContentLength)
+			}
+			for k, w := range map[string]string{
+				"Content-Encoding":             "aws-chunked,gzip",
+				"X-Amz-Decoded-Content-Length": strconv.Itoa(len(data)),
+				"X-Amz-Trailer":                "x-amz-checksum-crc32",
+			} {
+				if g := req.Header.Get(k); g != w {
+					t.Errorf("%s = %q want %q", k, g, w)
+				}
+			}
+			g, trailer := decodeChunked(t, b)
+			if !bytes.Equal(g, data) {
+				t.Error("decoded data differs")
+			}
+			sum := crc32.ChecksumIEEE(data)
+			w := "x-amz-checksum-crc32:" + base64.StdEncoding.EncodeToString([]byte{byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)})
+			if trailer != w {
+				t.Errorf("trailer = %q want %q", trailer, w)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Etag": {`"x"`}},
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	h := http.Header{"Content-Encoding": {"gzip"}}
+	if _, err := Put("https://b.s3.amazonaws.com/k", bytes.NewReader(data), h, &c); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+}
@@ -0,0 +1,123 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxDeleteKeys is the most keys S3 deletes in one request.
+const maxDeleteKeys = 1000
+
+// CleanPrefix deletes the objects under the S3 directory at url,
+// such as "https://mybucket.s3.amazonaws.com/staging/", that were
+// last modified more than olderThan ago, and returns the number
+// deleted. It is meant for prefixes that hold temporary objects,
+// such as those of AtomicWrite, which a crashed writer leaves
+// behind. It lists the objects a page at a time and deletes those
+// in each page with a single multi-object delete request.
+//
+// If c is nil, CleanPrefix uses DefaultConfig.
+func CleanPrefix(url string, olderThan time.Duration, c *Config) (int, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	f, err := NewFile(url, c)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	n := 0
+	for {
+		infos, err := f.ReaddirRecursive(maxDeleteKeys)
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		var keys []string
+		for _, fi := range infos {
+			st, ok := fi.Sys().(*Stat)
+			if ok && fi.ModTime().Before(cutoff) {
+				keys = append(keys, st.Key)
+			}
+		}
+		if len(keys) > 0 {
+			if err := deleteObjects(f.url, keys, c); err != nil {
+				return n, err
+			}
+			n += len(keys)
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+	}
+}
+
+type deleteRequest struct {
+	XMLName xml.Name       `xml:"Delete"`
+	Quiet   bool           `xml:"Quiet"`
+	Objects []deleteObject `xml:"Object"`
+}
+
+type deleteObject struct {
+	Key string
+}
+
+type deleteResult struct {
+	Errors []struct {
+		Key     string
+		Code    string
+		Message string
+	} `xml:"Error"`
+}
+
+// deleteObjects deletes the objects with the given keys, at most
+// maxDeleteKeys of them, from the bucket at bucketURL.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteObjects.html.
+func deleteObjects(bucketURL string, keys []string, c *Config) error {
+	d := deleteRequest{Quiet: true}
+	for _, k := range keys {
+		d.Objects = append(d.Objects, deleteObject{c.KeyPrefix + k})
+	}
+	b, err := xml.Marshal(d)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest("POST", subresourceURL(bucketURL, "delete"), bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(b)
+	r.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	resp, err := c.do(r)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	body, err := c.readXML(resp.Body)
+	if err != nil {
+		return err
+	}
+	var res deleteResult
+	if len(bytes.TrimSpace(body)) > 0 { // quiet mode may send nothing
+		if err := c.decodeXML(bytes.NewReader(body), &res); err != nil {
+			return err
+		}
+	}
+	if len(res.Errors) > 0 {
+		e := res.Errors[0]
+		err := fmt.Errorf("s3util: deleting %s: %s: %s", e.Key, e.Code, e.Message)
+		if len(res.Errors) > 1 {
+			err = fmt.Errorf("%v (and %d more)", err, len(res.Errors)-1)
+		}
+		return err
+	}
+	return nil
+}
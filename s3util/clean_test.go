@@ -0,0 +1,97 @@
+package s3util
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCleanPrefix(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().UTC().Format(time.RFC3339)
+	objs := map[string]string{
+		"staging/a.tmp-1": old,
+		"staging/a.tmp-2": recent,
+		"staging/d/b":     old,
+		"staging/c":       old,
+	}
+	var (
+		mu      sync.Mutex
+		deleted []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.Method {
+		case "GET":
+			if q.Get("delimiter") != "" {
+				t.Errorf("listing has delimiter %q", q.Get("delimiter"))
+			}
+			var keys []string
+			for k := range objs {
+				if strings.HasPrefix(k, q.Get("prefix")) && k > q.Get("marker") {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			io.WriteString(w, "<ListBucketResult>")
+			for _, k := range keys {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>1</Size></Contents>", k, objs[k])
+			}
+			io.WriteString(w, "</ListBucketResult>")
+		case "POST":
+			if _, ok := q["delete"]; !ok || r.Header.Get("Content-MD5") == "" {
+				t.Errorf("bad delete request %s %v", r.URL, r.Header)
+			}
+			var d deleteRequest
+			if err := xml.NewDecoder(r.Body).Decode(&d); err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			for _, o := range d.Objects {
+				deleted = append(deleted, o.Key)
+			}
+			mu.Unlock()
+			io.WriteString(w, "<DeleteResult></DeleteResult>")
+		}
+	}))
+	defer ts.Close()
+
+	n, err := CleanPrefix(ts.URL+"/staging/", 24*time.Hour, nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	sort.Strings(deleted)
+	w := []string{"staging/a.tmp-1", "staging/c", "staging/d/b"}
+	if n != 3 || !reflect.DeepEqual(deleted, w) {
+		t.Errorf("CleanPrefix = %d, deleted %q want 3, %q", n, deleted, w)
+	}
+}
+
+func TestDeleteObjectsErrors(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body: ioutil.NopCloser(strings.NewReader(`<DeleteResult>
+					<Error><Key>a</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>
+					<Error><Key>b</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>
+				</DeleteResult>`)),
+			}, nil
+		}),
+	}
+	err := deleteObjects("https://b.s3.amazonaws.com", []string{"a", "b"}, &c)
+	const w = "s3util: deleting a: AccessDenied: Access Denied (and 1 more)"
+	if err == nil || err.Error() != w {
+		t.Errorf("err = %v want %s", err, w)
+	}
+}
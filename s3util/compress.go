@@ -0,0 +1,30 @@
+package s3util
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// A Compressor compresses object data on the fly as it is uploaded.
+// See Config.Compressor.
+type Compressor interface {
+	// Encoding returns the value of the Content-Encoding header
+	// for objects compressed by this Compressor, such as "gzip".
+	Encoding() string
+
+	// NewWriter returns a WriteCloser that compresses data written
+	// to it and writes the result to w. Close must flush all
+	// buffered data to w but must not close w.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// Gzip is a Compressor that uses gzip at the default compression level.
+var Gzip Compressor = gzipCompressor{}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Encoding() string { return "gzip" }
+
+func (gzipCompressor) NewWriter(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
@@ -0,0 +1,51 @@
+package s3util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Concat creates the S3 object at dstURL from the concatenation
+// of the objects at srcURLs, in order. The data is copied on the
+// server with UploadPartCopy and never transits the client.
+//
+// Each source becomes one part of a multipart upload, or several
+// parts if it is over 5 GiB, the most S3 copies in one request.
+// Every source except the last must be at least 5 MiB, and there
+// may be at most 10,000 parts in all. Concat sends each source a
+// HEAD request to learn its size, and fails if a source changes
+// before it is copied.
+//
+// If c is nil, Concat uses DefaultConfig. Its Compressor,
+// DetectContentType, and Hashes settings are ignored.
+func Concat(dstURL string, srcURLs []string, c *Config) error {
+	if c == nil {
+		c = DefaultConfig
+	}
+	if len(srcURLs) == 0 {
+		return errors.New("s3util: no objects to concatenate")
+	}
+	if len(srcURLs) > maxNPart {
+		return errors.New("s3util: too many objects to concatenate")
+	}
+	cc := *c
+	cc.Compressor = nil
+	cc.DetectContentType = false
+	cc.Hashes = nil
+	u, err := newUploader(context.Background(), dstURL, nil, &cc)
+	if err != nil {
+		return err
+	}
+	for _, src := range srcURLs {
+		var resp *http.Response
+		if resp, err = head(src, &cc); err == nil {
+			err = u.copyObject(src, resp.Header.Get("Etag"), resp.ContentLength)
+		}
+		if err != nil {
+			u.setErr(err) // makes Close abort the upload
+			break
+		}
+	}
+	return u.Close()
+}
@@ -11,8 +11,17 @@ package s3util
 // TODO(kr): parse error responses; return structured data
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/kr/s3"
+	"hash"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
+	"time"
 )
 
 var DefaultConfig = &Config{
@@ -24,4 +33,337 @@ type Config struct {
 	*s3.Service
 	*s3.Keys
 	*http.Client // if nil, uses http.DefaultClient
+
+	// Compressor, if not nil, compresses the data written to
+	// objects created by Create and sets their Content-Encoding.
+	// Part sizes apply to the compressed data.
+	Compressor Compressor
+
+	// Hashes, if not nil, names hash functions, such as
+	// sha256.New, through which Create passes the data written
+	// to each new object, before any compression. When the upload
+	// completes, the hex digests are stored as user metadata under
+	// their names, so a hash named "sha256" is stored in
+	// X-Amz-Meta-Sha256. They are also reported in the upload's Result.
+	//
+	// The metadata is stored by copying the object onto itself,
+	// which adds a version in versioned buckets and gives the
+	// object a new ETag, not the multipart ETag of the upload,
+	// so not the one determined by the size given to CreateSized.
+	// The ETag and VersionId in the Result are those of the copy.
+	// Objects over 5 GiB are not copied, and their digests are
+	// only in the Result.
+	Hashes map[string]func() hash.Hash
+
+	// DetectContentType causes Create and Put to set the
+	// Content-Type of new objects that don't have one, from
+	// the key's extension or by sniffing the object's data.
+	DetectContentType bool
+
+	// OpaqueETags makes Get and GetParts treat ETags only as
+	// version identifiers, never as MD5 digests of the data to
+	// check downloads against, for S3-compatible services whose
+	// ETags look like MD5 digests but aren't.
+	OpaqueETags bool
+
+	// ChecksumTrailer, if set, makes Put send the object in
+	// aws-chunked encoding with a trailing checksum header,
+	// computed as the data is sent, for S3 to verify. It names
+	// the algorithm: "CRC32", "CRC32C", "SHA1", or "SHA256".
+	// Uploads made by Create are not affected.
+	ChecksumTrailer string
+
+	// WrapWriters, if not empty, are layers, such as encryption
+	// or throttling, put around the writers returned by Create,
+	// CreateContext, and CreateSized. The first is outermost:
+	// data written by the caller passes through WrapWriters[0],
+	// then WrapWriters[1], and so on, and then through the
+	// upload's Hashes and Compressor. Closing a layer must close
+	// the writer it wraps.
+	WrapWriters []WrapWriter
+
+	// Header holds default header fields, such as Cache-Control,
+	// Expires, and Content-Disposition, for objects created by
+	// Create and Put. A field given in the h argument of those
+	// functions replaces the default of the same name.
+	Header http.Header
+
+	// BufferPool, if not nil, supplies the part buffers of
+	// uploads made with this Config and takes them back when
+	// each part has been sent. Sharing a pool among uploads
+	// avoids allocating new multi-megabyte buffers for each one.
+	BufferPool *BufferPool
+
+	// Breaker, if not nil, is a circuit breaker through which
+	// all requests made with this Config pass. Share one Breaker
+	// among Configs to stop them all from retrying against a
+	// failing service.
+	Breaker *Breaker
+
+	// Meter, if not nil, counts the requests made with this
+	// Config and the bytes they transfer. Share one Meter among
+	// Configs to total their use.
+	Meter *Meter
+
+	// HedgeDelay, if positive, makes GET and HEAD requests that
+	// have not responded within HedgeDelay send a second, identical
+	// request. Whichever responds first is used, and the other is
+	// canceled. This trims tail latency at the cost of some extra
+	// requests; a delay near the 95th percentile latency is typical.
+	HedgeDelay time.Duration
+
+	// ExpectedBucketOwner, if set, is the account ID that must
+	// own the buckets used with this Config. It is sent in the
+	// X-Amz-Expected-Bucket-Owner header of every request, and S3
+	// refuses, with 403 Forbidden, requests for a bucket owned by
+	// another account, so that tools working across accounts
+	// can't write to a similarly named bucket by mistake. To check
+	// a single call, set the header in its h argument instead.
+	ExpectedBucketOwner string
+
+	// ReadOnly makes every request that would change anything,
+	// that is, any but GET and HEAD, fail with a *ReadOnlyError
+	// without being sent, for handing a Config to jobs that must
+	// never modify data. It takes precedence over DryRun.
+	// Presigned URLs are not affected.
+	ReadOnly bool
+
+	// DryRun, if not nil, turns off requests that change anything,
+	// such as those made by Create, Put, Copy, Delete, and the
+	// bucket configuration functions. Instead of being sent, each
+	// such request is logged to DryRun, with its credentials
+	// redacted, and answered with a made-up success. Requests that
+	// only read, GET and HEAD, are sent as usual.
+	DryRun *log.Logger
+
+	// MaxXMLBytes and MaxXMLElements limit the size of the XML
+	// responses, such as listings, decoded for requests made with
+	// this Config, so that a service listing or signing on behalf
+	// of untrusted URLs can't be made to use unbounded memory.
+	// A response over either limit fails with ErrXMLTooLarge.
+	// If zero, the limits are 32MiB and 262144 elements, far more
+	// than S3 sends. Error responses are kept only up to 64KiB.
+	MaxXMLBytes    int64
+	MaxXMLElements int
+
+	// ListRate, if not nil, limits the rate of the listing
+	// requests made by File and the functions built on it,
+	// and of the HEAD requests made by StatMany.
+	// Listings also back off and retry when S3 responds
+	// with 503 Slow Down, whether or not ListRate is set.
+	ListRate *RateLimit
+
+	// KeyPrefix, if set, is prepended to every object key used
+	// with this Config, so that a tenant of a shared bucket sees
+	// only the objects under its prefix. The object URLs given to
+	// the functions of this package, the copy sources of Copy, the
+	// listings of File, and presigned URLs are all relative to it,
+	// and keys in listings are reported without it. It usually
+	// ends in a slash, as in "tenants/acme/".
+	KeyPrefix string
+
+	// Resolve, if not nil, chooses where each request is sent,
+	// for pinning requests to particular addresses, using a zonal
+	// endpoint, or spreading load across gateway nodes. It returns
+	// the host, with an optional port, to connect to, or "" to
+	// use the request's own host. The request keeps its original
+	// Host header, which is what its signature covers. Over HTTPS,
+	// the server's certificate is checked against the returned
+	// host, so it should be a name the certificate covers, unless
+	// the transport sets TLSClientConfig.ServerName.
+	Resolve func(r *http.Request) string
+
+	// Redirects says what to do when S3 answers with a redirect,
+	// such as the 307 Temporary Redirect it sends for a new bucket
+	// addressed through the global endpoint. By default, the Client
+	// follows redirects, but it doesn't sign the requests it sends,
+	// so S3 usually refuses them with a confusing 403 Forbidden.
+	// See RedirectPolicy.
+	Redirects RedirectPolicy
+
+	// DisableHTTP2 turns off HTTP/2 in transports made by NewTransport.
+	DisableHTTP2 bool
+
+	// Proxy, RootCAs, and Certificates configure transports made
+	// by NewTransport, for S3-compatible services reached through
+	// a proxy or using a private certificate authority.
+	// Proxy is the URL of an HTTP proxy; if nil, the proxy is taken
+	// from the environment. RootCAs holds the certificate authorities
+	// used to verify servers; if nil, the host's are used.
+	// Certificates are presented to servers that ask for a client
+	// certificate.
+	Proxy        *url.URL
+	RootCAs      *x509.CertPool
+	Certificates []tls.Certificate
+}
+
+// A WrapWriter returns a writer that transforms data
+// and writes the result to w. See Config.WrapWriters.
+type WrapWriter func(w io.WriteCloser) io.WriteCloser
+
+// wrap returns u inside c's WrapWriters, if any.
+func (c *Config) wrap(u *Uploader) io.WriteCloser {
+	if len(c.WrapWriters) == 0 {
+		return u
+	}
+	var w io.WriteCloser = u
+	for i := len(c.WrapWriters) - 1; i >= 0; i-- {
+		w = c.WrapWriters[i](w)
+	}
+	return &wrappedUploader{w, u}
+}
+
+// A wrappedUploader is an Uploader inside WrapWriters,
+// which remembers the Uploader for AsUploader.
+type wrappedUploader struct {
+	io.WriteCloser
+	u *Uploader
+}
+
+// AsUploader returns the Uploader underneath w, a writer returned
+// by Create or one of its variants, even if w is wrapped by
+// Config.WrapWriters, so that the upload can be aborted or its
+// Result read. It returns nil if w has no Uploader.
+func AsUploader(w io.Writer) *Uploader {
+	switch w := w.(type) {
+	case *Uploader:
+		return w
+	case *wrappedUploader:
+		return w.u
+	}
+	return nil
+}
+
+// objectHeader returns a new header containing c.Header
+// overridden by the entries in h.
+func (c *Config) objectHeader(h http.Header) http.Header {
+	oh := make(http.Header)
+	for k, vs := range c.Header {
+		oh[http.CanonicalHeaderKey(k)] = append([]string(nil), vs...)
+	}
+	for k := range h {
+		oh.Del(k)
+	}
+	for k, vs := range h {
+		for _, v := range vs {
+			oh.Add(k, v)
+		}
+	}
+	return oh
+}
+
+func (c *Config) do(r *http.Request) (*http.Response, error) {
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	client = c.redirectClient(client)
+	c.scope(r)
+	if sigV4Only(r.URL.Host) {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, ErrSigV4Required
+	}
+	write := r.Method != "GET" && r.Method != "HEAD"
+	if write && c.ReadOnly {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, &ReadOnlyError{Method: r.Method, URL: r.URL.String()}
+	}
+	c.sign(r)
+	if write && c.DryRun != nil {
+		return dryRun(c.DryRun, r)
+	}
+	c.resolve(r)
+	probe, err := c.Breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		resp *http.Response
+		n    *opCount
+	)
+	for hops := 0; ; hops++ {
+		// Count each request sent: every hop of a redirect,
+		// and both copies of a hedged request.
+		n = c.Meter.count(r)
+		if c.HedgeDelay > 0 && (r.Method == "GET" || r.Method == "HEAD") {
+			hr := r
+			resp, err = hedge(client, r, c.HedgeDelay, func() { c.Meter.count(hr) })
+		} else {
+			resp, err = client.Do(r)
+		}
+		if err != nil || hops == maxRedirects {
+			break
+		}
+		next, rerr := c.redirect(r, resp)
+		if rerr != nil {
+			closeBody(resp.Body)
+			resp, err = nil, rerr
+			break
+		}
+		if next == nil {
+			break
+		}
+		r = next
+	}
+	if err != nil && r.Context().Err() != nil {
+		// The caller's doing, not the service's.
+		c.Breaker.release(probe)
+	} else {
+		c.Breaker.record(probe, requestOK(resp, err))
+	}
+	meterBody(n, resp)
+	if err == nil {
+		if err = c.checkOKBody(r, resp); err != nil {
+			resp = nil
+		}
+	}
+	return resp, err
+}
+
+// sign refreshes the Date header of r, adds c's expected bucket
+// owner, and signs r again, just before it is sent, so that a
+// request built before a long wait, such as the backoff of a
+// retry, is still within the 15 minutes S3 allows between a
+// request's date and its arrival. Requests dated by X-Amz-Date
+// keep their date.
+func (c *Config) sign(r *http.Request) {
+	if _, ok := r.Header["X-Amz-Date"]; !ok {
+		r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	if c.ExpectedBucketOwner != "" && r.Header.Get("X-Amz-Expected-Bucket-Owner") == "" {
+		r.Header.Set("X-Amz-Expected-Bucket-Owner", c.ExpectedBucketOwner)
+	}
+	c.Sign(r, *c.Keys)
+}
+
+// resolve sends r to the host chosen by c.Resolve, if any,
+// keeping its Host header.
+func (c *Config) resolve(r *http.Request) {
+	if c.Resolve == nil {
+		return
+	}
+	h := c.Resolve(r)
+	if h == "" || h == r.URL.Host {
+		return
+	}
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+	u := *r.URL
+	u.Host = h
+	r.URL = &u
+}
+
+// typeByExtension returns the MIME type for the extension
+// of the key in rawurl, or "" if it is unknown.
+func typeByExtension(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return ""
+	}
+	return mime.TypeByExtension(path.Ext(u.Path))
 }
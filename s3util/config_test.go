@@ -0,0 +1,97 @@
+package s3util
+
+import (
+	"github.com/kr/s3"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoResigns(t *testing.T) {
+	c := *DefaultConfig
+	c.Keys = &s3.Keys{AccessKey: "id", SecretKey: "secret"}
+	var got *http.Request
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			got = req
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	r, _ := http.NewRequest("GET", "https://b.s3.amazonaws.com/k", nil)
+	stale := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	r.Header.Set("Date", stale)
+	c.Sign(r, *c.Keys)
+	resp, err := c.do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	d, err := http.ParseTime(got.Header.Get("Date"))
+	if err != nil || time.Since(d) > time.Minute {
+		t.Errorf("Date = %q, want about now", got.Header.Get("Date"))
+	}
+	check := got.Clone(got.Context())
+	c.Sign(check, *c.Keys)
+	if g, w := got.Header.Get("Authorization"), check.Header.Get("Authorization"); g != w {
+		t.Errorf("Authorization = %q want %q, for the new date", g, w)
+	}
+}
+
+func TestExpectedBucketOwner(t *testing.T) {
+	c := *DefaultConfig
+	c.ExpectedBucketOwner = "111122223333"
+	var got []string
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			got = append(got, req.Header.Get("X-Amz-Expected-Bucket-Owner"))
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	if _, err := Head("https://b.s3.amazonaws.com/k", &c); err != nil {
+		t.Fatal(err)
+	}
+	h := http.Header{"X-Amz-Expected-Bucket-Owner": {"444455556666"}}
+	if _, err := Put("https://b.s3.amazonaws.com/k", strings.NewReader("x"), h, &c); err != nil {
+		t.Fatal(err)
+	}
+	w := []string{"111122223333", "444455556666"}
+	if strings.Join(got, " ") != strings.Join(w, " ") {
+		t.Errorf("owners = %q want %q", got, w)
+	}
+}
+
+func TestDoSignsOnce(t *testing.T) {
+	c := *DefaultConfig
+	c.ExpectedBucketOwner = "111122223333"
+	svc := *c.Service
+	var audited []string
+	svc.Audit = func(r *http.Request, stringToSign, sig string) {
+		audited = append(audited, stringToSign)
+	}
+	c.Service = &svc
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	if _, err := Put("https://b.s3.amazonaws.com/k", strings.NewReader("x"), nil, &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(audited) != 1 {
+		t.Fatalf("audited %d times want 1", len(audited))
+	}
+	if !strings.Contains(audited[0], "x-amz-expected-bucket-owner:111122223333") {
+		t.Errorf("string to sign %q lacks the expected bucket owner", audited[0])
+	}
+}
@@ -0,0 +1,99 @@
+package s3util
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+)
+
+// Copy copies the S3 object at src to dst without transferring
+// its data through the client. Both URLs must refer to the
+// service in c. A single copy request is limited by S3 to
+// objects of at most 5 GiB.
+//
+// If h is not nil, each of its entries is added to the HTTP request header.
+// By default the object's metadata is copied along with it; to replace
+// it, set X-Amz-Metadata-Directive to REPLACE in h.
+// If c is nil, Copy uses DefaultConfig.
+func Copy(dst, src string, h http.Header, c *Config) error {
+	return CopyWithOptions(dst, src, &ObjectOptions{Extra: h}, c)
+}
+
+// CopyWithOptions is like Copy, but takes the settings of the new
+// object as typed options. Unless o.ReplaceMetadata is set, S3
+// copies the source's metadata and tags, ignoring those in o.
+func CopyWithOptions(dst, src string, o *ObjectOptions, c *Config) error {
+	if c == nil {
+		c = DefaultConfig
+	}
+	h, err := o.Header()
+	if err != nil {
+		return err
+	}
+	_, err = copyObject(dst, src, h, c)
+	return err
+}
+
+// copyObject copies src to dst, as Copy does, and returns
+// a description of the new object.
+func copyObject(dst, src string, h http.Header, c *Config) (*Result, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequest("PUT", dst, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k := range h {
+		for _, v := range h[k] {
+			r.Header.Add(k, v)
+		}
+	}
+	r.Header.Set("X-Amz-Copy-Source", c.ObjectPath(u))
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	defer closeBody(resp.Body)
+	b, err := c.readXML(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var res struct{ ETag string } // CopyObjectResult
+	if len(bytes.TrimSpace(b)) > 0 {
+		if err := c.decodeXML(bytes.NewReader(b), &res); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{
+		ETag:      etagValue(res.ETag),
+		VersionId: resp.Header.Get("X-Amz-Version-Id"),
+	}, nil
+}
+
+// Delete deletes the S3 object at url.
+// Deleting an object that doesn't exist is not an error.
+//
+// If c is nil, Delete uses DefaultConfig.
+func Delete(url string, c *Config) error {
+	if c == nil {
+		c = DefaultConfig
+	}
+	r, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 && resp.StatusCode != 204 {
+		return newRespError(resp)
+	}
+	closeBody(resp.Body)
+	return nil
+}
@@ -0,0 +1,77 @@
+package s3util
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// copiedHeaders are the fields that CopyAcross carries from
+// the source object to the destination.
+var copiedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+}
+
+// CopyAcross copies the S3 object at src, read using sc, to dst,
+// written using dc. Unlike Copy, the two may be in different
+// services or accounts, such as Amazon S3 and a MinIO server,
+// since the data is streamed through the client: it is read with
+// Get, which resumes after a broken connection, and written with a
+// multipart upload, so memory use is bounded by the upload's part
+// buffers however large the object.
+//
+// The source's Content-Type and other representation headers,
+// and its user metadata, are copied to the new object, unless h is
+// not nil, in which case h is used instead. The Compressor,
+// DetectContentType, and WrapWriters settings of dc are ignored. If dc or sc is nil,
+// DefaultConfig is used in its place.
+func CopyAcross(dst, src string, h http.Header, dc, sc *Config) (*Result, error) {
+	if dc == nil {
+		dc = DefaultConfig
+	}
+	if sc == nil {
+		sc = DefaultConfig
+	}
+	info, err := Head(src, sc)
+	if err != nil {
+		return nil, err
+	}
+	if h == nil {
+		h = make(http.Header)
+		for _, k := range copiedHeaders {
+			if v := info.Header.Get(k); v != "" {
+				h.Set(k, v)
+			}
+		}
+		for k, vs := range info.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") {
+				h[k] = vs
+			}
+		}
+	}
+	cc := *dc
+	cc.Compressor = nil
+	cc.DetectContentType = false
+	var u *Uploader
+	if info.Size >= 0 {
+		u, err = newSizedUploader(dst, info.Size, h, &cc)
+	} else {
+		u, err = newUploader(context.Background(), dst, h, &cc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Get(src, u, sc); err != nil {
+		u.Abort()
+		return nil, err
+	}
+	if err := u.Close(); err != nil {
+		return nil, err
+	}
+	return u.Result(), nil
+}
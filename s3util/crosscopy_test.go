@@ -0,0 +1,79 @@
+package s3util
+
+import (
+	"github.com/kr/s3"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCopyAcross(t *testing.T) {
+	const data = "hello, other service"
+	sc := *DefaultConfig
+	sc.Keys = &s3.Keys{AccessKey: "src", SecretKey: "src"}
+	sc.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS src:") {
+				t.Error("source request not signed with source keys")
+			}
+			h := http.Header{
+				"Content-Type":     {"text/plain"},
+				"X-Amz-Meta-Color": {"red"},
+				"Etag":             {`"abc-2"`},
+			}
+			return &http.Response{
+				StatusCode:    200,
+				Header:        h,
+				ContentLength: int64(len(data)),
+				Body:          ioutil.NopCloser(strings.NewReader(data)),
+			}, nil
+		}),
+	}
+	var (
+		got    string
+		initHd http.Header
+	)
+	dc := *DefaultConfig
+	dc.Service = &s3.Service{Domain: "minio.example.com", Bucket: s3.IdentityBucket}
+	dc.Keys = &s3.Keys{AccessKey: "dst", SecretKey: "dst"}
+	dc.DetectContentType = true
+	dc.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.Header.Get("Authorization"), "AWS dst:") {
+				t.Error("destination request not signed with destination keys")
+			}
+			resp := &http.Response{StatusCode: 200, Header: make(http.Header)}
+			var s string
+			switch q := req.URL.Query(); {
+			case q["uploads"] != nil:
+				initHd = req.Header
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case q.Get("partNumber") != "":
+				b, _ := ioutil.ReadAll(req.Body)
+				got += string(b)
+				resp.Header.Set("Etag", `"1"`)
+			default:
+				s = `<CompleteMultipartUploadResult><ETag>"x-1"</ETag></CompleteMultipartUploadResult>`
+			}
+			resp.Body = ioutil.NopCloser(strings.NewReader(s))
+			return resp, nil
+		}),
+	}
+	res, err := CopyAcross("https://b.minio.example.com/k", "https://a.s3.amazonaws.com/k", nil, &dc, &sc)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if got != data {
+		t.Errorf("uploaded %q want %q", got, data)
+	}
+	if res.ETag != "x-1" {
+		t.Errorf("ETag = %q want x-1", res.ETag)
+	}
+	if g := initHd.Get("Content-Type"); g != "text/plain" {
+		t.Errorf("Content-Type = %q want text/plain", g)
+	}
+	if g := initHd.Get("X-Amz-Meta-Color"); g != "red" {
+		t.Errorf("X-Amz-Meta-Color = %q want red", g)
+	}
+}
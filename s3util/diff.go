@@ -0,0 +1,179 @@
+package s3util
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiffResult lists the keys that differ between two trees of
+// objects, relative to the roots of the trees.
+type DiffResult struct {
+	Added   []string // only in the second tree
+	Removed []string // only in the first tree
+	Changed []string // in both, but with different contents
+}
+
+// Diff compares the objects under aURL and bURL, each a bucket or
+// a directory in one, such as "https://mybucket.s3.amazonaws.com/logs/",
+// or a local directory, given as a path or a file URL. It reports
+// the keys added, removed, and changed going from a to b, each
+// sorted.
+//
+// Objects are compared by size and, when both ETags are MD5
+// digests, by ETag; the ETag of an object uploaded in parts
+// depends on the part size, so for those only the sizes are
+// compared. A local file has the MD5 digest of its contents as its
+// ETag, computed only if needed.
+//
+// If c is nil, Diff uses DefaultConfig.
+func Diff(aURL, bURL string, c *Config) (*DiffResult, error) {
+	a, err := treeEntries(aURL, c)
+	if err != nil {
+		return nil, err
+	}
+	b, err := treeEntries(bURL, c)
+	if err != nil {
+		return nil, err
+	}
+	return diffTrees(a, b)
+}
+
+// A treeEntry describes one object in a tree compared by Diff.
+type treeEntry struct {
+	key  string // full key, or path of a local file
+	size int64
+	etag string // "" for a local file until computed
+	path string // local file path, if any
+}
+
+// md5 returns e's ETag, computing it for a local file.
+func (e *treeEntry) md5() (string, error) {
+	if e.etag != "" || e.path == "" {
+		return e.etag, nil
+	}
+	f, err := os.Open(e.path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	e.etag = hex.EncodeToString(h.Sum(nil))
+	return e.etag, nil
+}
+
+// same reports whether a and b seem to hold the same data.
+func (a *treeEntry) same(b *treeEntry) (bool, error) {
+	if a.size != b.size {
+		return false, nil
+	}
+	if strings.Contains(a.etag, "-") || strings.Contains(b.etag, "-") {
+		return true, nil
+	}
+	ae, err := a.md5()
+	if err != nil {
+		return false, err
+	}
+	be, err := b.md5()
+	if err != nil {
+		return false, err
+	}
+	return ae == be, nil
+}
+
+// diffTrees compares trees of entries keyed by relative key.
+func diffTrees(a, b map[string]*treeEntry) (*DiffResult, error) {
+	d := new(DiffResult)
+	for k, ea := range a {
+		eb, ok := b[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+			continue
+		}
+		same, err := ea.same(eb)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d, nil
+}
+
+// treeEntries lists the objects under rawurl, keyed by their
+// keys relative to it.
+func treeEntries(rawurl string, c *Config) (map[string]*treeEntry, error) {
+	if dir, ok := localDir(rawurl); ok {
+		return localEntries(dir)
+	}
+	f, err := NewFile(rawurl, c)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := f.ReaddirRecursive(0)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*treeEntry, len(infos))
+	for _, fi := range infos {
+		st := fi.(*fileInfo).sys
+		m[strings.TrimPrefix(st.Key, f.prefix)] = &treeEntry{
+			key:  keyURL(f.url, st.Key),
+			size: fi.Size(),
+			etag: st.ETag,
+		}
+	}
+	return m, nil
+}
+
+// localDir reports whether rawurl names a local directory,
+// and if so, returns its path.
+func localDir(rawurl string) (string, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 { // path or drive letter
+		return rawurl, true
+	}
+	if u.Scheme == "file" {
+		return filepath.FromSlash(u.Path), true
+	}
+	return "", false
+}
+
+// localEntries lists the regular files under dir.
+func localEntries(dir string) (map[string]*treeEntry, error) {
+	m := make(map[string]*treeEntry)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		m[filepath.ToSlash(rel)] = &treeEntry{key: p, size: info.Size(), path: p}
+		return nil
+	})
+	return m, err
+}
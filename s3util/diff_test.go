@@ -0,0 +1,81 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var mu sync.Mutex
+	objs := map[string]manifestObj{
+		"a/same":    {"x", "", ""},
+		"a/changed": {"1", "", ""},
+		"a/removed": {"r", "", ""},
+		"b/same":    {"x", "", ""},
+		"b/changed": {"22", "", ""}, // fake ETags look multipart; sizes differ
+		"b/new/one": {"n", "", ""},
+	}
+	ts := manifestServer(objs, &mu)
+	defer ts.Close()
+	d, err := Diff(ts.URL+"/a/", ts.URL+"/b/", nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := &DiffResult{
+		Added:   []string{"new/one"},
+		Removed: []string{"removed"},
+		Changed: []string{"changed"},
+	}
+	if !reflect.DeepEqual(d, w) {
+		t.Errorf("Diff = %+v want %+v", d, w)
+	}
+}
+
+func TestDiffLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3util-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "same"), []byte("x"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "changed"), []byte("2"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "local"), []byte("l"), 0644)
+
+	var mu sync.Mutex
+	objs := map[string]manifestObj{
+		"p/same":        {"x", "", ""},
+		"p/sub/changed": {"1", "", ""},
+		"p/remote":      {"r", "", ""},
+	}
+	ts := manifestServer(objs, &mu)
+	defer ts.Close()
+	// The fake server's ETags are not MD5 digests, so give
+	// the remote side ETags that match the local files.
+	a, err := treeEntries(ts.URL+"/p/", nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	a["same"].etag = "9dd4e461268c8034f5c8564e155c67a6"        // md5("x")
+	a["sub/changed"].etag = "c4ca4238a0b923820dcc509a6f75849b" // md5("1")
+	b, err := treeEntries(dir, nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	d, err := diffTrees(a, b)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := &DiffResult{
+		Added:   []string{"local"},
+		Removed: []string{"remote"},
+		Changed: []string{"sub/changed"},
+	}
+	if !reflect.DeepEqual(d, w) {
+		t.Errorf("Diff = %+v want %+v", d, w)
+	}
+}
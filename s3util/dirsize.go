@@ -0,0 +1,49 @@
+package s3util
+
+import (
+	"os"
+)
+
+// DirStat describes the contents of a directory. It is returned
+// by the Sys method of directory FileInfos from a File with
+// DirSizes set.
+type DirStat struct {
+	Prefix  string // key prefix of the directory, ending in "/"
+	Objects int64  // number of objects beneath the directory
+	Size    int64  // total size of those objects, in bytes
+}
+
+// addDirStats sets the DirStat of each directory in infos,
+// listing up to concurrency directories at once.
+func (f *File) addDirStats(infos []os.FileInfo) error {
+	return forEach(len(infos), func(i int) error {
+		fi := infos[i].(*fileInfo)
+		if !fi.dir {
+			return nil
+		}
+		ds, err := f.dirStat(fi.name + "/")
+		if err != nil {
+			return err
+		}
+		fi.dirStat = ds
+		fi.size = ds.Size
+		return nil
+	})
+}
+
+// dirStat totals the objects under prefix, in the same bucket as f.
+func (f *File) dirStat(prefix string) (*DirStat, error) {
+	sub := &File{url: f.url, prefix: prefix, config: f.config}
+	ds := &DirStat{Prefix: prefix}
+	for sub.result == nil || sub.result.IsTruncated {
+		infos, err := sub.readPage(0, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range infos {
+			ds.Objects++
+			ds.Size += fi.Size()
+		}
+	}
+	return ds, nil
+}
@@ -0,0 +1,61 @@
+package s3util
+
+import (
+	"fmt"
+	"github.com/kr/s3"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// dryETag is the ETag of the empty object, given to
+// parts and objects written in a dry run.
+const dryETag = `"d41d8cd98f00b204e9800998ecf8427e"`
+
+// dryRun logs r to l and returns a response like the one
+// S3 would send for a successful request of its kind.
+func dryRun(l *log.Logger, r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	h := s3.RedactHeader(r.Header)
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "dry run: %s %s", r.Method, r.URL)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(&b, "\n\t%s: %s", k, v)
+		}
+	}
+	l.Print(b.String())
+
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Request:    r,
+	}
+	var body string
+	q := r.URL.Query()
+	switch {
+	case r.Method == "POST" && q["uploads"] != nil:
+		body = "<InitiateMultipartUploadResult><UploadId>dry-run</UploadId></InitiateMultipartUploadResult>"
+	case r.Method == "POST" && q.Get("uploadId") != "":
+		body = "<CompleteMultipartUploadResult><ETag>" + dryETag + "</ETag></CompleteMultipartUploadResult>"
+	case r.Method == "POST" && q["delete"] != nil:
+		body = "<DeleteResult></DeleteResult>"
+	case r.Method == "PUT" && r.Header.Get("X-Amz-Copy-Source") != "":
+		body = "<CopyObjectResult><ETag>" + dryETag + "</ETag></CopyObjectResult>"
+	case r.Method == "PUT":
+		resp.Header.Set("Etag", dryETag)
+	case r.Method == "DELETE":
+		resp.StatusCode = 204
+	}
+	resp.Body = ioutil.NopCloser(strings.NewReader(body))
+	return resp, nil
+}
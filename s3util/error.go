@@ -2,11 +2,42 @@ package s3util
 
 import (
 	"bytes"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"net/http"
 )
 
+// ErrPreconditionFailed is returned when S3 responds with
+// 412 Precondition Failed to a conditional request, such as a
+// Put with If-None-Match: * of an object that already exists.
+var ErrPreconditionFailed = errors.New("s3util: precondition failed")
+
+// ErrAborted is returned by the Write and Close methods of an
+// Uploader that has been stopped by a call to Abort.
+var ErrAborted = errors.New("s3util: upload aborted")
+
+// A ReadOnlyError is returned in place of sending a request
+// that would change something, such as a PUT, POST, or DELETE,
+// with a Config whose ReadOnly field is set. It satisfies
+// errors.Is(err, fs.ErrPermission).
+type ReadOnlyError struct {
+	Method string
+	URL    string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return "s3util: read-only config: refusing " + e.Method + " " + e.URL
+}
+
+// Is reports whether target is fs.ErrPermission.
+func (e *ReadOnlyError) Is(target error) bool {
+	return target == fs.ErrPermission
+}
+
 type respError struct {
 	r *http.Response
 	b bytes.Buffer
@@ -15,8 +46,8 @@ type respError struct {
 func newRespError(r *http.Response) *respError {
 	e := new(respError)
 	e.r = r
-	io.Copy(&e.b, r.Body)
-	r.Body.Close()
+	io.Copy(&e.b, io.LimitReader(r.Body, maxErrorBody))
+	closeBody(r.Body)
 	return e
 }
 
@@ -27,3 +58,90 @@ func (e *respError) Error() string {
 		e.b.String(),
 	)
 }
+
+// Is reports whether the response's status corresponds to target,
+// so that callers can test for common failures with errors.Is.
+func (e *respError) Is(target error) bool {
+	switch e.r.StatusCode {
+	case 403:
+		return target == fs.ErrPermission
+	case 404:
+		return target == fs.ErrNotExist
+	case 412:
+		return target == ErrPreconditionFailed
+	}
+	return false
+}
+
+// xmlError is an error reported by S3 in an XML Error element.
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html.
+type xmlError struct {
+	Code      string
+	Message   string
+	RequestId string
+}
+
+func (e *xmlError) Error() string {
+	return fmt.Sprintf("s3 error %s: %s", e.Code, e.Message)
+}
+
+// transient reports whether e is an error S3 reports when it is
+// briefly unable to serve a request, which may succeed if sent again.
+func (e *xmlError) transient() bool {
+	switch e.Code {
+	case "InternalError", "SlowDown", "ServiceUnavailable":
+		return true
+	}
+	return false
+}
+
+// checkErrorBody returns an *xmlError if b, the body of
+// an otherwise successful response, is an XML Error element.
+// Some S3 operations report errors this way.
+func checkErrorBody(b []byte) error {
+	var v struct {
+		XMLName xml.Name
+		xmlError
+	}
+	if xml.Unmarshal(b, &v) != nil || v.XMLName.Local != "Error" {
+		return nil
+	}
+	return &v.xmlError
+}
+
+// errorInBody reports whether S3 may answer r with a 200 response
+// whose body is an XML Error element, as it does for copies,
+// which include UploadPartCopy, and for completing a multipart
+// upload and deleting multiple objects.
+func errorInBody(r *http.Request) bool {
+	switch r.Method {
+	case "PUT":
+		return r.Header.Get("X-Amz-Copy-Source") != ""
+	case "POST":
+		q := r.URL.Query()
+		_, del := q["delete"]
+		_, complete := q["uploadId"]
+		return del || complete
+	}
+	return false
+}
+
+// checkOKBody reads the body of resp, a response to r, if S3
+// may report an error in it despite a 200 status, and returns
+// the error if it does. Otherwise it puts the body back in resp
+// for the caller to read.
+func (c *Config) checkOKBody(r *http.Request, resp *http.Response) error {
+	if resp.StatusCode != 200 || !errorInBody(r) {
+		return nil
+	}
+	b, err := c.readXML(resp.Body)
+	closeBody(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := checkErrorBody(b); err != nil {
+		return err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return nil
+}
@@ -0,0 +1,83 @@
+package s3util
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRespErrorIs(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		target error
+		w      bool
+	}{
+		{403, fs.ErrPermission, true},
+		{404, fs.ErrNotExist, true},
+		{412, ErrPreconditionFailed, true},
+		{404, fs.ErrPermission, false},
+		{500, fs.ErrNotExist, false},
+	} {
+		err := newRespError(&http.Response{
+			StatusCode: test.status,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		})
+		if g := errors.Is(err, test.target); g != test.w {
+			t.Errorf("errors.Is(%d, %v) = %v want %v", test.status, test.target, g, test.w)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	c := *DefaultConfig
+	c.ReadOnly = true
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != "GET" && req.Method != "HEAD" {
+				t.Errorf("%s %s was sent", req.Method, req.URL)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	const u = "https://b.s3.amazonaws.com/k"
+	_, err := Put(u, strings.NewReader("x"), nil, &c)
+	if e, ok := err.(*ReadOnlyError); !ok || e.Method != "PUT" || e.URL != u {
+		t.Errorf("Put err = %#v want *ReadOnlyError for PUT %s", err, u)
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("errors.Is(%v, fs.ErrPermission) = false", err)
+	}
+	if err := Delete(u, &c); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Delete err = %v want read-only error", err)
+	}
+	if _, err := Create(u, nil, &c); err == nil {
+		t.Error("Create succeeded")
+	}
+	if _, err := Head(u, &c); err != nil {
+		t.Errorf("Head err = %v", err)
+	}
+}
+
+func TestCopyErrorBody(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body: ioutil.NopCloser(strings.NewReader(
+					"\n<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>",
+				)),
+			}, nil
+		}),
+	}
+	err := Copy("https://b.s3.amazonaws.com/dst", "https://b.s3.amazonaws.com/src", nil, &c)
+	if e, ok := err.(*xmlError); !ok || e.Code != "SlowDown" {
+		t.Errorf("err = %v want SlowDown *xmlError", err)
+	}
+}
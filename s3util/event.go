@@ -0,0 +1,93 @@
+package s3util
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// An Event is an S3 event notification, as delivered to SNS, SQS,
+// and Lambda. The test message S3 sends when notifications are
+// configured decodes to an Event with no Records.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/notification-content-structure.html.
+type Event struct {
+	Records []EventRecord
+}
+
+// An EventRecord describes one change to an object.
+type EventRecord struct {
+	EventVersion string
+	EventSource  string
+	AWSRegion    string `json:"awsRegion"`
+	EventTime    time.Time
+	EventName    string // such as "ObjectCreated:Put"
+
+	UserIdentity struct {
+		PrincipalId string
+	}
+	RequestParameters struct {
+		SourceIPAddress string `json:"sourceIPAddress"`
+	}
+	ResponseElements map[string]string
+
+	S3 struct {
+		SchemaVersion   string `json:"s3SchemaVersion"`
+		ConfigurationId string
+		Bucket          EventBucket
+		Object          EventObject
+	}
+}
+
+// EventBucket identifies the bucket of an EventRecord.
+type EventBucket struct {
+	Name          string
+	ARN           string `json:"arn"`
+	OwnerIdentity struct {
+		PrincipalId string
+	}
+}
+
+// EventObject identifies the object of an EventRecord.
+type EventObject struct {
+	Key       string // decoded; see DecodeEvent
+	Size      int64
+	ETag      string `json:"eTag"`
+	VersionId string
+	Sequencer string
+}
+
+// DecodeEvent decodes an S3 event notification from r.
+// Object keys, which S3 sends URL-encoded, are decoded.
+func DecodeEvent(r io.Reader) (*Event, error) {
+	e := new(Event)
+	if err := json.NewDecoder(r).Decode(e); err != nil {
+		return nil, err
+	}
+	for i := range e.Records {
+		o := &e.Records[i].S3.Object
+		k, err := url.QueryUnescape(o.Key)
+		if err != nil {
+			return nil, err
+		}
+		o.Key = k
+	}
+	return e, nil
+}
+
+// URL returns the URL of the record's object in c's service,
+// suitable for Open, Get, and the other functions of this package.
+// The bucket's base URL comes from c.BucketURL, with the record's
+// region. If c is nil, URL uses DefaultConfig.
+func (r *EventRecord) URL(c *Config) string {
+	if c == nil {
+		c = DefaultConfig
+	}
+	u, err := url.Parse(c.BucketURL(r.S3.Bucket.Name, r.AWSRegion))
+	if err != nil {
+		return ""
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + r.S3.Object.Key
+	return u.String()
+}
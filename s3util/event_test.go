@@ -0,0 +1,69 @@
+package s3util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testEvent = `{
+  "Records": [{
+    "eventVersion": "2.1",
+    "eventSource": "aws:s3",
+    "awsRegion": "us-west-2",
+    "eventTime": "2024-05-01T12:34:56.789Z",
+    "eventName": "ObjectCreated:Put",
+    "userIdentity": {"principalId": "AWS:AIDAEXAMPLE"},
+    "requestParameters": {"sourceIPAddress": "192.0.2.1"},
+    "responseElements": {"x-amz-request-id": "C3D13FE58DE4C810"},
+    "s3": {
+      "s3SchemaVersion": "1.0",
+      "configurationId": "uploads",
+      "bucket": {
+        "name": "photos",
+        "ownerIdentity": {"principalId": "A3NL1KOZZKExample"},
+        "arn": "arn:aws:s3:::photos"
+      },
+      "object": {
+        "key": "2024/holiday+caf%C3%A9%3F.jpg",
+        "size": 1024,
+        "eTag": "d41d8cd98f00b204e9800998ecf8427e",
+        "versionId": "096fKKXTRTtl3on89fVO.nfljtsv6qko",
+        "sequencer": "0055AED6DCD90281E5"
+      }
+    }
+  }]
+}`
+
+func TestDecodeEvent(t *testing.T) {
+	e, err := DecodeEvent(strings.NewReader(testEvent))
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if len(e.Records) != 1 {
+		t.Fatalf("got %d records want 1", len(e.Records))
+	}
+	r := &e.Records[0]
+	if r.EventName != "ObjectCreated:Put" || r.AWSRegion != "us-west-2" {
+		t.Errorf("EventName, AWSRegion = %q, %q", r.EventName, r.AWSRegion)
+	}
+	if w := time.Date(2024, 5, 1, 12, 34, 56, 789e6, time.UTC); !r.EventTime.Equal(w) {
+		t.Errorf("EventTime = %v want %v", r.EventTime, w)
+	}
+	o := r.S3.Object
+	if o.Key != "2024/holiday café?.jpg" {
+		t.Errorf("Key = %q", o.Key)
+	}
+	if o.Size != 1024 || o.VersionId != "096fKKXTRTtl3on89fVO.nfljtsv6qko" || o.ETag == "" {
+		t.Errorf("Object = %+v", o)
+	}
+	if r.S3.Bucket.Name != "photos" || r.S3.Bucket.OwnerIdentity.PrincipalId != "A3NL1KOZZKExample" {
+		t.Errorf("Bucket = %+v", r.S3.Bucket)
+	}
+	if r.ResponseElements["x-amz-request-id"] != "C3D13FE58DE4C810" {
+		t.Errorf("ResponseElements = %v", r.ResponseElements)
+	}
+	if g, w := r.URL(nil), "https://photos.s3.amazonaws.com/2024/holiday%20caf%C3%A9%3F.jpg"; g != w {
+		t.Errorf("URL = %q want %q", g, w)
+	}
+}
@@ -25,7 +25,7 @@ func ExampleOpen() {
 	w.Close()
 }
 
-func ExampleReaddir() {
+func ExampleFile_Readdir() {
 	s3util.DefaultConfig.AccessKey = os.Getenv("S3_ACCESS_KEY")
 	s3util.DefaultConfig.SecretKey = os.Getenv("S3_SECRET_KEY")
 	f, err := s3util.NewFile("https://examle.s3.amazonaws.com/foo", nil)
@@ -0,0 +1,93 @@
+package s3util
+
+import (
+	"io"
+	"sync"
+)
+
+// fanoutDepth is the number of writes buffered for each consumer
+// of a fan-out before Write blocks.
+const fanoutDepth = 8
+
+// GetMulti is like Get, but copies the object to each of ws.
+// The object is downloaded once, and the writers are run
+// concurrently, each in its own goroutine, so that for example a
+// file on disk and a hash or scanning pipeline can be fed from a
+// single request. A writer may fall behind the download by a
+// few writes; beyond that, the download waits for it. If any
+// writer fails, the download stops and GetMulti returns that
+// writer's error. GetMulti returns once every writer has finished.
+//
+// If c is nil, GetMulti uses DefaultConfig.
+func GetMulti(url string, ws []io.Writer, c *Config) (int64, error) {
+	f := newFanout(ws)
+	n, err := Get(url, f, c)
+	if cerr := f.close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+type fanout struct {
+	chs []chan []byte
+	wg  sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+func newFanout(ws []io.Writer) *fanout {
+	f := new(fanout)
+	for _, w := range ws {
+		ch := make(chan []byte, fanoutDepth)
+		f.chs = append(f.chs, ch)
+		f.wg.Add(1)
+		go f.consume(w, ch)
+	}
+	return f
+}
+
+func (f *fanout) consume(w io.Writer, ch <-chan []byte) {
+	defer f.wg.Done()
+	for b := range ch {
+		if f.getErr() != nil {
+			continue // drain, so Write doesn't block
+		}
+		if _, err := w.Write(b); err != nil {
+			f.mu.Lock()
+			if f.err == nil {
+				f.err = err
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+func (f *fanout) getErr() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// Write queues a copy of p for each writer. It blocks while
+// any writer's queue is full.
+func (f *fanout) Write(p []byte) (int, error) {
+	if err := f.getErr(); err != nil {
+		return 0, err
+	}
+	b := append([]byte(nil), p...) // shared read-only by the consumers
+	for _, ch := range f.chs {
+		ch <- b
+	}
+	return len(p), nil
+}
+
+// close waits for the writers to finish and returns
+// the first error from any of them.
+func (f *fanout) close() error {
+	for _, ch := range f.chs {
+		close(ch)
+	}
+	f.wg.Wait()
+	return f.getErr()
+}
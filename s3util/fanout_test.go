@@ -0,0 +1,62 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowWriter writes to w after a delay.
+type slowWriter struct {
+	w io.Writer
+	d time.Duration
+}
+
+func (s slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.d)
+	return s.w.Write(p)
+}
+
+func TestGetMulti(t *testing.T) {
+	data := strings.Repeat("0123456789", 100000)
+	var nreq int
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			nreq++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(data)),
+			}, nil
+		}),
+	}
+	var buf, slow bytes.Buffer
+	h := sha256.New()
+	ws := []io.Writer{&buf, h, slowWriter{&slow, time.Microsecond}}
+	n, err := GetMulti("https://s3.amazonaws.com/foo/bar", ws, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if n != int64(len(data)) || buf.String() != data || slow.String() != data {
+		t.Errorf("got %d bytes, %d, %d want %d", n, buf.Len(), slow.Len(), len(data))
+	}
+	if g, w := fmt.Sprintf("%x", h.Sum(nil)), fmt.Sprintf("%x", sha256.Sum256([]byte(data))); g != w {
+		t.Errorf("hash = %s want %s", g, w)
+	}
+	if nreq != 1 {
+		t.Errorf("sent %d requests want 1", nreq)
+	}
+
+	werr := errors.New("scan failed")
+	ws = []io.Writer{ioutil.Discard, errWriter{werr}}
+	if _, err := GetMulti("https://s3.amazonaws.com/foo/bar", ws, &c); err != werr {
+		t.Errorf("err = %v want %v", err, werr)
+	}
+}
@@ -0,0 +1,47 @@
+package s3util
+
+import (
+	"os"
+)
+
+// A Filter selects objects in a listing by properties that S3
+// cannot filter on itself. It is applied to each page of results
+// as it arrives. Directories are always kept.
+type Filter struct {
+	// StorageClasses, if not empty, keeps only objects in one of
+	// the given storage classes, such as "GLACIER". S3 reports
+	// objects in the default class as "STANDARD".
+	StorageClasses []string
+
+	// OwnerID, if not empty, keeps only objects whose owner has
+	// this canonical user ID.
+	OwnerID string
+}
+
+// match reports whether the object described by st passes f.
+func (f *Filter) match(st *Stat) bool {
+	if f.OwnerID != "" && st.Owner.ID != f.OwnerID {
+		return false
+	}
+	if len(f.StorageClasses) == 0 {
+		return true
+	}
+	for _, sc := range f.StorageClasses {
+		if st.StorageClass == sc {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the entries of infos that pass f,
+// reusing the storage of infos.
+func (f *Filter) apply(infos []os.FileInfo) []os.FileInfo {
+	kept := infos[:0]
+	for _, fi := range infos {
+		if fi := fi.(*fileInfo); fi.dir || f.match(fi.sys) {
+			kept = append(kept, fi)
+		}
+	}
+	return kept
+}
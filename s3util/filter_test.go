@@ -0,0 +1,76 @@
+package s3util
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	pages := map[string]string{
+		"": `<ListBucketResult><IsTruncated>true</IsTruncated>
+			<Contents><Key>a</Key><StorageClass>STANDARD</StorageClass><Owner><ID>o1</ID></Owner></Contents>
+			<Contents><Key>b</Key><StorageClass>GLACIER</StorageClass><Owner><ID>o2</ID></Owner></Contents>
+			</ListBucketResult>`,
+		"b": `<ListBucketResult><IsTruncated>true</IsTruncated>
+			<Contents><Key>c</Key><StorageClass>STANDARD</StorageClass><Owner><ID>o2</ID></Owner></Contents>
+			</ListBucketResult>`,
+		"c": `<ListBucketResult><IsTruncated>false</IsTruncated>
+			<Contents><Key>d</Key><StorageClass>GLACIER</StorageClass><Owner><ID>o1</ID></Owner></Contents>
+			<CommonPrefixes><Prefix>e/</Prefix></CommonPrefixes>
+			</ListBucketResult>`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, pages[r.URL.Query().Get("marker")])
+	}))
+	defer ts.Close()
+
+	list := func(flt *Filter, read func(*File) ([]os.FileInfo, error)) string {
+		f, err := NewFile(ts.URL+"/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Filter = flt
+		fis, err := read(f)
+		if err != nil && err != io.EOF {
+			t.Fatal("unexpected err", err)
+		}
+		var a []string
+		for _, fi := range fis {
+			a = append(a, fi.Name())
+		}
+		return strings.Join(a, " ")
+	}
+	all := func(f *File) ([]os.FileInfo, error) {
+		var fis []os.FileInfo
+		for fi, err := range f.All() {
+			if err != nil {
+				return fis, err
+			}
+			fis = append(fis, fi)
+		}
+		return fis, nil
+	}
+	first := func(f *File) ([]os.FileInfo, error) { return f.Readdir(10) }
+
+	cases := []struct {
+		flt  *Filter
+		read func(*File) ([]os.FileInfo, error)
+		w    string
+	}{
+		{nil, all, "a b c d e"},
+		{&Filter{StorageClasses: []string{"GLACIER"}}, all, "b d e"},
+		{&Filter{OwnerID: "o1"}, all, "a d e"},
+		{&Filter{OwnerID: "o1", StorageClasses: []string{"GLACIER", "DEEP_ARCHIVE"}}, all, "d e"},
+		// The first two pages hold no match; Readdir skips them.
+		{&Filter{OwnerID: "o1", StorageClasses: []string{"GLACIER"}}, first, "d e"},
+	}
+	for _, c := range cases {
+		if g := list(c.flt, c.read); g != c.w {
+			t.Errorf("filter %+v: got %q want %q", c.flt, g, c.w)
+		}
+	}
+}
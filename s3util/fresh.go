@@ -0,0 +1,103 @@
+package s3util
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObjectInfo describes an S3 object, as reported in the
+// headers of a response to a HEAD or GET request.
+type ObjectInfo struct {
+	Size         int64  // -1 if unknown
+	ETag         string // ETag value, without double quotes
+	LastModified time.Time
+	ContentType  string
+	VersionId    string            // set only in versioned buckets
+	Metadata     map[string]string // user metadata; see Metadata
+	Header       http.Header       // all response headers
+
+	// PartsCount is the number of parts of an object created by
+	// a multipart upload, as reported by HeadPart, or 0.
+	PartsCount int
+}
+
+func objectInfo(resp *http.Response) *ObjectInfo {
+	t, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	parts, _ := strconv.Atoi(resp.Header.Get("X-Amz-Mp-Parts-Count"))
+	return &ObjectInfo{
+		Size:         resp.ContentLength,
+		ETag:         etagValue(resp.Header.Get("Etag")),
+		LastModified: t,
+		ContentType:  resp.Header.Get("Content-Type"),
+		VersionId:    resp.Header.Get("X-Amz-Version-Id"),
+		Metadata:     Metadata(resp.Header),
+		Header:       resp.Header,
+		PartsCount:   parts,
+	}
+}
+
+// Head returns information about the S3 object at url without
+// fetching its contents. If the object does not exist, the
+// error satisfies os.IsNotExist.
+//
+// If c is nil, Head uses DefaultConfig.
+func Head(url string, c *Config) (*ObjectInfo, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	resp, err := head(url, c)
+	if err != nil {
+		return nil, err
+	}
+	return objectInfo(resp), nil
+}
+
+// Fresh reports whether a locally cached copy of the S3 object at
+// url is still current, using a conditional HEAD request. The copy
+// is identified by etag, the ETag it was downloaded with, and
+// modSince, its Last-Modified time. Either may be zero; if both
+// are, the copy is never fresh.
+//
+// Fresh also returns information about the object. If the copy is
+// fresh, S3 sends only some of the object's headers, so the
+// returned ObjectInfo may be incomplete. If the object no longer
+// exists, the error satisfies os.IsNotExist.
+//
+// If c is nil, Fresh uses DefaultConfig.
+func Fresh(url, etag string, modSince time.Time, c *Config) (bool, *ObjectInfo, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	r, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	if etag != "" {
+		if !strings.HasPrefix(etag, `"`) {
+			etag = `"` + etag + `"`
+		}
+		r.Header.Set("If-None-Match", etag)
+	}
+	if !modSince.IsZero() {
+		r.Header.Set("If-Modified-Since", modSince.UTC().Format(http.TimeFormat))
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return false, nil, err
+	}
+	closeBody(resp.Body)
+	switch resp.StatusCode {
+	case 304:
+		info := objectInfo(resp)
+		info.Size = -1
+		return true, info, nil
+	case 200:
+		return false, objectInfo(resp), nil
+	case 404:
+		return false, nil, &os.PathError{Op: "head", Path: url, Err: os.ErrNotExist}
+	}
+	return false, nil, newRespError(resp)
+}
@@ -0,0 +1,70 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFresh(t *testing.T) {
+	mod := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			status := 200
+			switch {
+			case strings.HasSuffix(req.URL.Path, "/gone"):
+				status = 404
+			case req.Header.Get("If-None-Match") == `"v1"`:
+				status = 304
+			case req.Header.Get("If-Modified-Since") != "":
+				ims, _ := http.ParseTime(req.Header.Get("If-Modified-Since"))
+				if !mod.After(ims) {
+					status = 304
+				}
+			}
+			return &http.Response{
+				StatusCode:    status,
+				ContentLength: 3,
+				Header: http.Header{
+					"Etag":          {`"v1"`},
+					"Last-Modified": {mod.Format(http.TimeFormat)},
+				},
+				Body: ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	const url = "https://s3.amazonaws.com/foo/bar"
+	for _, test := range []struct {
+		etag string
+		mod  time.Time
+		w    bool
+	}{
+		{"v1", time.Time{}, true},
+		{`"v1"`, time.Time{}, true},
+		{"v0", time.Time{}, false},
+		{"", mod, true},
+		{"", mod.Add(-time.Hour), false},
+		{"", time.Time{}, false},
+	} {
+		fresh, info, err := Fresh(url, test.etag, test.mod, &c)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if fresh != test.w {
+			t.Errorf("Fresh(%q, %v) = %v want %v", test.etag, test.mod, fresh, test.w)
+		}
+		if info.ETag != "v1" || !info.LastModified.Equal(mod) {
+			t.Errorf("info = %+v", info)
+		}
+		if !fresh && info.Size != 3 {
+			t.Errorf("Size = %d want 3", info.Size)
+		}
+	}
+	if _, _, err := Fresh("https://s3.amazonaws.com/foo/gone", "v1", time.Time{}, &c); !os.IsNotExist(err) {
+		t.Errorf("err = %v want not exist", err)
+	}
+}
@@ -0,0 +1,137 @@
+package s3util
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Get copies the S3 object at url to w and returns the number of
+// bytes written. If the connection fails partway through, Get resumes
+// with a range request for the rest of the object, conditional on
+// its ETag, so that w never receives data from two different
+// versions of the object.
+//
+// If the object's ETag is the MD5 digest of its contents, as it is
+// for objects created by a single PUT without KMS or customer-provided
+// encryption keys, Get checks the data against it and reports an
+// error if they differ. By then the data has already been written to w.
+//
+// If c is nil, Get uses DefaultConfig.
+func Get(url string, w io.Writer, c *Config) (int64, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	var (
+		n       int64
+		etag    string
+		sum     hash.Hash
+		tw      = &trackWriter{w: w}
+		lasterr error
+	)
+	for i := 0; i < nTry; i++ {
+		resp, err := getFrom(url, n, etag, c)
+		if err != nil {
+			if _, ok := err.(*respError); ok {
+				return n, err
+			}
+			lasterr = err
+			continue
+		}
+		if n == 0 {
+			etag = resp.Header.Get("Etag")
+			if !c.OpaqueETags && md5ETag(resp.Header) {
+				sum = md5.New()
+				tw.w = io.MultiWriter(w, sum)
+			}
+		}
+		m, err := io.Copy(tw, resp.Body)
+		closeBody(resp.Body)
+		n += m
+		if tw.err != nil {
+			return n, tw.err
+		}
+		if err != nil {
+			lasterr = err
+			continue
+		}
+		if sum != nil {
+			if g := hex.EncodeToString(sum.Sum(nil)); g != etagValue(etag) {
+				return n, fmt.Errorf("s3util: %s: checksum mismatch: got md5 %s, ETag %s", url, g, etag)
+			}
+		}
+		return n, nil
+	}
+	return n, lasterr
+}
+
+// getFrom requests the object at url starting at offset off.
+// If off is greater than zero, the request is conditional on etag.
+func getFrom(url string, off int64, etag string, c *Config) (*http.Response, error) {
+	r, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	want := 200
+	if off > 0 {
+		r.Header.Set("Range", "bytes="+strconv.FormatInt(off, 10)+"-")
+		if etag != "" {
+			r.Header.Set("If-Match", etag)
+		}
+		want = 206
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		return nil, newRespError(resp)
+	}
+	return resp, nil
+}
+
+// etagValue returns the value of an ETag header field without
+// the quotes S3 puts around it, which some S3-compatible
+// services leave out, or any weak validator prefix.
+func etagValue(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "W/")
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// md5ETag reports whether the ETag in h is the MD5 digest
+// of the object's contents.
+func md5ETag(h http.Header) bool {
+	etag := etagValue(h.Get("Etag"))
+	if len(etag) != 2*md5.Size {
+		return false // multipart ETags end in -N
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return false
+	}
+	return h.Get("X-Amz-Server-Side-Encryption") != "aws:kms" &&
+		h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") == ""
+}
+
+// trackWriter records the first error returned by w,
+// to tell write errors apart from read errors in io.Copy.
+type trackWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (t *trackWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,139 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// failReader returns the data in r, then err.
+type failReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		err = f.err
+	}
+	return n, err
+}
+
+func TestGetResume(t *testing.T) {
+	const data = "hello, resumable world"
+	sum := md5.Sum([]byte(data))
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	for _, test := range []struct {
+		etag string
+		err  bool
+	}{
+		{etag, false},
+		{`"0123456789abcdef0123456789abcdef"`, true},
+		{`"0123456789abcdef0123456789abcdef-2"`, false}, // multipart; not checked
+	} {
+		var ranges []string
+		c := *DefaultConfig
+		c.Client = &http.Client{
+			Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				rng := req.Header.Get("Range")
+				ranges = append(ranges, rng)
+				if rng == "" {
+					return &http.Response{
+						StatusCode: 200,
+						Header:     http.Header{"Etag": {test.etag}},
+						Body: ioutil.NopCloser(&failReader{
+							strings.NewReader(data[:5]),
+							io.ErrUnexpectedEOF,
+						}),
+					}, nil
+				}
+				if req.Header.Get("If-Match") != test.etag {
+					t.Errorf("If-Match = %q want %q", req.Header.Get("If-Match"), test.etag)
+				}
+				off, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+				return &http.Response{
+					StatusCode: 206,
+					Header:     http.Header{"Etag": {test.etag}},
+					Body:       ioutil.NopCloser(strings.NewReader(data[off:])),
+				}, nil
+			}),
+		}
+		var buf bytes.Buffer
+		n, err := Get("https://s3.amazonaws.com/foo/bar", &buf, &c)
+		if (err != nil) != test.err {
+			t.Errorf("Get etag %s: err = %v want error %v", test.etag, err, test.err)
+		}
+		if n != int64(len(data)) || buf.String() != data {
+			t.Errorf("Get = %d, %q want %d, %q", n, buf.String(), len(data), data)
+		}
+		if w := []string{"", "bytes=5-"}; strings.Join(ranges, ",") != strings.Join(w, ",") {
+			t.Errorf("ranges = %q want %q", ranges, w)
+		}
+	}
+}
+
+func TestGetWriteError(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("data")),
+			}, nil
+		}),
+	}
+	werr := errors.New("disk full")
+	_, err := Get("https://s3.amazonaws.com/foo/bar", errWriter{werr}, &c)
+	if err != werr {
+		t.Errorf("err = %v want %v", err, werr)
+	}
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestETagValue(t *testing.T) {
+	for _, ts := range []struct{ s, w string }{
+		{`"abc"`, "abc"},
+		{"abc", "abc"},
+		{`W/"abc"`, "abc"},
+		{` "abc-2" `, "abc-2"},
+		{`"`, `"`},
+		{"", ""},
+	} {
+		if g := etagValue(ts.s); g != ts.w {
+			t.Errorf("etagValue(%q) = %q want %q", ts.s, g, ts.w)
+		}
+	}
+}
+
+func TestGetOpaqueETags(t *testing.T) {
+	const data = "hello"
+	wrong := md5.Sum([]byte("other"))
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Etag": {hex.EncodeToString(wrong[:])}}, // unquoted
+				Body:       ioutil.NopCloser(strings.NewReader(data)),
+			}, nil
+		}),
+	}
+	if _, err := Get("https://b.s3.amazonaws.com/k", ioutil.Discard, &c); err == nil {
+		t.Error("Get succeeded despite checksum mismatch")
+	}
+	c.OpaqueETags = true
+	if _, err := Get("https://b.s3.amazonaws.com/k", ioutil.Discard, &c); err != nil {
+		t.Errorf("Get with OpaqueETags: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package s3util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// parallelChunk is the size of the ranges GetParallel fetches.
+const parallelChunk = 8 << 20
+
+// GetParallel copies the S3 object at url to w, as Get does, but
+// fetches it in 8MiB ranges, several at once, and writes them to w
+// in order, for a large object that one connection would download
+// slowly. It holds at most a few ranges in memory at a time.
+//
+// The ranges are requested on the condition that the object still
+// has the ETag it had when GetParallel started; if the object
+// changes, GetParallel fails with ErrPreconditionFailed, and w may
+// have received part of the old version. The data is not checked
+// against the ETag.
+//
+// If c is nil, GetParallel uses DefaultConfig.
+func GetParallel(url string, w io.Writer, c *Config) (int64, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	resp, err := head(url, c)
+	if err != nil {
+		return 0, err
+	}
+	size, etag := resp.ContentLength, resp.Header.Get("Etag")
+	if size < 0 {
+		return Get(url, w, c)
+	}
+
+	type chunk struct {
+		b   []byte
+		err error
+	}
+	n := int((size + parallelChunk - 1) / parallelChunk)
+	results := make([]chan chunk, n)
+	for i := range results {
+		results[i] = make(chan chunk, 1)
+	}
+	sem := make(chan struct{}, concurrency) // chunks fetched, not yet written
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := range results {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func(i int) {
+				start := int64(i) * parallelChunk
+				end := min(start+parallelChunk, size)
+				b, err := getRange(url, etag, start, end, c)
+				results[i] <- chunk{b, err}
+			}(i)
+		}
+	}()
+
+	var written int64
+	for i := range results {
+		ch := <-results[i]
+		<-sem
+		if ch.err != nil {
+			return written, ch.err
+		}
+		m, err := w.Write(ch.b)
+		written += int64(m)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// getRange returns bytes start up to end of the object at url,
+// on the condition that it has the given ETag, retrying after
+// network errors.
+func getRange(url, etag string, start, end int64, c *Config) ([]byte, error) {
+	var lasterr error
+	for i := 0; i < nTry; i++ {
+		b, err := getRangeOnce(url, etag, start, end, c)
+		var re *respError
+		if err == nil || errors.As(err, &re) || err == ErrPreconditionFailed {
+			return b, err
+		}
+		lasterr = err
+	}
+	return nil, lasterr
+}
+
+func getRangeOnce(url, etag string, start, end int64, c *Config) ([]byte, error) {
+	r, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if etag != "" {
+		r.Header.Set("If-Match", etag)
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 412 {
+		closeBody(resp.Body)
+		return nil, ErrPreconditionFailed
+	}
+	// A server that ignores Range sends the whole object,
+	// which will do only if we wanted all of it.
+	if resp.StatusCode != 206 && !(resp.StatusCode == 200 && start == 0 && resp.ContentLength == end) {
+		return nil, newRespError(resp)
+	}
+	defer closeBody(resp.Body)
+	b := make([]byte, end-start)
+	if _, err := io.ReadFull(resp.Body, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
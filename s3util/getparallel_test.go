@@ -0,0 +1,60 @@
+package s3util
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetParallel(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*parallelChunk+1000)/16)
+	var ranges int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranges, 1)
+		}
+		w.Header().Set("Etag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	n, err := GetParallel(ts.URL+"/b/k", &buf, nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %d bytes, want %d, equal %v", n, len(data), bytes.Equal(buf.Bytes(), data))
+	}
+	if ranges != 3 {
+		t.Errorf("range requests = %d want 3", ranges)
+	}
+}
+
+func TestGetParallelChanged(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{
+				StatusCode:    200,
+				Header:        http.Header{"Etag": {`"v1"`}},
+				ContentLength: parallelChunk + 1,
+				Body:          ioutil.NopCloser(strings.NewReader("")),
+			}
+			if req.Method == "GET" && req.Header.Get("If-Match") == `"v1"` {
+				resp.StatusCode = 412 // changed since the HEAD
+			}
+			return resp, nil
+		}),
+	}
+	_, err := GetParallel("https://b.s3.amazonaws.com/k", ioutil.Discard, &c)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("err = %v want ErrPreconditionFailed", err)
+	}
+}
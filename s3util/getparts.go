@@ -0,0 +1,143 @@
+package s3util
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetParts copies the S3 object at url to w, fetching the parts
+// it was uploaded in concurrently, one request per part, and
+// returns the size of the object. Each part is written at its
+// offset in the object.
+//
+// Splitting the download along the original part boundaries lets
+// GetParts check each piece as it arrives: if S3 reports a
+// checksum for a part, the part's data is checked against it, and
+// once every part is in, the MD5 digests of the parts are combined
+// and checked against the object's multipart ETag, where that ETag
+// is derived from them. Requests for the parts are conditional on
+// the ETag of the object when the first part was examined, so if
+// the object changes during the download, GetParts returns
+// ErrPreconditionFailed.
+//
+// If c is nil, GetParts uses DefaultConfig.
+func GetParts(url string, w io.WriterAt, c *Config) (int64, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	info, sizes, err := partLayout(url, c)
+	if err != nil {
+		return 0, err
+	}
+	etag := info.Header.Get("Etag")
+	offsets := make([]int64, len(sizes))
+	var total int64
+	for i, n := range sizes {
+		offsets[i] = total
+		total += n
+	}
+	sums := make([][]byte, len(sizes))
+	err = forEach(len(sizes), func(i int) error {
+		sum, err := getPart(url, i+1, etag, io.NewOffsetWriter(w, offsets[i]), sizes[i], c)
+		sums[i] = sum
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if c.OpaqueETags {
+		return total, nil
+	}
+	if err := checkPartsETag(info.Header, sums); err != nil {
+		return total, fmt.Errorf("s3util: %s: %v", url, err)
+	}
+	return total, nil
+}
+
+// getPart copies part n of the object at url, which must have
+// the given size, to w, checking it against any checksum S3
+// reports for it, and returns its MD5 digest.
+func getPart(url string, n int, etag string, w io.Writer, size int64, c *Config) ([]byte, error) {
+	r, err := http.NewRequest("GET", url+"?partNumber="+strconv.Itoa(n), nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		r.Header.Set("If-Match", etag)
+	}
+	r.Header.Set("X-Amz-Checksum-Mode", "ENABLED")
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+	switch resp.StatusCode {
+	case 200, 206:
+	case 412:
+		return nil, ErrPreconditionFailed
+	default:
+		return nil, newRespError(resp)
+	}
+	sum := md5.New()
+	dst := []io.Writer{w, sum}
+	var (
+		check hash.Hash
+		want  string
+	)
+	for _, alg := range []string{"CRC32", "CRC32C", "SHA1", "SHA256"} {
+		v := resp.Header.Get("X-Amz-Checksum-" + alg)
+		if v != "" && !strings.Contains(v, "-") { // not a checksum of checksums
+			check, want = newChecksum(alg), v
+			dst = append(dst, check)
+			break
+		}
+	}
+	m, err := io.Copy(io.MultiWriter(dst...), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if m != size {
+		return nil, fmt.Errorf("s3util: %s: part %d: got %d bytes, want %d", url, n, m, size)
+	}
+	if check != nil {
+		if g := base64.StdEncoding.EncodeToString(check.Sum(nil)); g != want {
+			return nil, fmt.Errorf("s3util: %s: part %d: checksum mismatch: got %s, want %s", url, n, g, want)
+		}
+	}
+	return sum.Sum(nil), nil
+}
+
+// checkPartsETag checks the MD5 digests of an object's parts,
+// in sums, against the object's ETag in h, if the ETag is
+// derived from them.
+func checkPartsETag(h http.Header, sums [][]byte) error {
+	etag := etagValue(h.Get("Etag"))
+	var got string
+	if len(sums) == 1 {
+		if !md5ETag(h) {
+			return nil
+		}
+		got = hex.EncodeToString(sums[0])
+	} else {
+		if h.Get("X-Amz-Server-Side-Encryption") == "aws:kms" ||
+			h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+			return nil
+		}
+		all := md5.New()
+		for _, s := range sums {
+			all.Write(s)
+		}
+		got = hex.EncodeToString(all.Sum(nil)) + "-" + strconv.Itoa(len(sums))
+	}
+	if got != etag {
+		return fmt.Errorf("checksum mismatch: got ETag %s, want %s", got, etag)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// partServer returns a Config whose transport serves an object
+// uploaded in the given parts, answering HEAD and GET requests
+// with partNumber. If corrupt is not 0, that part's data is
+// altered in GET responses.
+func partServer(t *testing.T, parts []string, corrupt int) *Config {
+	all := md5.New()
+	for _, p := range parts {
+		s := md5.Sum([]byte(p))
+		all.Write(s[:])
+	}
+	etag := `"` + hex.EncodeToString(all.Sum(nil)) + "-" + strconv.Itoa(len(parts)) + `"`
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			n, _ := strconv.Atoi(req.URL.Query().Get("partNumber"))
+			if n < 1 || n > len(parts) {
+				t.Fatalf("bad part number %d", n)
+			}
+			p := parts[n-1]
+			h := http.Header{
+				"Etag":                 {etag},
+				"X-Amz-Mp-Parts-Count": {strconv.Itoa(len(parts))},
+			}
+			if req.Method == "GET" {
+				if g := req.Header.Get("If-Match"); g != etag {
+					t.Errorf("If-Match = %q want %q", g, etag)
+				}
+				sum := crc32.ChecksumIEEE([]byte(p))
+				h.Set("X-Amz-Checksum-Crc32", base64.StdEncoding.EncodeToString([]byte{
+					byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum),
+				}))
+				if n == corrupt {
+					p = strings.ToUpper(p)
+				}
+			}
+			return &http.Response{
+				StatusCode:    206,
+				ContentLength: int64(len(p)),
+				Header:        h,
+				Body:          ioutil.NopCloser(strings.NewReader(p)),
+			}, nil
+		}),
+	}
+	return &c
+}
+
+func TestGetParts(t *testing.T) {
+	parts := []string{"hello, ", "multipart ", "world"}
+	f, err := ioutil.TempFile("", "getparts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	n, err := GetParts("https://b.s3.amazonaws.com/k", f, partServer(t, parts, 0))
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	want := strings.Join(parts, "")
+	if n != int64(len(want)) {
+		t.Errorf("n = %d want %d", n, len(want))
+	}
+	g, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g, []byte(want)) {
+		t.Errorf("got %q want %q", g, want)
+	}
+
+	_, err = GetParts("https://b.s3.amazonaws.com/k", f, partServer(t, parts, 2))
+	if err == nil || !strings.Contains(err.Error(), "part 2: checksum mismatch") {
+		t.Errorf("err = %v want part 2 checksum mismatch", err)
+	}
+}
+
+func TestCheckPartsETag(t *testing.T) {
+	a, b := md5.Sum([]byte("a")), md5.Sum([]byte("b"))
+	sums := [][]byte{a[:], b[:]}
+	all := md5.Sum(append(a[:], b[:]...))
+	good := hex.EncodeToString(all[:]) + "-2"
+	cases := []struct {
+		h  http.Header
+		ok bool
+	}{
+		{http.Header{"Etag": {`"` + good + `"`}}, true},
+		{http.Header{"Etag": {`"` + hex.EncodeToString(a[:]) + `-2"`}}, false},
+		{http.Header{
+			"Etag":                         {`"x-2"`},
+			"X-Amz-Server-Side-Encryption": {"aws:kms"},
+		}, true},
+	}
+	for _, test := range cases {
+		err := checkPartsETag(test.h, sums)
+		if (err == nil) != test.ok {
+			t.Errorf("checkPartsETag(%v) = %v want ok %v", test.h, err, test.ok)
+		}
+	}
+}
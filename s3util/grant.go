@@ -0,0 +1,101 @@
+package s3util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// URIs of the predefined Amazon S3 groups, for use with Group.
+const (
+	AllUsers           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	AuthenticatedUsers = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+	LogDelivery        = "http://acs.amazonaws.com/groups/s3/LogDelivery"
+)
+
+// A Grantee is someone who can be granted access to an object
+// or bucket. Make one with CanonicalUser, EmailUser, or Group.
+type Grantee struct {
+	kind  string // "id", "emailAddress", or "uri"
+	value string
+}
+
+// CanonicalUser returns the grantee with the given canonical user ID.
+func CanonicalUser(id string) Grantee { return Grantee{"id", id} }
+
+// EmailUser returns the grantee with the given AWS account email
+// address. Not all regions support email grantees.
+func EmailUser(addr string) Grantee { return Grantee{"emailAddress", addr} }
+
+// Group returns the predefined group with the given URI,
+// such as AllUsers.
+func Group(uri string) Grantee { return Grantee{"uri", uri} }
+
+func (g Grantee) String() string {
+	return fmt.Sprintf("%s=%q", g.kind, g.value)
+}
+
+func (g Grantee) check() error {
+	switch {
+	case g.kind == "":
+		return errors.New("s3util: zero Grantee")
+	case g.value == "" || strings.ContainsAny(g.value, "\",\r\n"):
+		return fmt.Errorf("s3util: invalid grantee %s %q", g.kind, g.value)
+	case g.kind == "emailAddress" && !strings.Contains(g.value, "@"):
+		return fmt.Errorf("s3util: invalid grantee email address %q", g.value)
+	case g.kind == "uri" && !strings.HasPrefix(g.value, "http://acs.amazonaws.com/groups/"):
+		return fmt.Errorf("s3util: unknown grantee group %q", g.value)
+	}
+	return nil
+}
+
+// Grants lists the grantees of each permission, for the
+// x-amz-grant-* fields sent when creating or copying an object.
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/acl-overview.html.
+type Grants struct {
+	Read        []Grantee
+	Write       []Grantee // buckets only
+	ReadACP     []Grantee
+	WriteACP    []Grantee
+	FullControl []Grantee
+}
+
+// Header returns the header fields for g.
+// It returns an error if a grantee is invalid.
+func (g *Grants) Header() (http.Header, error) {
+	h := make(http.Header)
+	if err := g.addTo(h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// addTo adds the grantees in g to those already in h.
+func (g *Grants) addTo(h http.Header) error {
+	for _, f := range []struct {
+		key string
+		a   []Grantee
+	}{
+		{"X-Amz-Grant-Read", g.Read},
+		{"X-Amz-Grant-Write", g.Write},
+		{"X-Amz-Grant-Read-Acp", g.ReadACP},
+		{"X-Amz-Grant-Write-Acp", g.WriteACP},
+		{"X-Amz-Grant-Full-Control", g.FullControl},
+	} {
+		var s []string
+		if v := h.Get(f.key); v != "" {
+			s = append(s, v)
+		}
+		for _, gr := range f.a {
+			if err := gr.check(); err != nil {
+				return err
+			}
+			s = append(s, gr.String())
+		}
+		if len(s) > 0 {
+			h.Set(f.key, strings.Join(s, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package s3util
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestGrantsHeader(t *testing.T) {
+	g := &Grants{
+		Read:        []Grantee{Group(AllUsers), EmailUser("xyz@amazon.com")},
+		FullControl: []Grantee{CanonicalUser("79a59df900b949e5")},
+	}
+	h, err := g.Header()
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := http.Header{
+		"X-Amz-Grant-Read":         {`uri="http://acs.amazonaws.com/groups/global/AllUsers", emailAddress="xyz@amazon.com"`},
+		"X-Amz-Grant-Full-Control": {`id="79a59df900b949e5"`},
+	}
+	if !reflect.DeepEqual(h, w) {
+		t.Errorf("Header() = %v want %v", h, w)
+	}
+
+	o := &ObjectOptions{Grants: &Grants{Read: []Grantee{CanonicalUser("abc"), CanonicalUser("def")}}}
+	h, err = o.Header()
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if g, w := h.Get("X-Amz-Grant-Read"), `id="abc", id="def"`; g != w {
+		t.Errorf("X-Amz-Grant-Read = %q want %q", g, w)
+	}
+	o = &ObjectOptions{Grants: &Grants{Write: []Grantee{CanonicalUser("abc")}}}
+	if _, err := o.Header(); err == nil {
+		t.Error("expected error for object Write grant")
+	}
+
+	for _, gr := range []Grantee{
+		{},
+		CanonicalUser(""),
+		CanonicalUser(`a"b`),
+		EmailUser("nobody"),
+		Group("http://example.com/group"),
+	} {
+		if _, err := (&Grants{ReadACP: []Grantee{gr}}).Header(); err == nil {
+			t.Errorf("grantee %v: expected error", gr)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package s3util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HeadPart is like Head, but describes part n, counted from 1,
+// of an object created by a multipart upload: the returned Size
+// is that of the part, and PartsCount is the number of parts.
+// For an object uploaded in one piece, part 1 is the whole object
+// and PartsCount is 0.
+//
+// If c is nil, HeadPart uses DefaultConfig.
+func HeadPart(url string, n int, c *Config) (*ObjectInfo, error) {
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	return Head(url+sep+"partNumber="+strconv.Itoa(n), c)
+}
+
+// PartSizes returns the sizes of the parts of the object at url,
+// in order, as it was uploaded, so that a download can follow
+// the part boundaries or the object's multipart ETag can be
+// reproduced. It makes a HEAD request for each part, a few at a
+// time. For an object uploaded in one piece, it returns its size.
+//
+// If c is nil, PartSizes uses DefaultConfig.
+func PartSizes(url string, c *Config) ([]int64, error) {
+	_, sizes, err := partLayout(url, c)
+	return sizes, err
+}
+
+// partLayout returns information about the object at url,
+// as reported for its first part, and the sizes of its parts.
+func partLayout(url string, c *Config) (*ObjectInfo, []int64, error) {
+	first, err := HeadPart(url, 1, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first.PartsCount <= 1 {
+		return first, []int64{first.Size}, nil
+	}
+	sizes := make([]int64, first.PartsCount)
+	sizes[0] = first.Size
+	err = forEach(first.PartsCount-1, func(i int) error {
+		info, err := HeadPart(url, i+2, c)
+		if err != nil {
+			return err
+		}
+		sizes[i+1] = info.Size
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return first, sizes, nil
+}
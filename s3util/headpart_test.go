@@ -0,0 +1,46 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPartSizes(t *testing.T) {
+	parts := []int64{5 << 20, 5 << 20, 123}
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != "HEAD" {
+				t.Error("unexpected request", req.Method, req.URL)
+			}
+			n, _ := strconv.Atoi(req.URL.Query().Get("partNumber"))
+			if n < 1 || n > len(parts) {
+				t.Fatalf("bad part number %d", n)
+			}
+			return &http.Response{
+				StatusCode:    200,
+				ContentLength: parts[n-1],
+				Header:        http.Header{"X-Amz-Mp-Parts-Count": {strconv.Itoa(len(parts))}},
+				Body:          ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	info, err := HeadPart("https://b.s3.amazonaws.com/k", 3, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if info.Size != 123 || info.PartsCount != 3 {
+		t.Errorf("HeadPart = size %d, %d parts want 123, 3", info.Size, info.PartsCount)
+	}
+	g, err := PartSizes("https://b.s3.amazonaws.com/k", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if !reflect.DeepEqual(g, parts) {
+		t.Errorf("PartSizes = %v want %v", g, parts)
+	}
+}
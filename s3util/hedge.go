@@ -0,0 +1,81 @@
+package s3util
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// hedge sends r with client and, if no response has arrived
+// after delay, sends it again, first calling again, if not nil.
+// It returns the first successful response and cancels the other
+// request. The request must have no body.
+func hedge(client *http.Client, r *http.Request, delay time.Duration, again func()) (*http.Response, error) {
+	type result struct {
+		i    int
+		resp *http.Response
+		err  error
+	}
+	ch := make(chan result, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(r.Context())
+		i := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := client.Do(r.WithContext(ctx))
+			ch <- result{i, resp, err}
+		}()
+	}
+	send()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for pending := 1; ; {
+		select {
+		case <-timer.C:
+			if again != nil {
+				again()
+			}
+			send()
+			pending++
+		case res := <-ch:
+			pending--
+			if res.err != nil {
+				cancels[res.i]()
+				if pending == 0 {
+					return nil, res.err
+				}
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != res.i {
+					cancel()
+				}
+			}
+			if pending > 0 {
+				go func() {
+					if loser := <-ch; loser.err == nil {
+						loser.resp.Body.Close()
+					}
+				}()
+			}
+			res.resp.Body = &cancelBody{res.resp.Body, cancels[res.i]}
+			return res.resp, nil
+		}
+	}
+}
+
+// cancelBody is a response body that cancels its
+// request's context when closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,77 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHedge(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		n        int
+		canceled = make(chan bool, 1)
+	)
+	c := *DefaultConfig
+	c.HedgeDelay = 10 * time.Millisecond
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			n++
+			first := n == 1
+			mu.Unlock()
+			if first {
+				// The first request stalls until canceled.
+				<-req.Context().Done()
+				canceled <- true
+				return nil, req.Context().Err()
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("second")),
+			}, nil
+		}),
+	}
+	r, _ := http.NewRequest("GET", "https://foo.s3.amazonaws.com/x", nil)
+	resp, err := c.do(r)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "second" {
+		t.Errorf("body = %q want second", b)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Error("first request not canceled")
+	}
+}
+
+func TestHedgeFast(t *testing.T) {
+	n := 0
+	c := *DefaultConfig
+	c.HedgeDelay = time.Hour
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			n++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	r, _ := http.NewRequest("HEAD", "https://foo.s3.amazonaws.com/x", nil)
+	resp, err := c.do(r)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	resp.Body.Close()
+	if n != 1 {
+		t.Errorf("sent %d requests want 1", n)
+	}
+}
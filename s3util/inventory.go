@@ -0,0 +1,258 @@
+package s3util
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An InventoryManifest describes an S3 Inventory report,
+// as read from its manifest.json file.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/storage-inventory.html.
+type InventoryManifest struct {
+	SourceBucket      string          `json:"sourceBucket"`
+	DestinationBucket string          `json:"destinationBucket"`
+	Version           string          `json:"version"`
+	CreationTimestamp string          `json:"creationTimestamp"`
+	FileFormat        string          `json:"fileFormat"` // CSV, ORC, or Parquet
+	FileSchema        string          `json:"fileSchema"`
+	Files             []InventoryFile `json:"files"`
+}
+
+// An InventoryFile is one of the data files of an inventory report.
+type InventoryFile struct {
+	Key         string `json:"key"` // in the destination bucket
+	Size        int64  `json:"size"`
+	MD5Checksum string `json:"MD5checksum"`
+}
+
+// An InventoryItem is an object version listed in an inventory report.
+// Fields not in the report's schema are left zero; in particular,
+// Size is -1 if the report doesn't include it.
+type InventoryItem struct {
+	Bucket string
+	Key    string
+	ObjectInfo
+	IsLatest       bool
+	IsDeleteMarker bool
+	StorageClass   string
+
+	// Fields holds every column of the record,
+	// keyed by its name in the schema.
+	Fields map[string]string
+}
+
+// ReadInventoryManifest reads the inventory manifest.json at url.
+//
+// If c is nil, ReadInventoryManifest uses DefaultConfig.
+func ReadInventoryManifest(url string, c *Config) (*InventoryManifest, error) {
+	r, err := Open(url, c)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(r)
+	m := new(InventoryManifest)
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("s3util: reading inventory manifest: %v", err)
+	}
+	return m, nil
+}
+
+// Items returns an iterator over the items in the report's data
+// files, which are read from the destination bucket at bucketURL,
+// such as "https://dest.s3.amazonaws.com/". The CSV and ORC formats
+// are supported; for Parquet, the iterator yields a single error.
+// Iteration stops after the first error.
+//
+// The Fields of items from ORC files are keyed by the ORC column
+// names, such as "last_modified_date", and timestamps are given in
+// RFC 3339 form. ORC columns of types inventory reports don't use
+// are left out.
+//
+// If c is nil, Items uses DefaultConfig.
+func (m *InventoryManifest) Items(bucketURL string, c *Config) iter.Seq2[*InventoryItem, error] {
+	return func(yield func(*InventoryItem, error) bool) {
+		var read func(url string) bool
+		switch m.FileFormat {
+		case "CSV":
+			var schema []string
+			for _, s := range strings.Split(m.FileSchema, ",") {
+				schema = append(schema, strings.TrimSpace(s))
+			}
+			read = func(url string) bool { return m.readCSV(url, schema, c, yield) }
+		case "ORC":
+			read = func(url string) bool { return m.readORC(url, c, yield) }
+		default:
+			yield(nil, fmt.Errorf("s3util: unsupported inventory format %q", m.FileFormat))
+			return
+		}
+		base := strings.TrimSuffix(bucketURL, "/") + "/"
+		for _, f := range m.Files {
+			if !read(base + f.Key) {
+				return
+			}
+		}
+	}
+}
+
+// ReadInventorySymlink reads the symlink.txt file at url, written
+// for Apache Hive alongside an inventory report, such as
+// "https://dest.s3.amazonaws.com/inv/src/cfg/hive/dt=2024-01-02-00-00/symlink.txt".
+// It lists the report's data files by their s3:// URLs, and
+// ReadInventorySymlink returns them with only their keys set, for
+// use as the Files of the report's manifest:
+//
+//	m.Files, err = s3util.ReadInventorySymlink(symlinkURL, nil)
+//
+// If c is nil, ReadInventorySymlink uses DefaultConfig.
+func ReadInventorySymlink(url string, c *Config) ([]InventoryFile, error) {
+	r, err := Open(url, c)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(r)
+	var files []InventoryFile
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		rest, ok := strings.CutPrefix(line, "s3://")
+		_, key, ok2 := strings.Cut(rest, "/")
+		if !ok || !ok2 || key == "" {
+			return nil, fmt.Errorf("s3util: reading inventory symlink: bad URL %q", line)
+		}
+		files = append(files, InventoryFile{Key: key})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("s3util: reading inventory symlink: %v", err)
+	}
+	return files, nil
+}
+
+// readCSV yields the items in the gzipped CSV file at url.
+// It reports whether iteration should continue.
+func (m *InventoryManifest) readCSV(url string, schema []string, c *Config, yield func(*InventoryItem, error) bool) bool {
+	rc, err := Open(url, c)
+	if err != nil {
+		yield(nil, err)
+		return false
+	}
+	defer closeBody(rc)
+	zr, err := gzip.NewReader(rc)
+	if err != nil {
+		yield(nil, fmt.Errorf("s3util: %s: %v", url, err))
+		return false
+	}
+	cr := csv.NewReader(zr)
+	cr.FieldsPerRecord = len(schema)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return true
+		}
+		if err != nil {
+			yield(nil, fmt.Errorf("s3util: %s: %v", url, err))
+			return false
+		}
+		it, err := parseInventoryRecord(schema, rec, true)
+		if err != nil {
+			yield(nil, fmt.Errorf("s3util: %s: %v", url, err))
+			return false
+		}
+		if !yield(it, nil) {
+			return false
+		}
+	}
+}
+
+// readORC yields the items in the ORC file at url.
+// It reports whether iteration should continue.
+func (m *InventoryManifest) readORC(url string, c *Config, yield func(*InventoryItem, error) bool) bool {
+	r, err := NewReaderAt(url, c)
+	if err != nil {
+		yield(nil, err)
+		return false
+	}
+	f, err := openORC(r, r.Size())
+	if err != nil {
+		yield(nil, fmt.Errorf("s3util: %s: %v", url, err))
+		return false
+	}
+	rec := make([]string, len(f.names))
+	for _, s := range f.stripes {
+		cols, err := f.readStripe(s)
+		if err != nil {
+			yield(nil, fmt.Errorf("s3util: %s: %v", url, err))
+			return false
+		}
+		for i := 0; i < int(s.rows); i++ {
+			for j, col := range cols {
+				rec[j] = col[i]
+			}
+			it, err := parseInventoryRecord(f.names, rec, false)
+			if err != nil {
+				yield(nil, fmt.Errorf("s3util: %s: %v", url, err))
+				return false
+			}
+			if !yield(it, nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseInventoryRecord returns the item for the record rec, whose
+// columns are named by schema, in either the CSV or ORC style, as
+// "LastModifiedDate" or "last_modified_date". If escaped is set,
+// as in CSV reports, the key is URL-encoded.
+func parseInventoryRecord(schema, rec []string, escaped bool) (*InventoryItem, error) {
+	it := &InventoryItem{Fields: make(map[string]string, len(schema))}
+	it.Size = -1
+	for i, name := range schema {
+		v := rec[i]
+		it.Fields[name] = v
+		var err error
+		switch strings.ToLower(strings.ReplaceAll(name, "_", "")) {
+		case "bucket":
+			it.Bucket = v
+		case "key":
+			it.Key = v
+			if escaped {
+				it.Key, err = url.QueryUnescape(v)
+			}
+		case "versionid":
+			it.VersionId = v
+		case "islatest":
+			it.IsLatest = v == "true"
+		case "isdeletemarker":
+			it.IsDeleteMarker = v == "true"
+		case "size":
+			if v != "" {
+				it.Size, err = strconv.ParseInt(v, 10, 64)
+			}
+		case "lastmodifieddate":
+			if v != "" {
+				it.LastModified, err = time.Parse(time.RFC3339, v)
+			}
+		case "etag":
+			it.ETag = v
+		case "storageclass":
+			it.StorageClass = v
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad %s %q", name, v)
+		}
+	}
+	return it, nil
+}
@@ -0,0 +1,141 @@
+package s3util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInventory(t *testing.T) {
+	gz := func(s string) []byte {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		w.Write([]byte(s))
+		w.Close()
+		return b.Bytes()
+	}
+	objects := map[string][]byte{
+		"/dest/inv/src/cfg/2024-01-02T00-00Z/manifest.json": []byte(`{
+			"sourceBucket": "src",
+			"destinationBucket": "arn:aws:s3:::dest",
+			"version": "2016-11-30",
+			"fileFormat": "CSV",
+			"fileSchema": "Bucket, Key, Size, LastModifiedDate, ETag, StorageClass, IsLatest",
+			"files": [
+				{"key": "inv/src/cfg/data/a.csv.gz", "size": 1, "MD5checksum": "x"},
+				{"key": "inv/src/cfg/data/b.csv.gz", "size": 1, "MD5checksum": "x"}
+			]
+		}`),
+		"/dest/inv/src/cfg/data/a.csv.gz": gz(`"src","dir/hello+world.txt","12","2024-01-01T10:00:00.000Z","abc","STANDARD","true"` + "\n"),
+		"/dest/inv/src/cfg/data/b.csv.gz": gz(`"src","caf%C3%A9","","2024-01-01T11:00:00.000Z","def","GLACIER","false"` + "\n"),
+	}
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			b, ok := objects[req.URL.Path]
+			if !ok {
+				t.Fatal("unexpected request", req.URL)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	m, err := ReadInventoryManifest("https://s3.amazonaws.com/dest/inv/src/cfg/2024-01-02T00-00Z/manifest.json", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	var got []string
+	for it, err := range m.Items("https://s3.amazonaws.com/dest/", &c) {
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %d %s %s %s %v",
+			it.Bucket, it.Key, it.Size, it.LastModified.Format(time.RFC3339), it.ETag, it.StorageClass, it.IsLatest))
+	}
+	want := []string{
+		"src dir/hello world.txt 12 2024-01-01T10:00:00Z abc STANDARD true",
+		"src café -1 2024-01-01T11:00:00Z def GLACIER false",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("items = %q want %q", got, want)
+	}
+
+	m.FileFormat = "Parquet"
+	for _, err := range m.Items("https://s3.amazonaws.com/dest/", &c) {
+		if err == nil {
+			t.Error("expected error for Parquet")
+		}
+	}
+}
+
+func TestInventoryORC(t *testing.T) {
+	t1 := time.Date(2024, 1, 1, 10, 0, 0, 500, time.UTC)
+	t2 := time.Date(2014, 6, 1, 11, 0, 0, 0, time.UTC) // before the ORC epoch
+	cols := func(keys ...interface{}) []orcTestColumn {
+		return []orcTestColumn{
+			{"bucket", orcString, false, []interface{}{"src", "src"}},
+			{"key", orcString, false, keys},
+			{"size", orcLong, false, []interface{}{int64(12), nil}},
+			{"last_modified_date", orcTimestamp, false, []interface{}{t1, t2}},
+			{"e_tag", orcString, false, []interface{}{"abc", "def"}},
+			{"storage_class", orcString, true, []interface{}{"STANDARD", "GLACIER"}},
+			{"is_latest", orcBoolean, false, []interface{}{true, false}},
+		}
+	}
+	objects := map[string][]byte{
+		"/dest/data/a.orc": writeTestORC(orcNone, cols("dir/hello+world.txt", "café")),
+		"/dest/data/b.orc": writeTestORC(orcZlib, cols("x", "y"), cols("z", "w")),
+		"/dest/hive/symlink.txt": []byte(
+			"s3://dest/data/a.orc\n" +
+				"s3://dest/data/b.orc\n",
+		),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+	}))
+	defer ts.Close()
+
+	c := *DefaultConfig
+	files, err := ReadInventorySymlink(ts.URL+"/dest/hive/symlink.txt", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	m := &InventoryManifest{FileFormat: "ORC", Files: files}
+	var got []string
+	for it, err := range m.Items(ts.URL+"/dest/", &c) {
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %d %s %s %s %v",
+			it.Bucket, it.Key, it.Size, it.LastModified.Format(time.RFC3339Nano), it.ETag, it.StorageClass, it.IsLatest))
+	}
+	want := []string{
+		"src dir/hello+world.txt 12 2024-01-01T10:00:00.0000005Z abc STANDARD true",
+		"src café -1 2014-06-01T11:00:00Z def GLACIER false",
+		"src x 12 2024-01-01T10:00:00.0000005Z abc STANDARD true",
+		"src y -1 2014-06-01T11:00:00Z def GLACIER false",
+		"src z 12 2024-01-01T10:00:00.0000005Z abc STANDARD true",
+		"src w -1 2014-06-01T11:00:00Z def GLACIER false",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("items = %q want %q", got, want)
+	}
+
+	objects["/dest/hive/symlink.txt"] = []byte("dest/data/a.orc\n")
+	if _, err := ReadInventorySymlink(ts.URL+"/dest/hive/symlink.txt", &c); err == nil {
+		t.Error("expected error for a line that isn't an s3:// URL")
+	}
+}
@@ -0,0 +1,62 @@
+package s3util
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// scope prepends c.KeyPrefix to the keys named by r, in its path
+// and in any X-Amz-Copy-Source header.
+// Requests for a bucket, rather than an object, are unchanged.
+func (c *Config) scope(r *http.Request) {
+	u := *r.URL
+	if !c.scopeURL(&u) {
+		return
+	}
+	r.URL = &u
+	if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+		// The copy source is always "/bucket/key".
+		if i := strings.IndexByte(src[1:], '/'); i != -1 {
+			i += 2
+			r.Header.Set("X-Amz-Copy-Source", src[:i]+escapePrefix(c.KeyPrefix)+src[i:])
+		}
+	}
+}
+
+// scopeURL prepends c.KeyPrefix to the key of the object
+// addressed by u and reports whether it did. The key follows
+// the bucket name in the path of a path-style URL, and is the
+// whole path otherwise.
+func (c *Config) scopeURL(u *url.URL) bool {
+	if c.KeyPrefix == "" || u.Opaque != "" {
+		return false
+	}
+	n := 1 // length of the path before the key
+	if c.ObjectPath(u) == u.EscapedPath() {
+		// path-style; skip the bucket
+		i := strings.IndexByte(strings.TrimPrefix(u.Path, "/"), '/')
+		if i == -1 {
+			return false
+		}
+		n = i + 2
+	}
+	if len(u.Path) <= n {
+		return false
+	}
+	raw := u.RawPath
+	u.Path = u.Path[:n] + c.KeyPrefix + u.Path[n:]
+	if raw != "" {
+		j := 1
+		if n > 1 {
+			j = strings.IndexByte(raw[1:], '/') + 2
+		}
+		u.RawPath = raw[:j] + escapePrefix(c.KeyPrefix) + raw[j:]
+	}
+	return true
+}
+
+// escapePrefix escapes a key prefix as in a URL path.
+func escapePrefix(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
@@ -0,0 +1,100 @@
+package s3util
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	var got []string
+	c := *DefaultConfig
+	c.KeyPrefix = "tenants/a b/"
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			s := req.Method + " " + req.URL.RequestURI()
+			if src := req.Header.Get("X-Amz-Copy-Source"); src != "" {
+				s += " " + src
+			}
+			got = append(got, s)
+			body := ""
+			if req.Header.Get("X-Amz-Copy-Source") != "" {
+				body = "<CopyObjectResult></CopyObjectResult>"
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Etag": {`"e"`}},
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+	if _, err := Put("https://b.s3.amazonaws.com/k", strings.NewReader("hi"), nil, &c); err != nil {
+		t.Fatal("Put:", err)
+	}
+	if err := Copy("https://b.s3.amazonaws.com/k2", "https://b.s3.amazonaws.com/k", nil, &c); err != nil {
+		t.Fatal("Copy:", err)
+	}
+	if _, err := Head("http://127.0.0.1:9000/b/k", &c); err != nil {
+		t.Fatal("Head:", err)
+	}
+	if _, err := Head("https://b.s3.amazonaws.com/", &c); err != nil {
+		t.Fatal("Head:", err)
+	}
+	w := []string{
+		"PUT /tenants/a%20b/k",
+		"PUT /tenants/a%20b/k2 /b/tenants/a%20b/k",
+		"HEAD /b/tenants/a%20b/k",
+		"HEAD /",
+	}
+	if strings.Join(got, "\n") != strings.Join(w, "\n") {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(w, "\n"))
+	}
+	u, err := PresignDownload("https://b.s3.amazonaws.com/k", "", "", time.Hour, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(u, "https://b.s3.amazonaws.com/tenants/a%20b/k?") {
+		t.Errorf("presigned = %q, want it under the prefix", u)
+	}
+}
+
+func TestKeyPrefixReaddir(t *testing.T) {
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RawQuery)
+		io.WriteString(w, "<ListBucketResult><IsTruncated>true</IsTruncated>"+
+			"<Contents><Key>tenants/a b/dir/x</Key><Size>1</Size></Contents>"+
+			"<CommonPrefixes><Prefix>tenants/a b/dir/y/</Prefix></CommonPrefixes>"+
+			"</ListBucketResult>")
+	}))
+	defer ts.Close()
+	c := *DefaultConfig
+	c.KeyPrefix = "tenants/a b/"
+	f, err := NewFile(ts.URL+"/dir", &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := f.Readdir(10)
+	if err != nil {
+		t.Fatal("Readdir:", err)
+	}
+	var names []string
+	for _, x := range fi {
+		names = append(names, x.Name())
+	}
+	if g := strings.Join(names, " "); g != "dir/x dir/y" {
+		t.Errorf("names = %q want %q", g, "dir/x dir/y")
+	}
+	f.Readdir(10)
+	w := []string{
+		"prefix=tenants%2Fa+b%2Fdir%2F&delimiter=%2F&max-keys=10",
+		"prefix=tenants%2Fa+b%2Fdir%2F&delimiter=%2F&max-keys=10&marker=tenants%2Fa+b%2Fdir%2Fy%2F",
+	}
+	if strings.Join(got, "\n") != strings.Join(w, "\n") {
+		t.Errorf("queries = %q want %q", got, w)
+	}
+}
@@ -0,0 +1,76 @@
+package s3util
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Part describes a part of a multipart upload that
+// has been uploaded.
+// For the meaning of these fields, see
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadListParts.html.
+type Part struct {
+	PartNumber   int
+	LastModified string
+	ETag         string // ETag value, without double quotes.
+	Size         int64
+}
+
+type listPartsResult struct {
+	IsTruncated          bool
+	NextPartNumberMarker int
+	Part                 []Part
+}
+
+// ListParts returns the parts uploaded so far in the multipart upload
+// of the object at url identified by uploadId, in order of part number.
+// It sends as many requests as needed to list all of the parts.
+//
+// If c is nil, ListParts uses DefaultConfig.
+func ListParts(url, uploadId string, c *Config) ([]Part, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	var parts []Part
+	marker := 0
+	for {
+		res, err := listParts(url, uploadId, marker, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range res.Part {
+			p.ETag = etagValue(p.ETag)
+			parts = append(parts, p)
+		}
+		if !res.IsTruncated || res.NextPartNumberMarker <= marker {
+			return parts, nil
+		}
+		marker = res.NextPartNumberMarker
+	}
+}
+
+func listParts(rawurl, uploadId string, marker int, c *Config) (*listPartsResult, error) {
+	v := url.Values{}
+	v.Set("uploadId", uploadId)
+	if marker > 0 {
+		v.Set("part-number-marker", strconv.Itoa(marker))
+	}
+	r, err := http.NewRequest("GET", rawurl+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	res := new(listPartsResult)
+	if err := c.decodeXML(resp.Body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,49 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListParts(t *testing.T) {
+	pages := map[string]string{
+		"": `<ListPartsResult>
+			<IsTruncated>true</IsTruncated>
+			<NextPartNumberMarker>2</NextPartNumberMarker>
+			<Part><PartNumber>1</PartNumber><ETag>"a"</ETag><Size>5242880</Size></Part>
+			<Part><PartNumber>2</PartNumber><ETag>"b"</ETag><Size>5242880</Size></Part>
+		</ListPartsResult>`,
+		"2": `<ListPartsResult>
+			<IsTruncated>false</IsTruncated>
+			<Part><PartNumber>3</PartNumber><ETag>"c"</ETag><Size>10</Size></Part>
+		</ListPartsResult>`,
+	}
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			q := req.URL.Query()
+			if q.Get("uploadId") != "up" {
+				t.Errorf("uploadId = %q want up", q.Get("uploadId"))
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(pages[q.Get("part-number-marker")])),
+			}, nil
+		}),
+	}
+	parts, err := ListParts("https://b.s3.amazonaws.com/k", "up", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := []Part{
+		{PartNumber: 1, ETag: "a", Size: 5242880},
+		{PartNumber: 2, ETag: "b", Size: 5242880},
+		{PartNumber: 3, ETag: "c", Size: 10},
+	}
+	if !reflect.DeepEqual(parts, w) {
+		t.Errorf("parts = %+v want %+v", parts, w)
+	}
+}
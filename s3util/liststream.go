@@ -0,0 +1,119 @@
+package s3util
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// decodeList reads a ListObjects response from r, calling fn
+// with each object as it is decoded, and then with each
+// directory, so that the objects of a large page are not all
+// held at once. Once fn returns false, it is not called again,
+// but the rest of the page is still read, to learn where the
+// next page starts. On success, decodeList sets f's position
+// in the listing, as parseResponse does.
+func (f *File) decodeList(r io.Reader, fn func(os.FileInfo) bool) error {
+	c := f.config
+	if c == nil {
+		c = DefaultConfig
+	}
+	d := c.xmlDecoder(r)
+	var (
+		result  listObjectsResult
+		dirs    []string
+		lastKey string
+		more    = true
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "IsTruncated":
+			err = d.DecodeElement(&result.IsTruncated, &start)
+		case "NextMarker":
+			err = d.DecodeElement(&result.NextMarker, &start)
+			result.NextMarker = strings.TrimPrefix(result.NextMarker, c.KeyPrefix)
+		case "Contents":
+			var st Stat
+			if err = d.DecodeElement(&st, &start); err != nil {
+				break
+			}
+			st.Key = strings.TrimPrefix(st.Key, c.KeyPrefix)
+			lastKey = st.Key
+			more = more && fn(statInfo(st))
+		case "CommonPrefixes":
+			var p struct{ Prefix string }
+			err = d.DecodeElement(&p, &start)
+			dir := strings.TrimPrefix(p.Prefix, c.KeyPrefix)
+			dirs = append(dirs, dir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, dir := range dirs {
+		more = more && fn(&fileInfo{name: strings.TrimRight(dir, "/"), dir: true})
+	}
+
+	var lastDir string
+	if len(dirs) > 0 {
+		lastDir = dirs[len(dirs)-1]
+	}
+	f.result = &result
+	if result.NextMarker != "" {
+		f.marker = result.NextMarker
+	} else if lastKey > lastDir {
+		f.marker = lastKey
+	} else if lastDir != "" {
+		f.marker = lastDir
+	}
+	return nil
+}
+
+// statInfo returns the FileInfo for an object in a listing.
+// A zero-length object whose key ends in "/" is a directory
+// marker, and is reported as a directory.
+func statInfo(st Stat) *fileInfo {
+	st.ETag = etagValue(st.ETag)
+	size, _ := strconv.ParseInt(st.Size, 10, 0)
+	fi := &fileInfo{name: st.Key, size: size, sys: &st}
+	if size == 0 && strings.HasSuffix(st.Key, "/") {
+		fi.name = strings.TrimRight(st.Key, "/")
+		fi.dir = true
+	}
+	return fi
+}
+
+// streamPage requests one page of f's listing and calls yield
+// with each entry that passes f.Filter as it is decoded, until
+// yield returns false. It reports whether yield asked to stop.
+func (f *File) streamPage(yield func(os.FileInfo) bool) (bool, error) {
+	reader, err := f.sendRequest(0, true)
+	if err != nil {
+		return false, err
+	}
+	defer closeBody(reader)
+
+	stopped := false
+	err = f.decodeList(reader, func(fi os.FileInfo) bool {
+		if f.Filter != nil {
+			if fi := fi.(*fileInfo); !fi.dir && !f.Filter.match(fi.sys) {
+				return true
+			}
+		}
+		stopped = !yield(fi)
+		return !stopped
+	})
+	return stopped, err
+}
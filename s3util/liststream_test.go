@@ -0,0 +1,75 @@
+package s3util
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAllStreams(t *testing.T) {
+	sent := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("marker") != "" {
+			io.WriteString(w, `<ListBucketResult><IsTruncated>false</IsTruncated>
+				<Contents><Key>d</Key></Contents></ListBucketResult>`)
+			return
+		}
+		io.WriteString(w, `<ListBucketResult><IsTruncated>true</IsTruncated>
+			<Contents><Key>a</Key></Contents>`)
+		w.(http.Flusher).Flush()
+		<-sent // the first entry must arrive before the page is done
+		io.WriteString(w, `<CommonPrefixes><Prefix>b/</Prefix></CommonPrefixes>
+			<Contents><Key>c</Key></Contents></ListBucketResult>`)
+	}))
+	defer ts.Close()
+
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for fi, err := range f.All() {
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if len(got) == 0 {
+			close(sent)
+		}
+		got = append(got, fi.Name())
+	}
+	// Directories follow the objects of their page, as in Readdir.
+	if g, w := strings.Join(got, " "), "a c b d"; g != w {
+		t.Errorf("All = %q want %q", g, w)
+	}
+}
+
+func TestAllStopEarly(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+	ts := listServer(keys, 3)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fi, err := range f.All() {
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if fi.Name() == "a" {
+			break
+		}
+	}
+	// The rest of the page was read, so f continues after it.
+	if g, w := f.Marker(), "c"; g != w {
+		t.Errorf("Marker = %q want %q", g, w)
+	}
+	g, err := names(f, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := "d e"; strings.Join(g, " ") != w {
+		t.Errorf("next = %q want %q", g, w)
+	}
+}
@@ -0,0 +1,198 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minLockTTL is the shortest ttl accepted by Lock.
+// It is a variable for testing.
+var minLockTTL = time.Second
+
+// ErrLocked is returned by Lock when another holder's
+// lease on the lock has not expired.
+var ErrLocked = errors.New("s3util: locked")
+
+// A Lease is a hold on a lock stored in an S3 object,
+// as returned by Lock.
+type Lease struct {
+	url   string
+	ttl   time.Duration
+	c     *Config
+	owner string
+
+	etag    string    // of the lock object, as last written by us
+	expires time.Time // as last written by us
+
+	stop chan struct{}
+	done chan struct{}
+	lost chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// lockBody is the content of a lock object.
+type lockBody struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// Lock acquires a best-effort lease on the lock stored in the S3
+// object at url, for coordinating jobs such as periodic tasks that
+// should not run concurrently. It uses conditional writes to
+// create the object, or to take it over once the previous lease
+// has expired. If another lease is still current, Lock returns
+// ErrLocked at once; callers that want to wait can retry.
+//
+// The lease lasts for ttl and is renewed in the background until
+// Unlock is called. If a renewal fails, for instance because the
+// process was paused for longer than ttl and another holder took
+// over, the channel returned by Lost is closed. Expiry times are
+// taken from the local clock, so the clocks of all holders should
+// agree to well within ttl.
+//
+// The lease is renewed every ttl/3, and each renewal is a request
+// to S3, so ttl must be long enough for several requests to
+// complete: Lock returns an error if ttl is under one second.
+//
+// If c is nil, Lock uses DefaultConfig.
+func Lock(url string, ttl time.Duration, c *Config) (*Lease, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	if ttl < minLockTTL {
+		return nil, fmt.Errorf("s3util: lock ttl %v is under the minimum of %v", ttl, minLockTTL)
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	l := &Lease{
+		url:   url,
+		ttl:   ttl,
+		c:     c,
+		owner: hex.EncodeToString(b[:]),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		lost:  make(chan struct{}),
+	}
+	err := l.write(time.Now().Add(ttl), "")
+	if err == ErrPreconditionFailed {
+		err = l.takeOver()
+	}
+	if err != nil {
+		return nil, err
+	}
+	go l.renew()
+	return l, nil
+}
+
+// takeOver acquires the existing lock object if its lease has expired.
+func (l *Lease) takeOver() error {
+	resp, err := getFrom(l.url, 0, "", l.c)
+	if err != nil {
+		return err
+	}
+	var cur lockBody
+	err = json.NewDecoder(resp.Body).Decode(&cur)
+	closeBody(resp.Body)
+	if err == nil && time.Now().Before(cur.Expires) {
+		return ErrLocked
+	}
+	etag := etagValue(resp.Header.Get("Etag"))
+	err = l.write(time.Now().Add(l.ttl), etag)
+	if err == ErrPreconditionFailed {
+		return ErrLocked // someone else took it first
+	}
+	return err
+}
+
+// write stores the lock object with the given expiry time,
+// on condition that its ETag is etag, or that it does not
+// exist if etag is empty.
+func (l *Lease) write(expires time.Time, etag string) error {
+	b, err := json.Marshal(lockBody{l.owner, expires})
+	if err != nil {
+		return err
+	}
+	var res *Result
+	if etag == "" {
+		h := http.Header{"If-None-Match": {"*"}, "Content-Type": {"application/json"}}
+		res, err = Put(l.url, bytes.NewReader(b), h, l.c)
+	} else {
+		res, err = PutIfMatch(l.url, etag, bytes.NewReader(b), l.c)
+	}
+	if conflict(err) {
+		// Another conditional write of the lock object
+		// was in progress, and won.
+		return ErrPreconditionFailed
+	}
+	if err != nil {
+		return err
+	}
+	l.etag, l.expires = res.ETag, expires
+	return nil
+}
+
+// conflict reports whether err is the 409 Conflict response S3
+// sends to a conditional write that races another write of the
+// same object.
+func conflict(err error) bool {
+	e, ok := err.(*respError)
+	return ok && e.r.StatusCode == 409
+}
+
+func (l *Lease) renew() {
+	defer close(l.done)
+	t := time.NewTicker(l.ttl / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+		}
+		err := l.write(time.Now().Add(l.ttl), l.etag)
+		if err == nil {
+			continue
+		}
+		// Transient errors are retried until the lease expires.
+		if err == ErrPreconditionFailed || !time.Now().Before(l.expires) {
+			l.mu.Lock()
+			l.err = err
+			l.mu.Unlock()
+			close(l.lost)
+			return
+		}
+	}
+}
+
+// Lost returns a channel that is closed if the lease is lost
+// before Unlock is called.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock stops renewing the lease and releases the lock by marking
+// it expired, again with a conditional write. If the lease had
+// been lost, Unlock returns the error that caused the loss.
+// Unlock must be called only once.
+func (l *Lease) Unlock() error {
+	close(l.stop)
+	<-l.done
+	l.mu.Lock()
+	err := l.err
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return l.write(time.Time{}, l.etag)
+}
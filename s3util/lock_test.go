@@ -0,0 +1,175 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// condStore is a fake S3 object that supports conditional writes.
+type condStore struct {
+	mu       sync.Mutex
+	body     string
+	etag     string // empty if the object doesn't exist
+	n        int
+	conflict bool // answer the next PUT with 409 Conflict
+}
+
+func (s *condStore) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}
+	switch req.Method {
+	case "GET":
+		if s.etag == "" {
+			resp.StatusCode = 404
+			break
+		}
+		resp.Header.Set("Etag", s.etag)
+		resp.Body = ioutil.NopCloser(strings.NewReader(s.body))
+	case "PUT":
+		if s.conflict {
+			s.conflict = false
+			resp.StatusCode = 409
+			resp.Body = ioutil.NopCloser(strings.NewReader("<Error><Code>ConditionalRequestConflict</Code></Error>"))
+			break
+		}
+		if req.Header.Get("If-None-Match") == "*" && s.etag != "" ||
+			req.Header.Get("If-Match") != "" && req.Header.Get("If-Match") != s.etag {
+			resp.StatusCode = 412
+			break
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		s.n++
+		s.body, s.etag = string(b), `"`+strconv.Itoa(s.n)+`"`
+		resp.Header.Set("Etag", s.etag)
+	}
+	return resp, nil
+}
+
+func TestLock(t *testing.T) {
+	st := new(condStore)
+	c := *DefaultConfig
+	c.Client = &http.Client{Transport: st}
+	const url = "https://s3.amazonaws.com/foo/lock"
+
+	l, err := Lock(url, time.Hour, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if _, err := Lock(url, time.Hour, &c); err != ErrLocked {
+		t.Errorf("second Lock err = %v want %v", err, ErrLocked)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	l, err = Lock(url, time.Hour, &c)
+	if err != nil {
+		t.Fatal("Lock after Unlock err", err)
+	}
+
+	// Simulate a holder that paused past its expiry and lost the lock.
+	st.mu.Lock()
+	st.body = `{"owner":"x","expires":"2000-01-01T00:00:00Z"}`
+	st.etag = `"stale"`
+	st.mu.Unlock()
+	l2, err := Lock(url, time.Hour, &c)
+	if err != nil {
+		t.Fatal("takeover of expired lock err", err)
+	}
+	if err := l.Unlock(); err != ErrPreconditionFailed {
+		t.Errorf("Unlock of lost lease err = %v want %v", err, ErrPreconditionFailed)
+	}
+	l2.Unlock()
+}
+
+func TestLockRenew(t *testing.T) {
+	defer func(d time.Duration) { minLockTTL = d }(minLockTTL)
+	minLockTTL = 0 // renew quickly
+	st := new(condStore)
+	c := *DefaultConfig
+	c.Client = &http.Client{Transport: st}
+	const url = "https://s3.amazonaws.com/foo/lock"
+
+	l, err := Lock(url, 30*time.Millisecond, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := Lock(url, time.Hour, &c); err != ErrLocked {
+		t.Errorf("Lock after renewals err = %v want %v", err, ErrLocked)
+	}
+	select {
+	case <-l.Lost():
+		t.Error("lease lost")
+	default:
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	st.mu.Lock()
+	n := st.n
+	st.mu.Unlock()
+	if n < 3 {
+		t.Errorf("lock written %d times want at least 3", n)
+	}
+}
+
+func TestLockConflict(t *testing.T) {
+	defer func(d time.Duration) { minLockTTL = d }(minLockTTL)
+	minLockTTL = 0 // renew quickly
+	st := new(condStore)
+	c := *DefaultConfig
+	c.Client = &http.Client{Transport: st}
+	const url = "https://s3.amazonaws.com/foo/lock"
+
+	// A conflicting write of the lock object while
+	// it is being created means another holder won.
+	st.conflict = true
+	st.body = `{"owner":"x","expires":"2100-01-01T00:00:00Z"}`
+	st.etag = `"other"`
+	if _, err := Lock(url, time.Hour, &c); err != ErrLocked {
+		t.Errorf("Lock err = %v want %v", err, ErrLocked)
+	}
+
+	// A conflict on renewal loses the lease.
+	st.etag = ""
+	l, err := Lock(url, 30*time.Millisecond, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	st.mu.Lock()
+	st.conflict = true
+	st.mu.Unlock()
+	select {
+	case <-l.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease not lost")
+	}
+	if err := l.Unlock(); err != ErrPreconditionFailed {
+		t.Errorf("Unlock err = %v want %v", err, ErrPreconditionFailed)
+	}
+}
+
+func TestLockShortTTL(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{Transport: new(condStore)}
+	for _, ttl := range []time.Duration{0, -time.Second, 2, 100 * time.Millisecond, time.Second - 1} {
+		if _, err := Lock("https://s3.amazonaws.com/foo/lock", ttl, &c); err == nil {
+			t.Errorf("Lock with ttl %v: expected error", ttl)
+		}
+	}
+	l, err := Lock("https://s3.amazonaws.com/foo/lock", time.Second, &c)
+	if err != nil {
+		t.Fatal("Lock with ttl 1s: unexpected err", err)
+	}
+	l.Unlock()
+}
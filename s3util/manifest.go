@@ -0,0 +1,202 @@
+package s3util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A ManifestEntry records the state of one object
+// at the time a manifest was made.
+type ManifestEntry struct {
+	Key       string `json:"key"` // relative to the manifest's prefix
+	Size      int64  `json:"size"`
+	ETag      string `json:"etag"`
+	Checksum  string `json:"checksum,omitempty"`  // stored digest; see WriteManifest
+	VersionId string `json:"versionId,omitempty"` // set only in versioned buckets
+}
+
+// manifestFields is the header row of a CSV manifest.
+var manifestFields = []string{"key", "size", "etag", "checksum", "version_id"}
+
+// WriteManifest lists the objects under rawurl, a bucket or a
+// directory in one, and writes a manifest of them to w, in format
+// "csv" or "json". To store the manifest in S3, pass a writer
+// from Create.
+//
+// Each object is also examined with a HEAD request, a few at a
+// time, for its version ID and, if hash is not empty, the digest
+// stored in its metadata under that name, as by Config.Hashes;
+// for instance, with hash "sha256" the checksum is taken from
+// X-Amz-Meta-Sha256.
+//
+// If c is nil, WriteManifest uses DefaultConfig.
+func WriteManifest(w io.Writer, rawurl, format, hash string, c *Config) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("s3util: unknown manifest format %q", format)
+	}
+	f, err := NewFile(rawurl, c)
+	if err != nil {
+		return err
+	}
+	infos, err := f.ReaddirRecursive(0)
+	if err != nil {
+		return err
+	}
+	entries := make([]ManifestEntry, len(infos))
+	err = forEach(len(infos), func(i int) error {
+		st := infos[i].(*fileInfo).sys
+		info, err := Head(keyURL(f.url, st.Key), c)
+		if err != nil {
+			return err
+		}
+		entries[i] = ManifestEntry{
+			Key:       strings.TrimPrefix(st.Key, f.prefix),
+			Size:      infos[i].Size(),
+			ETag:      st.ETag,
+			VersionId: info.VersionId,
+		}
+		if hash != "" {
+			entries[i].Checksum = info.Metadata[hash]
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		return json.NewEncoder(w).Encode(entries)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(manifestFields)
+	for _, e := range entries {
+		cw.Write([]string{e.Key, strconv.FormatInt(e.Size, 10), e.ETag, e.Checksum, e.VersionId})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadManifest reads a manifest written by WriteManifest
+// in format "csv" or "json".
+func ReadManifest(r io.Reader, format string) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, err
+		}
+	case "csv":
+		rows, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(manifestFields, ",") {
+			return nil, errors.New("s3util: bad manifest header")
+		}
+		for _, row := range rows[1:] {
+			size, err := strconv.ParseInt(row[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("s3util: bad manifest size for %s: %v", row[0], err)
+			}
+			entries = append(entries, ManifestEntry{row[0], size, row[2], row[3], row[4]})
+		}
+	default:
+		return nil, fmt.Errorf("s3util: unknown manifest format %q", format)
+	}
+	return entries, nil
+}
+
+// A ManifestMismatch describes an object that does not match
+// its manifest entry.
+type ManifestMismatch struct {
+	Key     string
+	Problem string // such as "missing" or "size 10, want 12"
+}
+
+// VerifyManifest checks the objects under rawurl against entries, as
+// read by ReadManifest, a few at a time, and returns those that are
+// missing or differ in size, ETag, or, where the entry records
+// them, version ID or checksum. The URL may be the one the manifest
+// was made from or a copy of it. Objects not in the manifest are
+// ignored. The hash names the checksum's metadata, as for
+// WriteManifest.
+//
+// If c is nil, VerifyManifest uses DefaultConfig.
+func VerifyManifest(rawurl string, entries []ManifestEntry, hash string, c *Config) ([]ManifestMismatch, error) {
+	f, err := NewFile(rawurl, c)
+	if err != nil {
+		return nil, err
+	}
+	problems := make([]string, len(entries))
+	err = forEach(len(entries), func(i int) error {
+		e := entries[i]
+		u := keyURL(f.url, f.prefix+e.Key)
+		if e.VersionId != "" {
+			u += "?versionId=" + url.QueryEscape(e.VersionId)
+		}
+		info, err := Head(u, c)
+		switch {
+		case os.IsNotExist(err):
+			problems[i] = "missing"
+		case err != nil:
+			return err
+		case info.Size != e.Size:
+			problems[i] = fmt.Sprintf("size %d, want %d", info.Size, e.Size)
+		case info.ETag != e.ETag:
+			problems[i] = fmt.Sprintf("ETag %s, want %s", info.ETag, e.ETag)
+		case e.Checksum != "" && info.Metadata[hash] != e.Checksum:
+			problems[i] = fmt.Sprintf("checksum %s, want %s", info.Metadata[hash], e.Checksum)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var bad []ManifestMismatch
+	for i, p := range problems {
+		if p != "" {
+			bad = append(bad, ManifestMismatch{entries[i].Key, p})
+		}
+	}
+	return bad, nil
+}
+
+// forEach calls fn for 0 through n-1, up to concurrency at once,
+// and returns the first error.
+func forEach(n int, fn func(i int) error) error {
+	return forEachN(n, concurrency, fn)
+}
+
+// forEachN is forEach with up to limit calls at once.
+func forEachN(n, limit int, fn func(i int) error) error {
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan bool, limit)
+		mu    sync.Mutex
+		first error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- true
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return first
+}
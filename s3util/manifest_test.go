@@ -0,0 +1,103 @@
+package s3util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type manifestObj struct{ data, version, sum string }
+
+func manifestServer(objs map[string]manifestObj, mu *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		if r.Method == "GET" {
+			prefix := r.URL.Query().Get("prefix")
+			var keys []string
+			for k := range objs {
+				if strings.HasPrefix(k, prefix) {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			io.WriteString(w, "<ListBucketResult>")
+			for _, k := range keys {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><ETag>&quot;e-%s&quot;</ETag></Contents>", k, len(objs[k].data), objs[k].data)
+			}
+			io.WriteString(w, "</ListBucketResult>")
+			return
+		}
+		o, ok := objs[key]
+		if v := r.URL.Query().Get("versionId"); !ok || v != "" && v != o.version {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(o.data)))
+		w.Header().Set("Etag", `"e-`+o.data+`"`)
+		w.Header().Set("X-Amz-Version-Id", o.version)
+		w.Header().Set("X-Amz-Meta-Sha256", o.sum)
+	}))
+}
+
+func TestManifest(t *testing.T) {
+	var mu sync.Mutex
+	objs := map[string]manifestObj{
+		"p/a":   {"aaa", "v1", "s1"},
+		"p/b/c": {"cc", "v2", "s2"},
+		"p/d":   {"d", "v3", "s3"},
+		"q":     {"q", "v4", "s4"},
+	}
+	ts := manifestServer(objs, &mu)
+	defer ts.Close()
+
+	want := []ManifestEntry{
+		{"a", 3, "e-aaa", "s1", "v1"},
+		{"b/c", 2, "e-cc", "s2", "v2"},
+		{"d", 1, "e-d", "s3", "v3"},
+	}
+	for _, format := range []string{"csv", "json"} {
+		var buf bytes.Buffer
+		if err := WriteManifest(&buf, ts.URL+"/p/", format, "sha256", nil); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		g, err := ReadManifest(&buf, format)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if !reflect.DeepEqual(g, want) {
+			t.Errorf("%s: got %+v want %+v", format, g, want)
+		}
+	}
+
+	bad, err := VerifyManifest(ts.URL+"/p/", want, "sha256", nil)
+	if err != nil || len(bad) != 0 {
+		t.Fatalf("VerifyManifest = %v, %v want none", bad, err)
+	}
+	mu.Lock()
+	delete(objs, "p/a")
+	objs["p/b/c"] = manifestObj{"cc", "v2", "changed"}
+	objs["p/d"] = manifestObj{"dd", "v5", "s3"}
+	mu.Unlock()
+	bad, err = VerifyManifest(ts.URL+"/p/", want, "sha256", nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	wbad := []ManifestMismatch{
+		{"a", "missing"},
+		{"b/c", "checksum changed, want s2"},
+		{"d", "missing"}, // version v3 is gone
+	}
+	if !reflect.DeepEqual(bad, wbad) {
+		t.Errorf("VerifyManifest = %+v want %+v", bad, wbad)
+	}
+}
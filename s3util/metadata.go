@@ -0,0 +1,134 @@
+package s3util
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	metaPrefix      = "X-Amz-Meta-"
+	maxMetadataSize = 2 << 10 // keys and values together
+)
+
+// SetMetadata stores the user metadata in m as X-Amz-Meta-* fields
+// of h, for use with Create, Put, and Copy. S3 stores metadata keys
+// in lower case. SetMetadata returns an error if a key is not a
+// valid header field name, a value contains control characters or
+// invalid UTF-8, or the metadata exceeds S3's 2KB limit.
+//
+// S3 does not preserve non-ASCII characters in metadata, so values
+// containing them are stored as RFC 2047 encoded-words, such as
+// "=?utf-8?q?caf=C3=A9.txt?=", which Metadata decodes. So are
+// values containing "=?", which could otherwise be mistaken for
+// encoded-words. The limit applies to the encoded values.
+func SetMetadata(h http.Header, m map[string]string) error {
+	enc := make(map[string]string, len(m))
+	n := 0
+	for k, v := range m {
+		if err := checkMetadata(k, v); err != nil {
+			return err
+		}
+		v = encodeMetadata(v)
+		enc[k] = v
+		n += len(k) + len(v)
+	}
+	if n > maxMetadataSize {
+		return fmt.Errorf("s3util: metadata is %d bytes; limit is %d", n, maxMetadataSize)
+	}
+	for k, v := range enc {
+		h.Set(metaPrefix+k, v)
+	}
+	return nil
+}
+
+// Metadata returns the user metadata in h, the header of a response
+// to a GET or HEAD request, keyed by lower-case name without the
+// X-Amz-Meta- prefix. Values containing RFC 2047 encoded-words,
+// as written by SetMetadata, are decoded.
+func Metadata(h http.Header) map[string]string {
+	var dec mime.WordDecoder
+	m := make(map[string]string)
+	for k, v := range h {
+		if len(v) > 0 && len(k) > len(metaPrefix) && strings.EqualFold(k[:len(metaPrefix)], metaPrefix) {
+			s := v[0]
+			if strings.Contains(s, "=?") {
+				if d, err := dec.DecodeHeader(s); err == nil {
+					s = d
+				}
+			}
+			m[strings.ToLower(k[len(metaPrefix):])] = s
+		}
+	}
+	return m
+}
+
+// encodeMetadata returns v as RFC 2047 encoded-words if it is
+// not ASCII or contains "=?", and v itself otherwise.
+func encodeMetadata(v string) string {
+	if !strings.Contains(v, "=?") {
+		return mime.QEncoding.Encode("utf-8", v) // unchanged if ASCII
+	}
+	// mime leaves ASCII unchanged, so encode it here. Each word
+	// is at most 75 bytes, as RFC 2047 requires.
+	const prefix, suffix = "=?utf-8?q?", "?="
+	var b strings.Builder
+	n := 0
+	for _, r := range v {
+		var e string
+		switch {
+		case r == ' ':
+			e = "_"
+		case r > ' ' && r <= '~' && r != '=' && r != '?' && r != '_':
+			e = string(r)
+		default:
+			var buf [utf8.UTFMax]byte
+			for _, c := range buf[:utf8.EncodeRune(buf[:], r)] {
+				e += fmt.Sprintf("=%02X", c)
+			}
+		}
+		if n > 0 && n+len(e) > 75-len(prefix)-len(suffix) {
+			b.WriteString(suffix + " ")
+			n = 0
+		}
+		if n == 0 {
+			b.WriteString(prefix)
+		}
+		b.WriteString(e)
+		n += len(e)
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+func checkMetadata(k, v string) error {
+	if k == "" {
+		return fmt.Errorf("s3util: empty metadata key")
+	}
+	for i := 0; i < len(k); i++ {
+		if !isTokenChar(k[i]) {
+			return fmt.Errorf("s3util: invalid metadata key %q", k)
+		}
+	}
+	if !utf8.ValidString(v) {
+		return fmt.Errorf("s3util: invalid UTF-8 in metadata %q", k)
+	}
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c < ' ' && c != '\t' || c == 0x7f {
+			return fmt.Errorf("s3util: invalid character in metadata %q", k)
+		}
+	}
+	return nil
+}
+
+// isTokenChar reports whether c may appear in a header field
+// name, per RFC 7230 section 3.2.6.
+func isTokenChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,82 @@
+package s3util
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	m := map[string]string{"Color": "red", "file-name": "a b.txt"}
+	h := make(http.Header)
+	if err := SetMetadata(h, m); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if g := h.Get("X-Amz-Meta-File-Name"); g != "a b.txt" {
+		t.Errorf("X-Amz-Meta-File-Name = %q want %q", g, "a b.txt")
+	}
+	h.Set("Content-Type", "text/plain")
+	w := map[string]string{"color": "red", "file-name": "a b.txt"}
+	if g := Metadata(h); !reflect.DeepEqual(g, w) {
+		t.Errorf("Metadata = %v want %v", g, w)
+	}
+}
+
+func TestMetadataNonASCII(t *testing.T) {
+	for _, v := range []string{
+		"café.txt",
+		"日本語のファイル名",
+		strings.Repeat("ü", 100), // longer than one encoded-word
+	} {
+		h := make(http.Header)
+		if err := SetMetadata(h, map[string]string{"name": v}); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		enc := h.Get("X-Amz-Meta-Name")
+		for i := 0; i < len(enc); i++ {
+			if enc[i] >= 0x80 {
+				t.Errorf("encoded value %q is not ASCII", enc)
+				break
+			}
+		}
+		if g := Metadata(h)["name"]; g != v {
+			t.Errorf("round trip of %q = %q", v, g)
+		}
+	}
+}
+
+func TestMetadataEncodedWordLike(t *testing.T) {
+	for _, v := range []string{
+		"=?utf-8?q?caf=C3=A9?=",
+		"a =? b_c",
+		"café =?",
+		strings.Repeat("=?", 60), // longer than one encoded-word
+	} {
+		h := make(http.Header)
+		if err := SetMetadata(h, map[string]string{"name": v}); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if enc := h.Get("X-Amz-Meta-Name"); enc == v {
+			t.Errorf("value %q was not encoded", v)
+		}
+		if g := Metadata(h)["name"]; g != v {
+			t.Errorf("round trip of %q = %q", v, g)
+		}
+	}
+}
+
+func TestSetMetadataInvalid(t *testing.T) {
+	for _, m := range []map[string]string{
+		{"": "x"},
+		{"a b": "x"},
+		{"a:b": "x"},
+		{"k": "line\nbreak"},
+		{"k": "bad \xff utf-8"},
+		{"k": strings.Repeat("x", maxMetadataSize)},
+	} {
+		if err := SetMetadata(make(http.Header), m); err == nil {
+			t.Errorf("SetMetadata(%q): expected error", m)
+		}
+	}
+}
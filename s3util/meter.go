@@ -0,0 +1,126 @@
+package s3util
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// A Meter counts the requests made with one or more Configs,
+// the bytes of their bodies, and the bytes read from the bodies
+// of their responses, by class of operation, so that the cost
+// of a component's use of S3 can be estimated. The classes are
+// "get", "head", "list", "put", "copy", "post", and "delete";
+// S3 bills "list", "put", "copy", and "post" requests at the
+// higher rate. Every request sent is counted, including both
+// copies of a request hedged by Config.HedgeDelay and each
+// request of a redirect followed with RedirectResign.
+//
+// A Meter may be shared by several Configs and is safe for
+// concurrent use. The zero value is ready to use.
+type Meter struct {
+	mu  sync.Mutex
+	ops map[string]*opCount
+}
+
+// OpUsage is the use counted by a Meter for one class of operation.
+type OpUsage struct {
+	Requests      int64
+	BytesSent     int64
+	BytesReceived int64
+}
+
+type opCount struct {
+	requests, sent, received atomic.Int64
+}
+
+// Usage returns a snapshot of the counts, by operation class.
+func (m *Meter) Usage() map[string]OpUsage {
+	u := make(map[string]OpUsage)
+	if m == nil {
+		return u
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for op, n := range m.ops {
+		u[op] = OpUsage{
+			Requests:      n.requests.Load(),
+			BytesSent:     n.sent.Load(),
+			BytesReceived: n.received.Load(),
+		}
+	}
+	return u
+}
+
+// count records the sending of r and returns the counters
+// for its class, or nil if m is nil.
+func (m *Meter) count(r *http.Request) *opCount {
+	if m == nil {
+		return nil
+	}
+	op := opClass(r)
+	m.mu.Lock()
+	n := m.ops[op]
+	if n == nil {
+		if m.ops == nil {
+			m.ops = make(map[string]*opCount)
+		}
+		n = new(opCount)
+		m.ops[op] = n
+	}
+	m.mu.Unlock()
+	n.requests.Add(1)
+	if r.ContentLength > 0 {
+		n.sent.Add(r.ContentLength)
+	}
+	return n
+}
+
+// meterBody arranges for the bytes read from resp's body to be
+// added to n.
+func meterBody(n *opCount, resp *http.Response) {
+	if n != nil && resp != nil && resp.Body != nil {
+		resp.Body = &meteredBody{resp.Body, n}
+	}
+}
+
+type meteredBody struct {
+	io.ReadCloser
+	n *opCount
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.received.Add(int64(n))
+	return n, err
+}
+
+// opClass returns the operation class of r for a Meter.
+func opClass(r *http.Request) string {
+	switch r.Method {
+	case "GET":
+		q := r.URL.Query()
+		for _, k := range []string{"prefix", "list-type", "uploads", "uploadId", "versions"} {
+			if _, ok := q[k]; ok {
+				return "list"
+			}
+		}
+		if r.URL.RawQuery == "" && (r.URL.Path == "" || r.URL.Path == "/") {
+			return "list"
+		}
+		return "get"
+	case "PUT":
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			return "copy"
+		}
+		return "put"
+	case "HEAD":
+		return "head"
+	case "POST":
+		return "post"
+	case "DELETE":
+		return "delete"
+	}
+	return r.Method
+}
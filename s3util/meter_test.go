@@ -0,0 +1,132 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMeter(t *testing.T) {
+	c := *DefaultConfig
+	c.Meter = new(Meter)
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := ""
+			switch {
+			case req.Method == "GET":
+				body = "hello, world"
+			case req.Header.Get("X-Amz-Copy-Source") != "":
+				body = "<CopyObjectResult></CopyObjectResult>"
+			}
+			return &http.Response{
+				StatusCode:    200,
+				ContentLength: int64(len(body)),
+				Header:        http.Header{"Etag": {`"e"`}},
+				Body:          ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+	const u = "https://b.s3.amazonaws.com/k"
+	if _, err := Put(u, strings.NewReader("abc"), nil, &c); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Get(u, ioutil.Discard, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Copy(u+"2", u, nil, &c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Head(u, &c); err != nil {
+		t.Fatal(err)
+	}
+	w := map[string]OpUsage{
+		"put":  {Requests: 1, BytesSent: 3},
+		"get":  {Requests: 2, BytesReceived: 24},
+		"copy": {Requests: 1, BytesReceived: 37},
+		"head": {Requests: 1},
+	}
+	if g := c.Meter.Usage(); !reflect.DeepEqual(g, w) {
+		t.Errorf("Usage = %+v want %+v", g, w)
+	}
+}
+
+func TestMeterHedge(t *testing.T) {
+	var (
+		mu sync.Mutex
+		n  int
+	)
+	c := *DefaultConfig
+	c.Meter = new(Meter)
+	c.HedgeDelay = 10 * time.Millisecond
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			n++
+			first := n == 1
+			mu.Unlock()
+			if first {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	if _, err := Head("https://b.s3.amazonaws.com/k", &c); err != nil {
+		t.Fatal(err)
+	}
+	if g := c.Meter.Usage()["head"].Requests; g != 2 {
+		t.Errorf("head requests = %d want 2", g)
+	}
+}
+
+func TestMeterRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket/old" {
+			w.Header().Set("Location", "/bucket/new")
+			w.WriteHeader(307)
+			return
+		}
+		w.Header().Set("Etag", `"e"`)
+	}))
+	defer ts.Close()
+
+	c := *DefaultConfig
+	c.Meter = new(Meter)
+	c.Redirects = RedirectResign
+	if _, err := Put(ts.URL+"/bucket/old", strings.NewReader("data"), nil, &c); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := OpUsage{Requests: 2, BytesSent: 8}
+	if g := c.Meter.Usage()["put"]; g != w {
+		t.Errorf("put usage = %+v want %+v", g, w)
+	}
+}
+
+func TestOpClass(t *testing.T) {
+	for _, ts := range []struct {
+		method, url, w string
+	}{
+		{"GET", "https://b.s3.amazonaws.com/k", "get"},
+		{"GET", "https://b.s3.amazonaws.com/?prefix=a%2F", "list"},
+		{"GET", "https://b.s3.amazonaws.com/", "list"},
+		{"GET", "https://b.s3.amazonaws.com/?acl", "get"},
+		{"GET", "https://b.s3.amazonaws.com/k?uploadId=x", "list"},
+		{"POST", "https://b.s3.amazonaws.com/k?uploads", "post"},
+		{"DELETE", "https://b.s3.amazonaws.com/k", "delete"},
+	} {
+		r, _ := http.NewRequest(ts.method, ts.url, nil)
+		if g := opClass(r); g != ts.w {
+			t.Errorf("opClass(%s %s) = %q want %q", ts.method, ts.url, g, ts.w)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package s3util
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// A Destination names an object to be written by CreateMirror
+// and the Config used to write it.
+type Destination struct {
+	URL    string
+	Config *Config // if nil, DefaultConfig is used
+}
+
+// A Mirror uploads the same data to several S3 objects at once,
+// possibly in different services. It is returned by CreateMirror.
+type Mirror struct {
+	us  []*Uploader
+	err error
+}
+
+// CreateMirror creates a multipart upload to each of dsts, with
+// header h, and returns a Mirror that writes its data to all of
+// them. The upload succeeds only if every destination does: the
+// first failure aborts the uploads still in progress and is
+// returned by Write or Close.
+//
+// Each destination has its own part buffers, so a Mirror uses as
+// much memory as an Uploader for each destination. The WrapWriters
+// of the destinations' Configs are not used.
+//
+// The objects are completed independently when Close is called,
+// so if one fails to complete, the others may already exist.
+// Close's error then names the destinations that failed.
+func CreateMirror(h http.Header, dsts ...Destination) (*Mirror, error) {
+	if len(dsts) == 0 {
+		return nil, errors.New("s3util: no mirror destinations")
+	}
+	m := new(Mirror)
+	for _, d := range dsts {
+		c := d.Config
+		if c == nil {
+			c = DefaultConfig
+		}
+		u, err := newUploader(context.Background(), d.URL, h, c)
+		if err != nil {
+			m.Abort()
+			return nil, err
+		}
+		m.us = append(m.us, u)
+	}
+	return m, nil
+}
+
+// Write writes p to every destination.
+func (m *Mirror) Write(p []byte) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	for _, u := range m.us {
+		if _, err := u.Write(p); err != nil {
+			m.err = err
+			m.Abort()
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close completes the uploads to all destinations, concurrently.
+func (m *Mirror) Close() error {
+	if m.err != nil {
+		return m.err
+	}
+	errs := make([]error, len(m.us))
+	var wg sync.WaitGroup
+	for i, u := range m.us {
+		wg.Add(1)
+		go func(i int, u *Uploader) {
+			defer wg.Done()
+			errs[i] = u.Close()
+		}(i, u)
+	}
+	wg.Wait()
+	var failed []error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, &mirrorError{m.us[i].url, err})
+		}
+	}
+	m.err = errors.Join(failed...)
+	return m.err
+}
+
+// Abort aborts the uploads to all destinations.
+func (m *Mirror) Abort() error {
+	var first error
+	for _, u := range m.us {
+		if err := u.Abort(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Results returns the Result of the upload to each destination,
+// in order, or nil if Close has not returned successfully.
+func (m *Mirror) Results() []*Result {
+	if m.err != nil {
+		return nil
+	}
+	rs := make([]*Result, len(m.us))
+	for i, u := range m.us {
+		if rs[i] = u.Result(); rs[i] == nil {
+			return nil
+		}
+	}
+	return rs
+}
+
+// mirrorError reports the failure of one destination of a Mirror.
+type mirrorError struct {
+	url string
+	err error
+}
+
+func (e *mirrorError) Error() string { return "s3util: mirror to " + e.url + ": " + e.err.Error() }
+func (e *mirrorError) Unwrap() error { return e.err }
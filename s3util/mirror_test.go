@@ -0,0 +1,90 @@
+package s3util
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mirrorConfig returns a config whose fake service accepts a
+// multipart upload, appending the parts' data to *got. If fail is
+// set, completing the upload fails.
+func mirrorConfig(mu *sync.Mutex, got *string, fail bool) *Config {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{StatusCode: 200, Header: make(http.Header)}
+			var s string
+			switch q := req.URL.Query(); {
+			case q["uploads"] != nil:
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case q.Get("partNumber") != "":
+				b, _ := ioutil.ReadAll(req.Body)
+				mu.Lock()
+				*got += string(b)
+				mu.Unlock()
+				resp.Header.Set("Etag", `"1"`)
+			case req.Method == "POST" && fail:
+				resp.StatusCode = 500
+			case req.Method == "POST":
+				s = `<CompleteMultipartUploadResult><ETag>"x-1"</ETag></CompleteMultipartUploadResult>`
+			}
+			resp.Body = ioutil.NopCloser(strings.NewReader(s))
+			return resp, nil
+		}),
+	}
+	return &c
+}
+
+func TestMirror(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		g1, g2 string
+	)
+	m, err := CreateMirror(nil,
+		Destination{"https://a.s3.amazonaws.com/k", mirrorConfig(&mu, &g1, false)},
+		Destination{"https://b.s3.amazonaws.com/k", mirrorConfig(&mu, &g2, false)},
+	)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	io.WriteString(m, "hello, ")
+	io.WriteString(m, "mirror")
+	if err := m.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if g1 != "hello, mirror" || g2 != g1 {
+		t.Errorf("got %q and %q want hello, mirror", g1, g2)
+	}
+	if rs := m.Results(); len(rs) != 2 || rs[1].ETag != "x-1" {
+		t.Errorf("Results() = %v", rs)
+	}
+}
+
+func TestMirrorFail(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		g1, g2 string
+	)
+	m, err := CreateMirror(nil,
+		Destination{"https://a.s3.amazonaws.com/k", mirrorConfig(&mu, &g1, false)},
+		Destination{"https://b.s3.amazonaws.com/k", mirrorConfig(&mu, &g2, true)},
+	)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	io.WriteString(m, "data")
+	err = m.Close()
+	if err == nil || !strings.Contains(err.Error(), "https://b.s3.amazonaws.com/k") {
+		t.Errorf("err = %v, want failure of b", err)
+	}
+	if strings.Contains(err.Error(), "https://a.s3") {
+		t.Errorf("err = %v, names a", err)
+	}
+	if m.Results() != nil {
+		t.Error("Results() not nil after failure")
+	}
+}
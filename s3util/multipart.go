@@ -0,0 +1,104 @@
+package s3util
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// A MultipartUpload is a multipart upload whose parts are sent by
+// other clients, such as browsers or workers, using presigned URLs.
+// A coordinator initiates the upload with InitiateMultipart, hands
+// out a URL from PresignPart for each part, and, once the parts have
+// been sent, calls Complete with their ETags.
+type MultipartUpload struct {
+	URL      string
+	UploadId string
+
+	c *Config
+}
+
+// InitiateMultipart starts a multipart upload of the S3 object at url.
+// If h is not nil, each of its entries is added to the HTTP request
+// header, along with any defaults in c.Header.
+//
+// If c is nil, InitiateMultipart uses DefaultConfig.
+func InitiateMultipart(url string, h http.Header, c *Config) (*MultipartUpload, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	u := bareUploader(url, c)
+	u.h = c.objectHeader(h)
+	if err := u.initiate(); err != nil {
+		return nil, err
+	}
+	return &MultipartUpload{URL: url, UploadId: u.UploadId, c: c}, nil
+}
+
+// bareUploader returns an Uploader for making the requests of
+// a multipart upload, with no buffers or workers.
+func bareUploader(url string, c *Config) *Uploader {
+	u := &Uploader{
+		ctx:  context.Background(),
+		s3:   *c.Service,
+		keys: *c.Keys,
+		url:  url,
+	}
+	u.c = u.config(c)
+	return u
+}
+
+// PresignPart returns a URL with which a client can upload part n
+// of m, numbered from 1, with a PUT request before the given time.
+// The request must not set Content-Type or Content-MD5, which are
+// covered by the signature. The ETag header of the response to
+// that request is needed to complete the upload.
+func (m *MultipartUpload) PresignPart(n int, expires time.Time) (string, error) {
+	v := url.Values{}
+	v.Set("partNumber", strconv.Itoa(n))
+	v.Set("uploadId", m.UploadId)
+	r, err := http.NewRequest("PUT", m.URL+"?"+v.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	m.c.scopeURL(r.URL)
+	m.c.Presign(r, *m.c.Keys, expires)
+	return r.URL.String(), nil
+}
+
+// Complete completes the upload from its parts, whose ETags,
+// in order of part number starting at 1, are given in etags.
+func (m *MultipartUpload) Complete(etags []string) (*Result, error) {
+	u := bareUploader(m.URL, m.c)
+	u.UploadId = m.UploadId
+	for i, etag := range etags {
+		u.xml.Part = append(u.xml.Part, &part{PartNumber: i + 1, ETag: etag})
+	}
+	if err := checkParts(u.xml.Part); err != nil {
+		return nil, err
+	}
+	body, err := xml.Marshal(u.xml)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < nTry; i++ {
+		err = u.complete(body)
+		if e, ok := err.(*xmlError); !ok || !e.transient() {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u.result, nil
+}
+
+// Abort aborts the upload, discarding any parts already sent.
+func (m *MultipartUpload) Abort() error {
+	u := bareUploader(m.URL, m.c)
+	u.UploadId = m.UploadId
+	return u.abort()
+}
@@ -3,7 +3,6 @@ package s3util
 import (
 	"io"
 	"net/http"
-	"time"
 )
 
 // Open requests the S3 object at url. An HTTP status other than 200 is
@@ -16,13 +15,7 @@ func Open(url string, c *Config) (io.ReadCloser, error) {
 	}
 	// TODO(kr): maybe parallel range fetching
 	r, _ := http.NewRequest("GET", url, nil)
-	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-	c.Sign(r, *c.Keys)
-	client := c.Client
-	if client == nil {
-		client = http.DefaultClient
-	}
-	resp, err := client.Do(r)
+	resp, err := c.do(r)
 	if err != nil {
 		return nil, err
 	}
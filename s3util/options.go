@@ -0,0 +1,137 @@
+package s3util
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// ObjectOptions holds settings for new objects as typed fields,
+// instead of raw header fields. It is taken by CreateWithOptions,
+// CreateSizedWithOptions, PutWithOptions, and CopyWithOptions:
+//
+//	w, err := s3util.CreateWithOptions(url, &s3util.ObjectOptions{
+//		ContentType:  "text/csv",
+//		StorageClass: s3util.StorageClassStandardIA,
+//	}, nil)
+//
+// Create, CreateSized, Put, and Copy are the same functions with
+// only Extra set.
+//
+// For the meaning of these settings, see
+// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPUT.html.
+type ObjectOptions struct {
+	// These set the standard HTTP header fields of the same name.
+	ContentType        string
+	ContentEncoding    string
+	ContentLanguage    string
+	ContentDisposition string
+	CacheControl       string
+
+	// ACL is a canned ACL, such as "private" or "public-read".
+	ACL string
+
+	// Grants lists grantees by permission. Its Write field
+	// applies only to buckets and must be empty.
+	Grants *Grants
+
+	StorageClass string // such as StorageClassStandardIA
+
+	// SSE is the server-side encryption algorithm, "AES256" or
+	// "aws:kms". SSEKMSKeyId names the KMS key, if not the default.
+	SSE         string
+	SSEKMSKeyId string
+
+	// SSECustomerKey, if set, is a 256-bit key with which S3
+	// encrypts the object (SSE-C). The same key must be given
+	// to read the object.
+	SSECustomerKey []byte
+
+	Tagging         map[string]string
+	Metadata        map[string]string // see SetMetadata
+	WebsiteRedirect string            // redirect location for website hosting
+	RequesterPays   bool              // the requester pays for the request
+
+	// ReplaceMetadata makes Copy replace the source object's
+	// metadata and tags with those given here, rather than
+	// copying them.
+	ReplaceMetadata bool
+
+	// Extra holds further header fields, added as given,
+	// for settings that have no field here.
+	Extra http.Header
+}
+
+// Header returns the header fields for the settings in o, which
+// may be nil. It returns an error if the settings are inconsistent.
+func (o *ObjectOptions) Header() (http.Header, error) {
+	h := make(http.Header)
+	if o == nil {
+		return h, nil
+	}
+	set := func(k, v string) {
+		if v != "" {
+			h.Set(k, v)
+		}
+	}
+	set("Content-Type", o.ContentType)
+	set("Content-Encoding", o.ContentEncoding)
+	set("Content-Language", o.ContentLanguage)
+	set("Content-Disposition", o.ContentDisposition)
+	set("Cache-Control", o.CacheControl)
+	set("X-Amz-Acl", o.ACL)
+	if o.Grants != nil {
+		if len(o.Grants.Write) > 0 {
+			return nil, errors.New("s3util: Grants.Write is for buckets, not objects")
+		}
+		if err := o.Grants.addTo(h); err != nil {
+			return nil, err
+		}
+	}
+	set("X-Amz-Storage-Class", o.StorageClass)
+	set("X-Amz-Website-Redirect-Location", o.WebsiteRedirect)
+
+	if o.SSEKMSKeyId != "" && o.SSE != "aws:kms" {
+		return nil, errors.New("s3util: SSEKMSKeyId requires SSE aws:kms")
+	}
+	set("X-Amz-Server-Side-Encryption", o.SSE)
+	set("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id", o.SSEKMSKeyId)
+	if k := o.SSECustomerKey; k != nil {
+		if len(k) != 32 {
+			return nil, errors.New("s3util: SSECustomerKey must be 32 bytes")
+		}
+		if o.SSE != "" {
+			return nil, errors.New("s3util: SSE and SSECustomerKey are mutually exclusive")
+		}
+		sum := md5.Sum(k)
+		h.Set("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")
+		h.Set("X-Amz-Server-Side-Encryption-Customer-Key", base64.StdEncoding.EncodeToString(k))
+		h.Set("X-Amz-Server-Side-Encryption-Customer-Key-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+
+	if len(o.Tagging) > 0 {
+		v := make(url.Values)
+		for k, s := range o.Tagging {
+			v.Set(k, s)
+		}
+		h.Set("X-Amz-Tagging", v.Encode())
+	}
+	if err := SetMetadata(h, o.Metadata); err != nil {
+		return nil, err
+	}
+	if o.RequesterPays {
+		h.Set("X-Amz-Request-Payer", "requester")
+	}
+	if o.ReplaceMetadata {
+		h.Set("X-Amz-Metadata-Directive", "REPLACE")
+		h.Set("X-Amz-Tagging-Directive", "REPLACE")
+	}
+	for k, vs := range o.Extra {
+		for _, v := range vs {
+			h.Add(k, v)
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,91 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectOptionsHeader(t *testing.T) {
+	key := make([]byte, 32)
+	o := &ObjectOptions{
+		ACL:             "public-read",
+		Grants:          &Grants{Read: []Grantee{CanonicalUser("abc")}},
+		StorageClass:    "STANDARD_IA",
+		SSECustomerKey:  key,
+		Tagging:         map[string]string{"b": "2 3", "a": "1"},
+		Metadata:        map[string]string{"color": "red"},
+		WebsiteRedirect: "/other",
+		RequesterPays:   true,
+		ReplaceMetadata: true,
+	}
+	h, err := o.Header()
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := http.Header{
+		"X-Amz-Acl":                       {"public-read"},
+		"X-Amz-Grant-Read":                {`id="abc"`},
+		"X-Amz-Storage-Class":             {"STANDARD_IA"},
+		"X-Amz-Website-Redirect-Location": {"/other"},
+		"X-Amz-Server-Side-Encryption-Customer-Algorithm": {"AES256"},
+		"X-Amz-Server-Side-Encryption-Customer-Key":       {"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="},
+		"X-Amz-Server-Side-Encryption-Customer-Key-Md5":   {"cLyPS3KoaSFGi/joRB3OUQ=="},
+		"X-Amz-Tagging":            {"a=1&b=2+3"},
+		"X-Amz-Meta-Color":         {"red"},
+		"X-Amz-Request-Payer":      {"requester"},
+		"X-Amz-Metadata-Directive": {"REPLACE"},
+		"X-Amz-Tagging-Directive":  {"REPLACE"},
+	}
+	if !reflect.DeepEqual(h, w) {
+		t.Errorf("Header() = %v want %v", h, w)
+	}
+
+	for _, o := range []*ObjectOptions{
+		{SSEKMSKeyId: "k"},
+		{SSE: "AES256", SSEKMSKeyId: "k"},
+		{SSECustomerKey: []byte("short")},
+		{SSE: "AES256", SSECustomerKey: key},
+	} {
+		if _, err := o.Header(); err == nil {
+			t.Errorf("Header() of %+v: expected error", o)
+		}
+	}
+}
+
+func TestPutWithOptions(t *testing.T) {
+	var got http.Header
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			got = req.Header
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+				Header:     http.Header{"Etag": {`"foo"`}},
+			}, nil
+		}),
+	}
+	o := &ObjectOptions{
+		ContentType:  "text/csv",
+		CacheControl: "no-cache",
+		StorageClass: StorageClassStandardIA,
+		Extra:        http.Header{"Expires": {"Thu, 01 Dec 2044 16:00:00 GMT"}},
+	}
+	_, err := PutWithOptions("https://mybucket.s3.amazonaws.com/a.csv", strings.NewReader("a,b"), o, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	for k, v := range map[string]string{
+		"Content-Type":        "text/csv",
+		"Cache-Control":       "no-cache",
+		"X-Amz-Storage-Class": "STANDARD_IA",
+		"Expires":             "Thu, 01 Dec 2044 16:00:00 GMT",
+	} {
+		if g := got.Get(k); g != v {
+			t.Errorf("%s = %q want %q", k, g, v)
+		}
+	}
+}
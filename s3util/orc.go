@@ -0,0 +1,902 @@
+package s3util
+
+// This file reads the ORC files of S3 Inventory reports. It supports
+// what those use: a struct of string, integer, boolean, and timestamp
+// columns, either uncompressed or with ZLIB compression. See
+// https://orc.apache.org/specification/ORCv1/.
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+var errBadORC = errors.New("malformed ORC file")
+
+// ORC type kinds.
+const (
+	orcBoolean          = 0
+	orcShort            = 2
+	orcInt              = 3
+	orcLong             = 4
+	orcString           = 7
+	orcTimestamp        = 9
+	orcStruct           = 12
+	orcVarchar          = 16
+	orcChar             = 17
+	orcTimestampInstant = 18
+)
+
+// ORC stream kinds.
+const (
+	orcPresent        = 0
+	orcData           = 1
+	orcLength         = 2
+	orcDictionaryData = 3
+	orcSecondary      = 5
+)
+
+// ORC column encodings.
+const (
+	orcDirect       = 0
+	orcDictionary   = 1
+	orcDirectV2     = 2
+	orcDictionaryV2 = 3
+)
+
+// ORC compression codecs.
+const (
+	orcNone = 0
+	orcZlib = 1
+)
+
+// orcEpoch is the time from which ORC timestamps are counted,
+// in seconds since the Unix epoch.
+const orcEpoch = 1420070400 // 2015-01-01T00:00:00Z
+
+// An orcFile is an open ORC file.
+type orcFile struct {
+	r       io.ReaderAt
+	codec   uint64
+	stripes []orcStripe
+
+	// The columns of the root struct that we can read.
+	names   []string
+	columns []int // column ids
+	kinds   []int
+}
+
+type orcStripe struct {
+	offset, indexLength, dataLength, footerLength, rows uint64
+}
+
+// openORC reads the footer of the ORC file in r, of the given size.
+// Columns of types other than those used by inventory reports are
+// left out.
+func openORC(r io.ReaderAt, size int64) (*orcFile, error) {
+	n := int64(16 << 10)
+	if n > size {
+		n = size
+	}
+	tail, err := orcRead(r, size-n, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(tail) == 0 {
+		return nil, errBadORC
+	}
+	psLen := int64(tail[len(tail)-1])
+	if psLen+1 > n {
+		return nil, errBadORC
+	}
+	ps, err := pbFields(tail[n-1-psLen : n-1])
+	if err != nil {
+		return nil, err
+	}
+	f := &orcFile{r: r}
+	var footerLen uint64
+	for _, fl := range ps {
+		switch fl.num {
+		case 1:
+			footerLen = fl.v
+		case 2:
+			f.codec = fl.v
+		case 8000:
+			if string(fl.b) != "ORC" {
+				return nil, errBadORC
+			}
+		}
+	}
+	if footerLen > uint64(size-psLen-1) {
+		return nil, errBadORC
+	}
+	off := size - psLen - 1 - int64(footerLen)
+	var b []byte
+	if off >= size-n {
+		b = tail[off-(size-n) : n-1-psLen]
+	} else if b, err = orcRead(r, off, int64(footerLen)); err != nil {
+		return nil, err
+	}
+	if b, err = orcDecompress(f.codec, b); err != nil {
+		return nil, err
+	}
+	footer, err := pbFields(b)
+	if err != nil {
+		return nil, err
+	}
+	var types [][]pbField
+	for _, fl := range footer {
+		switch fl.num {
+		case 3:
+			sf, err := pbFields(fl.b)
+			if err != nil {
+				return nil, err
+			}
+			var s orcStripe
+			for _, fl := range sf {
+				switch fl.num {
+				case 1:
+					s.offset = fl.v
+				case 2:
+					s.indexLength = fl.v
+				case 3:
+					s.dataLength = fl.v
+				case 4:
+					s.footerLength = fl.v
+				case 5:
+					s.rows = fl.v
+				}
+			}
+			f.stripes = append(f.stripes, s)
+		case 4:
+			tf, err := pbFields(fl.b)
+			if err != nil {
+				return nil, err
+			}
+			types = append(types, tf)
+		}
+	}
+	if len(types) == 0 || orcKind(types[0]) != orcStruct {
+		return nil, errors.New("ORC file is not a table")
+	}
+	var names []string
+	var subtypes []uint64
+	for _, fl := range types[0] {
+		switch fl.num {
+		case 2:
+			subtypes = append(subtypes, fl.uints()...)
+		case 3:
+			names = append(names, string(fl.b))
+		}
+	}
+	if len(names) != len(subtypes) {
+		return nil, errBadORC
+	}
+	for i, id := range subtypes {
+		if id == 0 || id >= uint64(len(types)) {
+			return nil, errBadORC
+		}
+		switch k := orcKind(types[id]); k {
+		case orcBoolean, orcShort, orcInt, orcLong, orcString, orcVarchar, orcChar, orcTimestamp, orcTimestampInstant:
+			f.names = append(f.names, names[i])
+			f.columns = append(f.columns, int(id))
+			f.kinds = append(f.kinds, k)
+		}
+	}
+	return f, nil
+}
+
+func orcKind(t []pbField) int {
+	for _, fl := range t {
+		if fl.num == 1 {
+			return int(fl.v)
+		}
+	}
+	return 0
+}
+
+// orcRead reads n bytes at off in r.
+func orcRead(r io.ReaderAt, off, n int64) ([]byte, error) {
+	b := make([]byte, n)
+	m, err := r.ReadAt(b, off)
+	if m == len(b) {
+		return b, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+// readStripe returns the values of f's columns in stripe s,
+// formatted as in a CSV inventory report, with "" for null.
+func (f *orcFile) readStripe(s orcStripe) ([][]string, error) {
+	b, err := orcRead(f.r, int64(s.offset), int64(s.indexLength+s.dataLength+s.footerLength))
+	if err != nil {
+		return nil, err
+	}
+	end := s.indexLength + s.dataLength
+	sfb, err := orcDecompress(f.codec, b[end:])
+	if err != nil {
+		return nil, err
+	}
+	sf, err := pbFields(sfb)
+	if err != nil {
+		return nil, err
+	}
+	type key struct{ col, kind uint64 }
+	streams := make(map[key][]byte)
+	var encs [][]pbField
+	loc := time.UTC
+	off := uint64(0)
+	for _, fl := range sf {
+		switch fl.num {
+		case 1:
+			st, err := pbFields(fl.b)
+			if err != nil {
+				return nil, err
+			}
+			var k key
+			var n uint64
+			for _, fl := range st {
+				switch fl.num {
+				case 1:
+					k.kind = fl.v
+				case 2:
+					k.col = fl.v
+				case 3:
+					n = fl.v
+				}
+			}
+			if n > end-off {
+				return nil, errBadORC
+			}
+			streams[k] = b[off : off+n]
+			off += n
+		case 2:
+			enc, err := pbFields(fl.b)
+			if err != nil {
+				return nil, err
+			}
+			encs = append(encs, enc)
+		case 3:
+			if l, err := time.LoadLocation(string(fl.b)); err == nil {
+				loc = l
+			}
+		}
+	}
+
+	cols := make([][]string, len(f.columns))
+	for i, id := range f.columns {
+		if id >= len(encs) {
+			return nil, errBadORC
+		}
+		var enc, dictSize uint64
+		for _, fl := range encs[id] {
+			switch fl.num {
+			case 1:
+				enc = fl.v
+			case 2:
+				dictSize = fl.v
+			}
+		}
+		stream := func(kind uint64) ([]byte, error) {
+			return orcDecompress(f.codec, streams[key{uint64(id), kind}])
+		}
+		cols[i], err = readORCColumn(f.kinds[i], enc, dictSize, int(s.rows), loc, stream)
+		if err != nil {
+			return nil, fmt.Errorf("ORC column %s: %v", f.names[i], err)
+		}
+	}
+	return cols, nil
+}
+
+// readORCColumn decodes the given number of rows of a column
+// with the given type kind and encoding, reading its streams
+// with stream. Timestamps are taken to be in loc, except for
+// those of kind orcTimestampInstant, which are in UTC.
+func readORCColumn(kind int, enc, dictSize uint64, rows int, loc *time.Location, stream func(kind uint64) ([]byte, error)) ([]string, error) {
+	b, err := stream(orcPresent)
+	if err != nil {
+		return nil, err
+	}
+	present := make([]bool, rows)
+	n := rows
+	if len(b) > 0 {
+		if present, err = orcBools(b, rows); err != nil {
+			return nil, err
+		}
+		n = 0
+		for _, p := range present {
+			if p {
+				n++
+			}
+		}
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+	data, err := stream(orcData)
+	if err != nil {
+		return nil, err
+	}
+	v2 := enc == orcDirectV2 || enc == orcDictionaryV2
+	vals := make([]string, n)
+	switch kind {
+	case orcBoolean:
+		bs, err := orcBools(data, n)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range bs {
+			vals[i] = strconv.FormatBool(v)
+		}
+	case orcShort, orcInt, orcLong:
+		ints, err := orcInts(data, n, true, v2)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range ints {
+			vals[i] = strconv.FormatInt(v, 10)
+		}
+	case orcString, orcVarchar, orcChar:
+		lb, err := stream(orcLength)
+		if err != nil {
+			return nil, err
+		}
+		if enc == orcDictionary || enc == orcDictionaryV2 {
+			db, err := stream(orcDictionaryData)
+			if err != nil {
+				return nil, err
+			}
+			lens, err := orcInts(lb, int(dictSize), false, v2)
+			if err != nil {
+				return nil, err
+			}
+			dict, err := orcStrings(db, lens)
+			if err != nil {
+				return nil, err
+			}
+			idx, err := orcInts(data, n, false, v2)
+			if err != nil {
+				return nil, err
+			}
+			for i, j := range idx {
+				if j < 0 || j >= int64(len(dict)) {
+					return nil, errBadORC
+				}
+				vals[i] = dict[j]
+			}
+		} else {
+			lens, err := orcInts(lb, n, false, v2)
+			if err != nil {
+				return nil, err
+			}
+			if vals, err = orcStrings(data, lens); err != nil {
+				return nil, err
+			}
+		}
+	case orcTimestamp, orcTimestampInstant:
+		sb, err := stream(orcSecondary)
+		if err != nil {
+			return nil, err
+		}
+		secs, err := orcInts(data, n, true, v2)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := orcInts(sb, n, false, v2)
+		if err != nil {
+			return nil, err
+		}
+		base := int64(orcEpoch)
+		if kind == orcTimestamp {
+			// Counted from the epoch in the writer's time zone.
+			base = time.Date(2015, 1, 1, 0, 0, 0, 0, loc).Unix()
+		}
+		for i := range vals {
+			t := time.Unix(base+secs[i], orcNanos(nanos[i]))
+			vals[i] = t.UTC().Format(time.RFC3339Nano)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %d", kind)
+	}
+
+	col := make([]string, rows)
+	j := 0
+	for i, p := range present {
+		if p {
+			col[i] = vals[j]
+			j++
+		}
+	}
+	return col, nil
+}
+
+// orcNanos decodes the nanoseconds of an ORC timestamp,
+// whose low 3 bits count trailing decimal zeros dropped.
+func orcNanos(v int64) int64 {
+	n := v >> 3
+	if z := v & 7; z != 0 {
+		for i := int64(0); i <= z; i++ {
+			n *= 10
+		}
+	}
+	return n
+}
+
+// orcStrings splits b into strings of the given lengths.
+func orcStrings(b []byte, lens []int64) ([]string, error) {
+	s := make([]string, len(lens))
+	for i, l := range lens {
+		if l < 0 || l > int64(len(b)) {
+			return nil, errBadORC
+		}
+		s[i] = string(b[:l])
+		b = b[l:]
+	}
+	return s, nil
+}
+
+// orcDecompress returns the contents of b, an ORC stream compressed
+// with codec, which is made of chunks each with a 3-byte header.
+func orcDecompress(codec uint64, b []byte) ([]byte, error) {
+	switch codec {
+	case orcNone:
+		return b, nil
+	case orcZlib:
+	default:
+		return nil, fmt.Errorf("unsupported ORC compression %d", codec)
+	}
+	var out bytes.Buffer
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, errBadORC
+		}
+		h := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+		n := h >> 1
+		b = b[3:]
+		if n > len(b) {
+			return nil, errBadORC
+		}
+		if h&1 != 0 { // stored uncompressed
+			out.Write(b[:n])
+		} else {
+			zr := flate.NewReader(bytes.NewReader(b[:n]))
+			_, err := io.Copy(&out, zr)
+			zr.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		b = b[n:]
+	}
+	return out.Bytes(), nil
+}
+
+// orcBytes decodes n bytes in ORC's byte run-length encoding.
+func orcBytes(b []byte, n int) ([]byte, error) {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		if len(b) == 0 {
+			return nil, errBadORC
+		}
+		h := int(b[0])
+		b = b[1:]
+		if h < 128 {
+			if len(b) == 0 {
+				return nil, errBadORC
+			}
+			for i := 0; i < h+3; i++ {
+				out = append(out, b[0])
+			}
+			b = b[1:]
+		} else {
+			l := 256 - h
+			if l > len(b) {
+				return nil, errBadORC
+			}
+			out = append(out, b[:l]...)
+			b = b[l:]
+		}
+	}
+	return out[:n], nil
+}
+
+// orcBools decodes n booleans, stored as bits, most significant
+// first, in ORC's byte run-length encoding.
+func orcBools(b []byte, n int) ([]bool, error) {
+	bs, err := orcBytes(b, (n+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = bs[i/8]&(0x80>>(i%8)) != 0
+	}
+	return out, nil
+}
+
+// orcInts decodes n integers in ORC's integer run-length encoding,
+// version 2 if v2 is set, otherwise version 1.
+func orcInts(b []byte, n int, signed, v2 bool) ([]int64, error) {
+	d := &orcIntDecoder{b: b, signed: signed}
+	out := make([]int64, 0, n)
+	for len(out) < n {
+		var err error
+		if v2 {
+			out, err = d.runV2(out)
+		} else {
+			out, err = d.runV1(out)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out[:n], nil
+}
+
+type orcIntDecoder struct {
+	b      []byte
+	signed bool
+}
+
+func (d *orcIntDecoder) byte() (byte, error) {
+	if len(d.b) == 0 {
+		return 0, errBadORC
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c, nil
+}
+
+func (d *orcIntDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		return 0, errBadORC
+	}
+	d.b = d.b[n:]
+	return v, nil
+}
+
+func (d *orcIntDecoder) svarint() (int64, error) {
+	v, err := d.uvarint()
+	return unzigzag(v), err
+}
+
+// value decodes an integer, which is zigzag encoded if d is signed.
+func (d *orcIntDecoder) value(u uint64) int64 {
+	if d.signed {
+		return unzigzag(u)
+	}
+	return int64(u)
+}
+
+func (d *orcIntDecoder) varint() (int64, error) {
+	u, err := d.uvarint()
+	return d.value(u), err
+}
+
+// bigEndian reads a w-byte big-endian integer.
+func (d *orcIntDecoder) bigEndian(w int) (uint64, error) {
+	if w > len(d.b) || w > 8 {
+		return 0, errBadORC
+	}
+	var v uint64
+	for _, c := range d.b[:w] {
+		v = v<<8 | uint64(c)
+	}
+	d.b = d.b[w:]
+	return v, nil
+}
+
+// unpack reads n integers of w bits each, packed most significant
+// bit first and padded to a whole byte.
+func (d *orcIntDecoder) unpack(n, w int) ([]uint64, error) {
+	nb := (n*w + 7) / 8
+	if n < 0 || w > 64 || nb > len(d.b) {
+		return nil, errBadORC
+	}
+	p := d.b[:nb]
+	d.b = d.b[nb:]
+	vals := make([]uint64, n)
+	var acc uint64 // unread bits of the current byte, in the low nacc bits
+	var nacc int
+	for i := range vals {
+		var v uint64
+		for need := w; need > 0; {
+			if nacc == 0 {
+				acc, nacc = uint64(p[0]), 8
+				p = p[1:]
+			}
+			take := need
+			if take > nacc {
+				take = nacc
+			}
+			v = v<<take | acc>>(nacc-take)&(1<<take-1)
+			nacc -= take
+			need -= take
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func (d *orcIntDecoder) runV1(out []int64) ([]int64, error) {
+	h, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	if h < 128 {
+		delta, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		base, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(h)+3; i++ {
+			out = append(out, base+int64(i)*int64(int8(delta)))
+		}
+		return out, nil
+	}
+	for i := 0; i < 256-int(h); i++ {
+		v, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (d *orcIntDecoder) runV2(out []int64) ([]int64, error) {
+	h, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch h >> 6 {
+	case 0: // short repeat
+		u, err := d.bigEndian(int(h>>3&7) + 1)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(h&7)+3; i++ {
+			out = append(out, d.value(u))
+		}
+		return out, nil
+	case 1: // direct
+		n, err := d.runLength(h)
+		if err != nil {
+			return nil, err
+		}
+		vals, err := d.unpack(n, orcWidth(h>>1&0x1f))
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range vals {
+			out = append(out, d.value(u))
+		}
+		return out, nil
+	case 2: // patched base
+		return d.patchedBase(h, out)
+	}
+	return d.delta(h, out)
+}
+
+// runLength reads the 9-bit length of a run, whose header byte is h.
+func (d *orcIntDecoder) runLength(h byte) (int, error) {
+	c, err := d.byte()
+	return int(h&1)<<8 | int(c) + 1, err
+}
+
+func (d *orcIntDecoder) patchedBase(h byte, out []int64) ([]int64, error) {
+	w := orcWidth(h >> 1 & 0x1f)
+	n, err := d.runLength(h)
+	if err != nil {
+		return nil, err
+	}
+	b3, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	b4, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	bw := int(b3>>5) + 1
+	pw := orcWidth(b3 & 0x1f)
+	pgw := int(b4>>5) + 1
+	pl := int(b4 & 0x1f)
+	u, err := d.bigEndian(bw)
+	if err != nil {
+		return nil, err
+	}
+	// The base is in sign-magnitude form.
+	base := int64(u)
+	if sign := uint64(1) << (bw*8 - 1); u&sign != 0 {
+		base = -int64(u &^ sign)
+	}
+	vals, err := d.unpack(n, w)
+	if err != nil {
+		return nil, err
+	}
+	pbits := orcFixedBits(pw + pgw)
+	if pbits > 64 || pw+w > 64 {
+		return nil, errBadORC
+	}
+	patches, err := d.unpack(pl, pbits)
+	if err != nil {
+		return nil, err
+	}
+	// Each patch holds the gap from the previous patched
+	// position and the high bits of the value there. Gaps
+	// over 255 take several patches, all but the last empty.
+	pos := 0
+	for _, p := range patches {
+		gap, patch := int(p>>pw), p&(1<<pw-1)
+		pos += gap
+		if gap == 255 && patch == 0 {
+			continue
+		}
+		if pos >= n {
+			return nil, errBadORC
+		}
+		vals[pos] |= patch << w
+	}
+	for _, v := range vals {
+		out = append(out, base+int64(v))
+	}
+	return out, nil
+}
+
+func (d *orcIntDecoder) delta(h byte, out []int64) ([]int64, error) {
+	w := int(h >> 1 & 0x1f)
+	if w != 0 {
+		w = orcWidth(byte(w))
+	}
+	n, err := d.runLength(h)
+	if err != nil {
+		return nil, err
+	}
+	v, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	delta, err := d.svarint()
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, v)
+	if w == 0 { // a fixed delta
+		for i := 1; i < n; i++ {
+			v += delta
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	if n < 2 {
+		return nil, errBadORC
+	}
+	v += delta
+	out = append(out, v)
+	// The rest of the deltas have the sign of the first.
+	deltas, err := d.unpack(n-2, w)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range deltas {
+		if delta < 0 {
+			v -= int64(u)
+		} else {
+			v += int64(u)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// orcWidth decodes the 5-bit encoded bit width of RLEv2.
+func orcWidth(code byte) int {
+	switch {
+	case code < 24:
+		return int(code) + 1
+	case code < 28:
+		return 26 + int(code-24)*2
+	}
+	return 40 + int(code-28)*8
+}
+
+// orcFixedBits returns the least bit width that RLEv2
+// can encode that holds n bits.
+func orcFixedBits(n int) int {
+	switch {
+	case n == 0:
+		return 1
+	case n <= 24:
+		return n
+	case n <= 32:
+		return n + n&1
+	}
+	return (n + 7) / 8 * 8
+}
+
+func unzigzag(u uint64) int64 {
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// A pbField is a field of a protocol buffer message.
+type pbField struct {
+	num  int
+	wire int
+	v    uint64 // of a varint or fixed-size field
+	b    []byte // of a length-delimited field
+}
+
+// uints returns the values of a repeated integer field,
+// which may be packed.
+func (f pbField) uints() []uint64 {
+	if f.wire != 2 {
+		return []uint64{f.v}
+	}
+	var vs []uint64
+	for b := f.b; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			break
+		}
+		vs = append(vs, v)
+		b = b[n:]
+	}
+	return vs
+}
+
+// pbFields parses the protocol buffer message in b.
+func pbFields(b []byte) ([]pbField, error) {
+	var fs []pbField
+	for len(b) > 0 {
+		k, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errBadORC
+		}
+		b = b[n:]
+		f := pbField{num: int(k >> 3), wire: int(k & 7)}
+		switch f.wire {
+		case 0:
+			if f.v, n = binary.Uvarint(b); n <= 0 {
+				return nil, errBadORC
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return nil, errBadORC
+			}
+			f.v = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return nil, errBadORC
+			}
+			f.b = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return nil, errBadORC
+			}
+			f.v = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			return nil, errBadORC
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
@@ -0,0 +1,296 @@
+package s3util
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// The examples of the ORC specification.
+var orcIntTests = []struct {
+	b      []byte
+	v2     bool
+	signed bool
+	want   []int64
+}{
+	{[]byte{0x61, 0x00, 0x07}, false, false, repeatInt(7, 100)},
+	{[]byte{0xfb, 0x02, 0x03, 0x06, 0x07, 0x0b}, false, false, []int64{2, 3, 6, 7, 11}},
+	{[]byte{0x0a, 0x27, 0x10}, true, false, repeatInt(10000, 5)},
+	{[]byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef}, true, false, []int64{23713, 43806, 57005, 48879}},
+	{
+		[]byte{
+			0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46,
+			0x50, 0x5a, 0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8,
+		},
+		true, false,
+		[]int64{
+			2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090,
+			2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190,
+		},
+	},
+	{[]byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46}, true, false, []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+	{[]byte{0xc0, 0x04, 0x09, 0x03}, true, true, []int64{-5, -7, -9, -11, -13}}, // fixed delta
+}
+
+func repeatInt(v int64, n int) []int64 {
+	s := make([]int64, n)
+	for i := range s {
+		s[i] = v
+	}
+	return s
+}
+
+func TestORCInts(t *testing.T) {
+	for _, test := range orcIntTests {
+		got, err := orcInts(test.b, len(test.want), test.signed, test.v2)
+		if err != nil {
+			t.Errorf("orcInts(% x) err = %v", test.b, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("orcInts(% x) = %v want %v", test.b, got, test.want)
+		}
+	}
+	if _, err := orcInts([]byte{0x5e, 0x03, 0x5c}, 4, false, true); err == nil {
+		t.Error("expected error for short data")
+	}
+}
+
+func TestORCBytes(t *testing.T) {
+	got, err := orcBytes([]byte{0x61, 0x00, 0xfe, 0x44, 0x45}, 102)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	want := append(make([]byte, 100), 0x44, 0x45)
+	if !bytes.Equal(got, want) {
+		t.Errorf("orcBytes = % x want % x", got, want)
+	}
+}
+
+func TestORCNanos(t *testing.T) {
+	for v, want := range map[int64]int64{0: 0, 5 << 3: 5, 5<<3 | 7: 500000000, 123<<3 | 2: 123000} {
+		if g := orcNanos(v); g != want {
+			t.Errorf("orcNanos(%#x) = %d want %d", v, g, want)
+		}
+	}
+}
+
+// An orcTestColumn is a column of an ORC file written by writeTestORC.
+// Its values are string, int64, bool, time.Time, or nil for null.
+type orcTestColumn struct {
+	name string
+	kind int
+	dict bool // dictionary-encode strings
+	vals []interface{}
+}
+
+// writeTestORC returns an ORC file holding the given stripes,
+// compressed with codec, which is orcNone or orcZlib.
+func writeTestORC(codec uint64, stripes ...[]orcTestColumn) []byte {
+	compress := func(b []byte) []byte {
+		if codec == orcNone {
+			return b
+		}
+		var z bytes.Buffer
+		zw, _ := flate.NewWriter(&z, flate.DefaultCompression)
+		zw.Write(b)
+		zw.Close()
+		n := z.Len() << 1
+		return append([]byte{byte(n), byte(n >> 8), byte(n >> 16)}, z.Bytes()...)
+	}
+	file := []byte("ORC")
+	var stripeInfos [][]byte
+	var nrows int
+	for _, cols := range stripes {
+		offset := len(file)
+		var streams, encs [][]byte
+		encs = append(encs, pbUint(nil, 1, orcDirect))
+		stream := func(col, kind int, b []byte) {
+			b = compress(b)
+			file = append(file, b...)
+			var s []byte
+			s = pbUint(s, 1, uint64(kind))
+			s = pbUint(s, 2, uint64(col))
+			s = pbUint(s, 3, uint64(len(b)))
+			streams = append(streams, s)
+		}
+		rows := len(cols[0].vals)
+		for i, c := range cols {
+			id := i + 1
+			var present []bool
+			var vals []interface{}
+			for _, v := range c.vals {
+				present = append(present, v != nil)
+				if v != nil {
+					vals = append(vals, v)
+				}
+			}
+			if len(vals) < len(c.vals) {
+				stream(id, orcPresent, testORCBools(present))
+			}
+			enc := pbUint(nil, 1, orcDirectV2)
+			switch c.kind {
+			case orcString:
+				var lens []uint64
+				var data []byte
+				if c.dict {
+					var dict []string
+					seen := map[string]bool{}
+					for _, v := range vals {
+						if s := v.(string); !seen[s] {
+							seen[s] = true
+							dict = append(dict, s)
+						}
+					}
+					sort.Strings(dict)
+					var idx []uint64
+					for _, v := range vals {
+						idx = append(idx, uint64(sort.SearchStrings(dict, v.(string))))
+					}
+					for _, s := range dict {
+						lens = append(lens, uint64(len(s)))
+						data = append(data, s...)
+					}
+					stream(id, orcData, testORCInts(idx))
+					stream(id, orcDictionaryData, data)
+					enc = pbUint(pbUint(nil, 1, orcDictionaryV2), 2, uint64(len(dict)))
+				} else {
+					for _, v := range vals {
+						lens = append(lens, uint64(len(v.(string))))
+						data = append(data, v.(string)...)
+					}
+					stream(id, orcData, data)
+				}
+				stream(id, orcLength, testORCInts(lens))
+			case orcLong:
+				var u []uint64
+				for _, v := range vals {
+					u = append(u, zigzag(v.(int64)))
+				}
+				stream(id, orcData, testORCInts(u))
+			case orcBoolean:
+				var bs []bool
+				for _, v := range vals {
+					bs = append(bs, v.(bool))
+				}
+				stream(id, orcData, testORCBools(bs))
+				enc = pbUint(nil, 1, orcDirect)
+			case orcTimestamp:
+				var secs, nanos []uint64
+				for _, v := range vals {
+					tm := v.(time.Time)
+					secs = append(secs, zigzag(tm.Unix()-orcEpoch))
+					nanos = append(nanos, uint64(tm.Nanosecond())<<3)
+				}
+				stream(id, orcData, testORCInts(secs))
+				stream(id, orcSecondary, testORCInts(nanos))
+			}
+			encs = append(encs, enc)
+		}
+		var sf []byte
+		for _, s := range streams {
+			sf = pbBytes(sf, 1, s)
+		}
+		for _, e := range encs {
+			sf = pbBytes(sf, 2, e)
+		}
+		sf = pbBytes(sf, 3, []byte("UTC"))
+		dataLen := len(file) - offset
+		sf = compress(sf)
+		file = append(file, sf...)
+		var si []byte
+		si = pbUint(si, 1, uint64(offset))
+		si = pbUint(si, 2, 0)
+		si = pbUint(si, 3, uint64(dataLen))
+		si = pbUint(si, 4, uint64(len(sf)))
+		si = pbUint(si, 5, uint64(rows))
+		stripeInfos = append(stripeInfos, si)
+		nrows += rows
+	}
+
+	var footer, root []byte
+	root = pbUint(root, 1, orcStruct)
+	for i, c := range stripes[0] {
+		root = pbUint(root, 2, uint64(i+1))
+		root = pbBytes(root, 3, []byte(c.name))
+	}
+	footer = pbUint(footer, 1, 3)
+	footer = pbUint(footer, 2, uint64(len(file)-3))
+	for _, si := range stripeInfos {
+		footer = pbBytes(footer, 3, si)
+	}
+	footer = pbBytes(footer, 4, root)
+	for _, c := range stripes[0] {
+		footer = pbBytes(footer, 4, pbUint(nil, 1, uint64(c.kind)))
+	}
+	footer = pbUint(footer, 6, uint64(nrows))
+	footer = compress(footer)
+	file = append(file, footer...)
+
+	var ps []byte
+	ps = pbUint(ps, 1, uint64(len(footer)))
+	ps = pbUint(ps, 2, codec)
+	ps = pbUint(ps, 3, 256<<10)
+	ps = pbBytes(ps, 4, []byte{0, 12})
+	ps = pbUint(ps, 5, 0)
+	ps = pbBytes(ps, 8000, []byte("ORC"))
+	file = append(file, ps...)
+	return append(file, byte(len(ps)))
+}
+
+func pbUint(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+func pbBytes(b []byte, num int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// testORCInts encodes vs with RLEv2 direct runs of 64-bit values.
+func testORCInts(vs []uint64) []byte {
+	var b []byte
+	for len(vs) > 0 {
+		n := len(vs)
+		if n > 512 {
+			n = 512
+		}
+		b = append(b, 1<<6|31<<1|byte((n-1)>>8), byte(n-1))
+		for _, v := range vs[:n] {
+			b = binary.BigEndian.AppendUint64(b, v)
+		}
+		vs = vs[n:]
+	}
+	return b
+}
+
+// testORCBools encodes bs as bits in byte RLE literal runs.
+func testORCBools(bs []bool) []byte {
+	packed := make([]byte, (len(bs)+7)/8)
+	for i, v := range bs {
+		if v {
+			packed[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	var b []byte
+	for len(packed) > 0 {
+		n := len(packed)
+		if n > 128 {
+			n = 128
+		}
+		b = append(b, byte(256-n))
+		b = append(b, packed[:n]...)
+		packed = packed[n:]
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package s3util
+
+import (
+	"sync"
+)
+
+// A BufferPool holds part buffers released by finished uploads,
+// so that later uploads can reuse them instead of allocating
+// new ones. A BufferPool may be shared by several Configs and is
+// safe for concurrent use. The zero value is an empty pool with
+// no limit on its size.
+type BufferPool struct {
+	// MaxBytes caps the total size of the buffers held by the pool.
+	// Buffers released while the pool is full are left to the
+	// garbage collector. If zero, there is no limit.
+	MaxBytes int64
+
+	mu   sync.Mutex
+	size int64
+	free [][]byte
+}
+
+// get returns a buffer of length n, reusing the smallest
+// pooled buffer with enough capacity, if there is one.
+func (p *BufferPool) get(n int64) []byte {
+	if p != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		j := -1
+		for i, b := range p.free {
+			if int64(cap(b)) >= n && (j < 0 || cap(b) < cap(p.free[j])) {
+				j = i
+			}
+		}
+		if j >= 0 {
+			b := p.free[j]
+			last := len(p.free) - 1
+			p.free[j] = p.free[last]
+			p.free[last] = nil
+			p.free = p.free[:last]
+			p.size -= int64(cap(b))
+			return b[:n]
+		}
+	}
+	return make([]byte, int(n))
+}
+
+// put returns b to the pool, if there is room for it.
+func (p *BufferPool) put(b []byte) {
+	if p == nil || cap(b) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.MaxBytes > 0 && p.size+int64(cap(b)) > p.MaxBytes {
+		return
+	}
+	p.free = append(p.free, b[:0])
+	p.size += int64(cap(b))
+}
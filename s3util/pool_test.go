@@ -0,0 +1,37 @@
+package s3util
+
+import (
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	p := &BufferPool{MaxBytes: 30}
+	p.put(make([]byte, 10))
+	p.put(make([]byte, 20))
+	p.put(make([]byte, 5)) // over MaxBytes; dropped
+	if p.size != 30 || len(p.free) != 2 {
+		t.Fatalf("pool holds %d buffers, %d bytes want 2, 30", len(p.free), p.size)
+	}
+
+	b := p.get(8)
+	if len(b) != 8 || cap(b) != 10 {
+		t.Errorf("get(8) len %d cap %d want 8, 10", len(b), cap(b))
+	}
+	b = p.get(15)
+	if len(b) != 15 || cap(b) != 20 {
+		t.Errorf("get(15) len %d cap %d want 15, 20", len(b), cap(b))
+	}
+	b = p.get(15)
+	if len(b) != 15 || cap(b) != 15 {
+		t.Errorf("get(15) from empty pool len %d cap %d want 15, 15", len(b), cap(b))
+	}
+	if p.size != 0 {
+		t.Errorf("size = %d want 0", p.size)
+	}
+
+	var nilPool *BufferPool
+	if b := nilPool.get(4); len(b) != 4 {
+		t.Errorf("nil get(4) len %d want 4", len(b))
+	}
+	nilPool.put(b)
+}
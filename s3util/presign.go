@@ -0,0 +1,41 @@
+package s3util
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PresignDownload returns a presigned URL that lets anyone download
+// the S3 object at url for the given duration. The response to the
+// URL tells browsers to save the object as filename, rather than
+// display it, and, if contentType is not empty, gives that as its
+// Content-Type, overriding the object's metadata.
+// Non-ASCII file names are sent as described in RFC 6266.
+//
+// If c is nil, PresignDownload uses DefaultConfig.
+func PresignDownload(rawurl, filename, contentType string, expiry time.Duration, c *Config) (string, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if filename != "" {
+		q.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	if contentType != "" {
+		q.Set("response-content-type", contentType)
+	}
+	u.RawQuery = q.Encode()
+	r, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	c.scopeURL(r.URL)
+	c.Presign(r, *c.Keys, time.Now().Add(expiry))
+	return r.URL.String(), nil
+}
@@ -0,0 +1,137 @@
+package s3util
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/kr/s3"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProfileFile is the file read by Config.Profile.
+// If empty, Profile uses $S3_CONFIG, or .s3config in the
+// user's home directory if that is unset.
+var ProfileFile string
+
+// Profile returns a copy of c with its Service and Keys replaced by
+// those of the named profile. Profiles are read from ProfileFile,
+// which uses the same syntax as git-config(1):
+//
+//   [profile "dev"]
+//     domain    = minio.example.com
+//     bucket    = identity
+//     accesskey = ...
+//     secretkey = ...
+//
+// The recognized variables are domain, bucket ("amazon" or
+// "identity"), accesskey, secretkey, and securitytoken.
+// Variables missing from the profile keep their values from c.
+func (c *Config) Profile(name string) (*Config, error) {
+	file := profileFile()
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	profiles, err := readProfiles(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: no profile %q", file, name)
+	}
+	return c.withProfile(p)
+}
+
+func (c *Config) withProfile(p map[string]string) (*Config, error) {
+	nc := *c
+	nc.Service = new(s3.Service)
+	if c.Service != nil {
+		*nc.Service = *c.Service
+	}
+	nc.Keys = new(s3.Keys)
+	if c.Keys != nil {
+		*nc.Keys = *c.Keys
+	}
+	for k, v := range p {
+		switch k {
+		case "domain":
+			nc.Domain = v
+		case "bucket":
+			switch v {
+			case "amazon":
+				nc.Bucket = s3.AmazonBucket
+			case "identity":
+				nc.Bucket = s3.IdentityBucket
+			default:
+				return nil, fmt.Errorf("unknown bucket style %q", v)
+			}
+		case "accesskey":
+			nc.AccessKey = v
+		case "secretkey":
+			nc.SecretKey = v
+		case "securitytoken":
+			nc.SecurityToken = v
+		default:
+			return nil, fmt.Errorf("unknown variable %q", k)
+		}
+	}
+	return &nc, nil
+}
+
+func profileFile() string {
+	if ProfileFile != "" {
+		return ProfileFile
+	}
+	if s := os.Getenv("S3_CONFIG"); s != "" {
+		return s
+	}
+	home, _ := os.UserHomeDir() // $HOME, or %USERPROFILE% on Windows
+	return filepath.Join(home, ".s3config")
+}
+
+// readProfiles parses the [profile "name"] sections of a
+// git-config style file. Variable names are case-insensitive;
+// other sections are ignored.
+func readProfiles(r io.Reader) (map[string]map[string]string, error) {
+	profiles := make(map[string]map[string]string)
+	var cur map[string]string
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: bad section header", n)
+			}
+			sect := strings.TrimSpace(line[1 : len(line)-1])
+			cur = nil
+			if strings.HasPrefix(sect, "profile ") {
+				name := strings.TrimSpace(sect[len("profile "):])
+				if len(name) < 2 || name[0] != '"' || name[len(name)-1] != '"' {
+					return nil, fmt.Errorf("line %d: profile name must be quoted", n)
+				}
+				name = name[1 : len(name)-1]
+				if profiles[name] == nil {
+					profiles[name] = make(map[string]string)
+				}
+				cur = profiles[name]
+			}
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: missing '='", n)
+		}
+		if cur != nil {
+			k := strings.ToLower(strings.TrimSpace(line[:i]))
+			cur[k] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return profiles, sc.Err()
+}
@@ -0,0 +1,59 @@
+package s3util
+
+import (
+	"github.com/kr/s3"
+	"strings"
+	"testing"
+)
+
+const testProfiles = `
+# comment
+[core]
+	editor = vi
+[profile "prod"]
+	accesskey = AKPROD
+	secretkey = secretprod
+[profile "dev"]
+	Domain = minio.example.com
+	bucket = identity
+	accesskey = AKDEV
+	secretkey = secret=dev
+`
+
+func TestReadProfiles(t *testing.T) {
+	p, err := readProfiles(strings.NewReader(testProfiles))
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if len(p) != 2 {
+		t.Fatalf("len(p) = %d want 2", len(p))
+	}
+	c, err := DefaultConfig.withProfile(p["dev"])
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if c.Domain != "minio.example.com" {
+		t.Errorf("Domain = %q want minio.example.com", c.Domain)
+	}
+	if got := c.Bucket("mybucket"); got != "mybucket" {
+		t.Errorf("Bucket = %q want mybucket", got)
+	}
+	if c.AccessKey != "AKDEV" || c.SecretKey != "secret=dev" {
+		t.Errorf("Keys = %+v", *c.Keys)
+	}
+	if DefaultConfig.Service != s3.DefaultService || s3.DefaultService.Domain != "amazonaws.com" {
+		t.Errorf("DefaultConfig was modified")
+	}
+}
+
+func TestReadProfilesErrors(t *testing.T) {
+	for _, s := range []string{
+		"[profile dev]\n",
+		"[profile \"dev\"\n",
+		"[profile \"dev\"]\naccesskey\n",
+	} {
+		if _, err := readProfiles(strings.NewReader(s)); err == nil {
+			t.Errorf("readProfiles(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package s3util
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Put creates an S3 object at url with a single PUT request,
+// reading its contents from r, and returns the new object's
+// ETag and version ID. It is best suited to small objects;
+// use Create for large or streaming data.
+//
+// S3 needs to know the object's length in advance. If r is a
+// *bytes.Buffer, *bytes.Reader, or *strings.Reader, its length is
+// used; otherwise Put reads all of r into memory first.
+//
+// If h is not nil, each of its entries is added to the HTTP request header,
+// along with any defaults in c.Header.
+// If c is nil, Put uses DefaultConfig.
+//
+// To create the object only if it does not already exist, set
+// If-None-Match to "*" in h. If the object exists, Put returns
+// ErrPreconditionFailed.
+func Put(url string, r io.Reader, h http.Header, c *Config) (*Result, error) {
+	return PutWithOptions(url, r, &ObjectOptions{Extra: h}, c)
+}
+
+// PutWithOptions is like Put, but takes the settings of the new
+// object as typed options.
+func PutWithOptions(url string, r io.Reader, o *ObjectOptions, c *Config) (*Result, error) {
+	h, err := o.Header()
+	if err != nil {
+		return nil, err
+	}
+	return put(url, r, h, c)
+}
+
+func put(url string, r io.Reader, h http.Header, c *Config) (*Result, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	req, err := http.NewRequest("PUT", url, r)
+	if err != nil {
+		return nil, err
+	}
+	if req.ContentLength == 0 && req.Body != nil && req.Body != http.NoBody {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		req.ContentLength = int64(len(b))
+		req.GetBody = nil
+	}
+	for k, vs := range c.objectHeader(h) {
+		req.Header[k] = vs
+	}
+	if c.DetectContentType && req.Header.Get("Content-Type") == "" {
+		t := typeByExtension(url)
+		if t == "" {
+			t, req.Body, err = sniff(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			req.GetBody = nil
+		}
+		req.Header.Set("Content-Type", t)
+	}
+	if c.ChecksumTrailer != "" && req.ContentLength > 0 {
+		if err := setChunkedBody(req, c.ChecksumTrailer); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 412 {
+		closeBody(resp.Body)
+		return nil, ErrPreconditionFailed
+	}
+	if resp.StatusCode != 200 {
+		return nil, newRespError(resp)
+	}
+	closeBody(resp.Body)
+	return &Result{
+		ETag:      etagValue(resp.Header.Get("Etag")),
+		VersionId: resp.Header.Get("X-Amz-Version-Id"),
+	}, nil
+}
+
+// sniff detects the content type of the data in rc.
+// It returns a ReadCloser that yields all of the original data.
+func sniff(rc io.ReadCloser) (string, io.ReadCloser, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(rc, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	buf = buf[:n]
+	r := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), rc), rc}
+	return http.DetectContentType(buf), r, nil
+}
+
+// PutIfMatch replaces the S3 object at url with the contents of r,
+// but only if the object's current ETag is etag. It returns the
+// new object's ETag and version ID. If the object has
+// changed, PutIfMatch returns ErrPreconditionFailed. This gives
+// optimistic concurrency control for small objects: read an object
+// and its ETag, modify it, and write it back with PutIfMatch,
+// retrying from the read if another writer got there first.
+//
+// The etag may be given with or without its surrounding double quotes.
+// If c is nil, PutIfMatch uses DefaultConfig.
+func PutIfMatch(url, etag string, r io.Reader, c *Config) (*Result, error) {
+	if !strings.HasPrefix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	return Put(url, r, http.Header{"If-Match": {etag}}, c)
+}
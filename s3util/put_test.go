@@ -0,0 +1,168 @@
+package s3util
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var contentTypeTests = []struct {
+	url  string
+	h    http.Header
+	data string
+	w    string
+}{
+	{"https://s3.amazonaws.com/foo/a.html", nil, "hello", "text/html; charset=utf-8"},
+	{"https://s3.amazonaws.com/foo/a", nil, "<html><body>hi", "text/html; charset=utf-8"},
+	{"https://s3.amazonaws.com/foo/a", nil, "\x89PNG\x0d\x0a\x1a\x0a", "image/png"},
+	{"https://s3.amazonaws.com/foo/a.png", http.Header{"Content-Type": {"x/y"}}, "", "x/y"},
+}
+
+func TestPutDetectContentType(t *testing.T) {
+	for _, test := range contentTypeTests {
+		var gotType, gotBody string
+		c := *DefaultConfig
+		c.DetectContentType = true
+		c.Client = &http.Client{
+			Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				gotType = req.Header.Get("Content-Type")
+				b, _ := ioutil.ReadAll(req.Body)
+				gotBody = string(b)
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}, nil
+			}),
+		}
+		// Hide the concrete type of the reader, to exercise sniff.
+		r := struct{ io.Reader }{strings.NewReader(test.data)}
+		if _, err := Put(test.url, r, test.h, &c); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if gotType != test.w {
+			t.Errorf("%s: Content-Type = %q want %q", test.url, gotType, test.w)
+		}
+		if gotBody != test.data {
+			t.Errorf("%s: body = %q want %q", test.url, gotBody, test.data)
+		}
+	}
+}
+
+func TestCreateDetectContentType(t *testing.T) {
+	for _, test := range contentTypeTests {
+		var gotType string
+		c := *DefaultConfig
+		c.DetectContentType = true
+		c.Client = &http.Client{
+			Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				var s string
+				if req.Method == "POST" && req.URL.Query()["uploads"] != nil {
+					gotType = req.Header.Get("Content-Type")
+					s = `<UploadId>foo</UploadId>`
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(strings.NewReader(s)),
+					Header:     http.Header{"Etag": {`"foo"`}},
+				}, nil
+			}),
+		}
+		w, err := Create(test.url, test.h, &c)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if _, err := io.WriteString(w, test.data); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if gotType != test.w {
+			t.Errorf("%s: Content-Type = %q want %q", test.url, gotType, test.w)
+		}
+	}
+}
+
+func TestObjectHeader(t *testing.T) {
+	c := *DefaultConfig
+	c.Header = http.Header{
+		"Cache-Control":       {"max-age=3600"},
+		"content-disposition": {"inline"},
+	}
+	h := c.objectHeader(http.Header{
+		"Cache-Control": {"no-cache"},
+		"Expires":       {"Thu, 01 Dec 1994 16:00:00 GMT"},
+	})
+	w := http.Header{
+		"Cache-Control":       {"no-cache"},
+		"Content-Disposition": {"inline"},
+		"Expires":             {"Thu, 01 Dec 1994 16:00:00 GMT"},
+	}
+	if !reflect.DeepEqual(h, w) {
+		t.Errorf("header = %v want %v", h, w)
+	}
+}
+
+func TestPutIfNoneMatch(t *testing.T) {
+	exists := false
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			status := 200
+			if req.Header.Get("If-None-Match") == "*" && exists {
+				status = 412
+			}
+			exists = true
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	h := http.Header{"If-None-Match": {"*"}}
+	if _, err := Put("https://s3.amazonaws.com/foo/lock", strings.NewReader("a"), h, &c); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	_, err := Put("https://s3.amazonaws.com/foo/lock", strings.NewReader("b"), h, &c)
+	if err != ErrPreconditionFailed {
+		t.Fatalf("err = %v want %v", err, ErrPreconditionFailed)
+	}
+}
+
+func TestPutIfMatch(t *testing.T) {
+	etag := `"v1"`
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			status := 200
+			if req.Header.Get("If-Match") != etag {
+				status = 412
+			} else {
+				etag = `"v2"`
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+				Header: http.Header{
+					"Etag":             {etag},
+					"X-Amz-Version-Id": {"3HL4kqtJlcpXroDTDmJ"},
+				},
+			}, nil
+		}),
+	}
+	const url = "https://s3.amazonaws.com/foo/state.json"
+	res, err := PutIfMatch(url, "v1", strings.NewReader("{}"), &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if w := (Result{ETag: "v2", VersionId: "3HL4kqtJlcpXroDTDmJ"}); !reflect.DeepEqual(*res, w) {
+		t.Errorf("result = %+v want %+v", *res, w)
+	}
+	_, err = PutIfMatch(url, "v1", strings.NewReader("{}"), &c)
+	if err != ErrPreconditionFailed {
+		t.Fatalf("err = %v want %v", err, ErrPreconditionFailed)
+	}
+}
@@ -0,0 +1,52 @@
+package s3util
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A RateLimit spaces requests evenly so that no more than
+// PerSecond are sent each second. A RateLimit may be shared by
+// several Configs and is safe for concurrent use.
+type RateLimit struct {
+	PerSecond float64 // if not positive, there is no limit
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until l allows another request.
+func (l *RateLimit) wait() {
+	if l == nil || l.PerSecond <= 0 {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	t := l.next
+	l.next = l.next.Add(time.Duration(float64(time.Second) / l.PerSecond))
+	l.mu.Unlock()
+	time.Sleep(t.Sub(now))
+}
+
+// S3 answers 503 Slow Down when it is throttling requests.
+// Listings retry such responses after a backoff that starts
+// at slowDownBase and doubles, up to slowDownTries attempts.
+var (
+	slowDownBase  = 100 * time.Millisecond
+	slowDownTries = 8
+)
+
+// slowDownDelay returns how long to wait before attempt try,
+// counted from zero, after a 503 response: an exponentially
+// growing interval with random jitter.
+func slowDownDelay(try int) time.Duration {
+	d := slowDownBase << uint(try)
+	if d > 20*time.Second || d <= 0 {
+		d = 20 * time.Second
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,49 @@
+package s3util
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListSlowDown(t *testing.T) {
+	defer func(d time.Duration) { slowDownBase = d }(slowDownBase)
+	slowDownBase = time.Millisecond
+	n := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n <= 3 {
+			w.WriteHeader(503)
+			io.WriteString(w, "<Error><Code>SlowDown</Code></Error>")
+			return
+		}
+		io.WriteString(w, "<ListBucketResult><Contents><Key>a</Key></Contents></ListBucketResult>")
+	}))
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fis, err := f.Readdir(0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if len(fis) != 1 || n != 4 {
+		t.Errorf("got %d entries after %d requests, want 1 after 4", len(fis), n)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	l := &RateLimit{PerSecond: 100}
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		l.wait()
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("6 requests at 100/s took %v, want at least 50ms", d)
+	}
+	var nilLimit *RateLimit
+	nilLimit.wait()
+}
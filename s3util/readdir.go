@@ -2,9 +2,11 @@ package s3util
 
 import (
 	"bytes"
-	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"iter"
 	"net/http"
 	"net/url"
 	"os"
@@ -13,12 +15,35 @@ import (
 	"time"
 )
 
+// ErrTooManyEntries is returned by Readdir(0) when a listing
+// has more entries than the File's MaxEntries.
+var ErrTooManyEntries = errors.New("s3util: too many directory entries")
+
 // File represents an S3 object or directory.
 type File struct {
+	// MaxEntries, if positive, caps the number of entries that
+	// Readdir(0) collects. If the listing has more, Readdir
+	// returns the first MaxEntries and ErrTooManyEntries, and a
+	// later call continues where it stopped.
+	MaxEntries int
+
+	// DirSizes makes Readdir count the objects beneath each
+	// directory it returns, and their total size, by listing the
+	// directory's contents, a few directories at a time. The
+	// directory's FileInfo reports the total in Size, and its Sys
+	// method returns a *DirStat. This costs at least one request
+	// per directory.
+	DirSizes bool
+
+	// Filter, if not nil, selects which objects are listed.
+	Filter *Filter
+
 	url    string
 	prefix string
 	config *Config
 	result *listObjectsResult
+	marker string // key after which the next page starts
+	delim  bool   // of the last request
 }
 
 type fileInfo struct {
@@ -27,6 +52,7 @@ type fileInfo struct {
 	dir     bool
 	modTime time.Time
 	sys     *Stat
+	dirStat *DirStat // set by DirSizes
 }
 
 // Stat contains information about an S3 object or directory.
@@ -41,14 +67,21 @@ type Stat struct {
 	ETag         string // ETag value, without double quotes.
 	Size         string
 	StorageClass string
-	OwnerID      string `xml:"Owner>ID"`
-	OwnerName    string `xml:"Owner>DisplayName"`
+	Owner        Owner
 }
 
+// Owner identifies the account that owns an S3 object,
+// as reported in listings.
+type Owner struct {
+	ID          string
+	DisplayName string
+}
+
+// listObjectsResult holds the fields of a ListObjects response
+// other than its entries, which decodeList passes on one by one.
 type listObjectsResult struct {
 	IsTruncated bool
-	Contents    []Stat
-	Directories []string `xml:"CommonPrefixes>Prefix"` // Suffix "/" trimmed
+	NextMarker  string
 }
 
 func (f *fileInfo) Name() string { return f.name }
@@ -67,7 +100,12 @@ func (f *fileInfo) ModTime() time.Time {
 	return f.modTime
 }
 func (f *fileInfo) IsDir() bool      { return f.dir }
-func (f *fileInfo) Sys() interface{} { return f.sys }
+func (f *fileInfo) Sys() interface{} {
+	if f.dirStat != nil {
+		return f.dirStat
+	}
+	return f.sys
+}
 
 // NewFile returns a new File with the given URL and config.
 //
@@ -92,7 +130,7 @@ func NewFile(rawurl string, c *Config) (*File, error) {
 		prefix += "/"
 	}
 	u.Path = ""
-	return &File{u.String(), prefix, c, nil}, nil
+	return &File{url: u.String(), prefix: prefix, config: c}, nil
 }
 
 // Readdir requests a list of entries in the S3 directory
@@ -100,109 +138,272 @@ func NewFile(rawurl string, c *Config) (*File, error) {
 // values, in alphabetical order. Subsequent calls
 // on the same File will yield further FileInfos.
 // Only direct children are returned, not deeper descendants.
+//
+// If n > 0, Readdir makes one request, asking S3 for at most n
+// entries; S3 returns no more than 1000 at a time. At the end of
+// the listing it returns an empty slice and io.EOF.
+//
+// If n <= 0, Readdir requests pages until the listing is
+// complete and returns all the remaining entries, and a nil error
+// at the end of the listing; see MaxEntries.
 func (f *File) Readdir(n int) ([]os.FileInfo, error) {
-	if f.result != nil && !f.result.IsTruncated {
-		return make([]os.FileInfo, 0), io.EOF
+	return f.readdir(n, true)
+}
+
+// ReaddirRecursive is like Readdir, but it lists every object
+// under f's prefix, however deeply nested, and no directories.
+// The name of each FileInfo is the object's full key.
+//
+// ReaddirRecursive and Readdir share f's position in the listing;
+// a File should be read with one or the other, not both.
+func (f *File) ReaddirRecursive(n int) ([]os.FileInfo, error) {
+	return f.readdir(n, false)
+}
+
+// Readdirnames is like Readdir, but it returns only the names
+// of the entries.
+func (f *File) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, fi := range infos {
+		names[i] = fi.Name()
+	}
+	return names, err
+}
+
+// ReadDir is like Readdir, but it returns fs.DirEntry values,
+// as os.File.ReadDir does. The entries' names are keys, as in
+// Readdir. The listing already holds everything Info reports,
+// so Info makes no further requests.
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	infos, err := f.Readdir(n)
+	entries := make([]fs.DirEntry, len(infos))
+	for i, fi := range infos {
+		entries[i] = fs.FileInfoToDirEntry(fi)
 	}
+	return entries, err
+}
+
+func (f *File) readdir(n int, delim bool) ([]os.FileInfo, error) {
+	if n <= 0 {
+		return f.readdirAll(delim)
+	}
+	for f.result == nil || f.result.IsTruncated {
+		// A filtered page may be empty.
+		infos, err := f.readPage(n, delim)
+		if len(infos) > 0 || err != nil {
+			return infos, err
+		}
+	}
+	return make([]os.FileInfo, 0), io.EOF
+}
+
+// All returns an iterator over the entries of f, from its
+// current position to the end of the listing, as Readdir would
+// return them. Iteration stops after the first error.
+//
+// Unless f.DirSizes is set, each object is yielded as soon as it
+// has been decoded from the response, without waiting for the
+// rest of its page. If the loop stops early, f's position is at
+// the end of the page that was being read.
+func (f *File) All() iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		for f.result == nil || f.result.IsTruncated {
+			if !f.DirSizes {
+				stopped, err := f.streamPage(func(fi os.FileInfo) bool {
+					return yield(fi, nil)
+				})
+				if stopped {
+					return
+				}
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				continue
+			}
+			infos, err := f.readPage(0, true)
+			for _, fi := range infos {
+				if !yield(fi, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// readdirAll reads pages of the listing until it is complete
+// or f.MaxEntries entries have been read.
+func (f *File) readdirAll(delim bool) ([]os.FileInfo, error) {
+	infos := make([]os.FileInfo, 0)
+	for f.result == nil || f.result.IsTruncated {
+		if f.MaxEntries > 0 && len(infos) >= f.MaxEntries {
+			return infos, ErrTooManyEntries
+		}
+		page, err := f.readPage(f.MaxEntries-len(infos), delim)
+		infos = append(infos, page...)
+		if err != nil {
+			return infos, err
+		}
+	}
+	return infos, nil
+}
 
-	reader, err := f.sendRequest(n)
+// readPage requests one page of up to n entries, or S3's
+// default page size if n <= 0. If delim is set, keys are
+// grouped into directories at each "/".
+func (f *File) readPage(n int, delim bool) ([]os.FileInfo, error) {
+	reader, err := f.sendRequest(n, delim)
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
+	defer closeBody(reader)
 
-	return f.parseResponse(reader)
+	infos, err := f.parseResponse(reader)
+	if err == nil && f.Filter != nil {
+		infos = f.Filter.apply(infos)
+	}
+	if err == nil && delim && f.DirSizes {
+		err = f.addDirStats(infos)
+	}
+	return infos, err
 }
 
-func (f *File) sendRequest(count int) (io.ReadCloser, error) {
+func (f *File) sendRequest(count int, delim bool) (io.ReadCloser, error) {
 	c := f.config
 	if c == nil {
 		c = DefaultConfig
 	}
+	f.delim = delim
 	var buf bytes.Buffer
 	buf.WriteString(f.url)
-	buf.WriteString("?delimiter=%2F")
-	if f.prefix != "" {
-		buf.WriteString("&prefix=")
-		buf.WriteString(url.QueryEscape(f.prefix))
+	buf.WriteString("?prefix=")
+	buf.WriteString(url.QueryEscape(c.KeyPrefix + f.prefix))
+	if delim {
+		buf.WriteString("&delimiter=%2F")
 	}
 	if count > 0 {
 		buf.WriteString("&max-keys=")
 		buf.WriteString(strconv.Itoa(count))
 	}
-	if f.result != nil && f.result.IsTruncated {
-		var lastDir, lastKey, marker string
-		if len(f.result.Directories) > 0 {
-			lastDir = f.result.Directories[len(f.result.Directories)-1]
-		}
-		if len(f.result.Contents) > 0 {
-			lastKey = f.result.Contents[len(f.result.Contents)-1].Key
+	if f.marker != "" {
+		buf.WriteString("&marker=")
+		buf.WriteString(url.QueryEscape(c.KeyPrefix + f.marker))
+	}
+	u := buf.String()
+	for try := 1; ; try++ {
+		c.ListRate.wait()
+		r, _ := http.NewRequest("GET", u, nil)
+		resp, err := c.do(r)
+		if err != nil {
+			return nil, err
 		}
-
-		if lastKey > lastDir {
-			marker = lastKey
-		} else {
-			marker = lastDir
+		if resp.StatusCode == 503 && try < slowDownTries {
+			closeBody(resp.Body)
+			time.Sleep(slowDownDelay(try - 1))
+			continue
 		}
-
-		if marker != "" {
-			buf.WriteString("&marker=")
-			buf.WriteString(url.QueryEscape(marker))
+		if resp.StatusCode != 200 {
+			return nil, newRespError(resp)
 		}
+		return resp.Body, nil
 	}
-	u := buf.String()
-	r, _ := http.NewRequest("GET", u, nil)
-	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-	c.Sign(r, *c.Keys)
-	resp, err := http.DefaultClient.Do(r)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != 200 {
-		return nil, newRespError(resp)
-	}
-	return resp.Body, nil
 }
 
 func (f *File) parseResponse(reader io.Reader) ([]os.FileInfo, error) {
-	decoder := xml.NewDecoder(reader)
-	result := listObjectsResult{}
-	var err error
-	err = decoder.Decode(&result)
+	infos := make([]os.FileInfo, 0)
+	err := f.decodeList(reader, func(fi os.FileInfo) bool {
+		infos = append(infos, fi)
+		return true
+	})
 	if err != nil {
 		return nil, err
 	}
+	return infos, nil
+}
 
-	infos := make([]os.FileInfo, len(result.Contents)+len(result.Directories))
-	var size int64
-	var name string
-	var is_dir bool
-	for i, content := range result.Contents {
-		c := content
-		c.ETag = strings.Trim(c.ETag, `"`)
-		size, _ = strconv.ParseInt(c.Size, 10, 0)
-		if size == 0 && strings.HasSuffix(c.Key, "/") {
-			name = strings.TrimRight(c.Key, "/")
-			is_dir = true
-		} else {
-			name = c.Key
-			is_dir = false
-		}
-		infos[i] = &fileInfo{
-			name: name,
-			size: size,
-			dir:  is_dir,
-			sys:  &c,
-		}
+// Rewind returns f to the start of its listing, so that the
+// next call to Readdir lists it again from the beginning.
+func (f *File) Rewind() {
+	f.SeekTo("")
+}
+
+// SeekTo moves f's position in its listing to just after the
+// key or directory marker, so that the next call to Readdir
+// continues from there. Together with Marker, it lets a long
+// listing be resumed after a restart.
+func (f *File) SeekTo(marker string) {
+	f.result = nil
+	f.marker = marker
+}
+
+// Marker returns the key or directory after which the next
+// call to Readdir will continue, or "" at the start of the listing.
+func (f *File) Marker() string {
+	return f.marker
+}
+
+// Subdir returns a File for the directory name in f's listing,
+// as reported by the Name method of its FileInfo, which is the
+// directory's full key without the trailing slash. A name not
+// beginning with f's own prefix is taken as relative to f.
+// The new File uses f's Config and has f's MaxEntries, DirSizes,
+// and Filter settings, and its listing starts at the beginning.
+func (f *File) Subdir(name string) *File {
+	name = strings.TrimSuffix(name, "/")
+	if !strings.HasPrefix(name, f.prefix) {
+		name = f.prefix + name
 	}
-	for i, dir := range result.Directories {
-		infos[len(result.Contents)+i] = &fileInfo{
-			name: strings.TrimRight(dir, "/"),
-			size: 0,
-			dir:  true,
-		}
+	return &File{
+		MaxEntries: f.MaxEntries,
+		DirSizes:   f.DirSizes,
+		Filter:     f.Filter,
+		url:        f.url,
+		prefix:     name + "/",
+		config:     f.config,
 	}
-	f.result = &result
+}
 
-	return infos, nil
+// ListState is the position of a File in its listing, which
+// a job can store and pass to Restore, perhaps in another
+// process, to continue an enormous listing after a restart.
+type ListState struct {
+	Prefix    string // of the listed directory
+	Delimiter string // "/", or "" for ReaddirRecursive
+	Marker    string // key or directory after which the next page starts
+	Done      bool   // the listing has ended
+}
+
+// State returns f's position in its listing, as of the last
+// page requested.
+func (f *File) State() ListState {
+	s := ListState{
+		Prefix: f.prefix,
+		Marker: f.marker,
+		Done:   f.result != nil && !f.result.IsTruncated,
+	}
+	if f.delim {
+		s.Delimiter = "/"
+	}
+	return s
+}
+
+// Restore moves f to the position in s, which must be the state
+// of a File for the same directory. The listing continues with
+// the next call to Readdir, if s.Delimiter is "/", or to
+// ReaddirRecursive, if it is empty.
+func (f *File) Restore(s ListState) error {
+	if s.Prefix != f.prefix {
+		return fmt.Errorf("s3util: list state for prefix %q, not %q", s.Prefix, f.prefix)
+	}
+	f.SeekTo(s.Marker)
+	f.delim = s.Delimiter != ""
+	if s.Done {
+		f.result = &listObjectsResult{}
+	}
+	return nil
 }
@@ -0,0 +1,401 @@
+package s3util
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// listServer serves ListObjects requests for keys, honoring
+// prefix, delimiter, marker, and max-keys, with pages of at
+// most pageSize entries.
+func listServer(keys []string, pageSize int) *httptest.Server {
+	sort.Strings(keys)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		prefix, delim, marker := q.Get("prefix"), q.Get("delimiter"), q.Get("marker")
+		max := pageSize
+		if s := q.Get("max-keys"); s != "" {
+			if n, _ := strconv.Atoi(s); n < max {
+				max = n
+			}
+		}
+		var b strings.Builder
+		b.WriteString("<ListBucketResult>")
+		n, truncated, lastDir := 0, false, ""
+		for _, k := range keys {
+			if !strings.HasPrefix(k, prefix) || k <= marker {
+				continue
+			}
+			dir := ""
+			if delim != "" {
+				if i := strings.Index(k[len(prefix):], delim); i >= 0 {
+					dir = k[:len(prefix)+i+1]
+				}
+			}
+			if dir != "" && (dir == lastDir || dir <= marker) {
+				continue
+			}
+			if n == max {
+				truncated = true
+				break
+			}
+			n++
+			if dir != "" {
+				lastDir = dir
+				fmt.Fprintf(&b, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", dir)
+			} else {
+				fmt.Fprintf(&b, "<Contents><Key>%s</Key><Size>%d</Size><ETag>&quot;e&quot;</ETag></Contents>", k, len(k))
+			}
+		}
+		fmt.Fprintf(&b, "<IsTruncated>%v</IsTruncated></ListBucketResult>", truncated)
+		io.WriteString(w, b.String())
+	}))
+}
+
+func names(f *File, n int) ([]string, error) {
+	fis, err := f.Readdir(n)
+	var a []string
+	for _, fi := range fis {
+		a = append(a, fi.Name())
+	}
+	return a, err
+}
+
+func TestReaddirAll(t *testing.T) {
+	keys := []string{"d/a", "d/b", "d/c", "d/e/1", "d/e/2", "d/f", "d/g", "x"}
+	ts := listServer(keys, 2)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := names(f, 0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := "d/a d/b d/c d/e d/f d/g"
+	if strings.Join(g, " ") != w {
+		t.Errorf("Readdir(0) = %v want %s", g, w)
+	}
+	g, err = names(f, 0)
+	if err != nil || len(g) != 0 {
+		t.Errorf("Readdir(0) at end = %v, %v want none, nil", g, err)
+	}
+}
+
+func TestReaddirMaxEntries(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+	ts := listServer(keys, 1000)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.MaxEntries = 3
+	g, err := names(f, 0)
+	if err != ErrTooManyEntries {
+		t.Errorf("err = %v want ErrTooManyEntries", err)
+	}
+	if strings.Join(g, " ") != "a b c" {
+		t.Errorf("Readdir(0) = %v want a b c", g)
+	}
+	g, err = names(f, 0)
+	if err != nil || strings.Join(g, " ") != "d e" {
+		t.Errorf("Readdir(0) = %v, %v want d e, nil", g, err)
+	}
+}
+
+func TestReaddirPage(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	ts := listServer(keys, 1000)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := names(f, 2)
+	if err != nil || strings.Join(g, " ") != "a b" {
+		t.Errorf("Readdir(2) = %v, %v want a b, nil", g, err)
+	}
+	g, err = names(f, 2)
+	if err != nil || strings.Join(g, " ") != "c" {
+		t.Errorf("Readdir(2) = %v, %v want c, nil", g, err)
+	}
+	if _, err = names(f, 2); err != io.EOF {
+		t.Errorf("err = %v want io.EOF", err)
+	}
+}
+
+func TestReaddirRecursive(t *testing.T) {
+	keys := []string{"d/a", "d/e/1", "d/e/f/2", "d/g", "x"}
+	ts := listServer(keys, 2)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/d/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fis, err := f.ReaddirRecursive(0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	var g []string
+	for _, fi := range fis {
+		if fi.IsDir() {
+			t.Errorf("%s is a directory", fi.Name())
+		}
+		g = append(g, fi.Name())
+	}
+	w := "d/a d/e/1 d/e/f/2 d/g"
+	if strings.Join(g, " ") != w {
+		t.Errorf("ReaddirRecursive(0) = %v want %s", g, w)
+	}
+}
+
+func TestReaddirDirSizes(t *testing.T) {
+	// listServer gives each object a size equal to its key length.
+	keys := []string{"a", "d/x", "d/e/yy", "f/zzzz"}
+	ts := listServer(keys, 1)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.DirSizes = true
+	fis, err := f.Readdir(0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	want := map[string]DirStat{
+		"d": {"d/", 2, 3 + 6},
+		"f": {"f/", 1, 6},
+	}
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		ds, ok := fi.Sys().(*DirStat)
+		if !ok {
+			t.Errorf("%s: Sys() = %T want *DirStat", fi.Name(), fi.Sys())
+			continue
+		}
+		if *ds != want[fi.Name()] {
+			t.Errorf("%s: DirStat = %+v want %+v", fi.Name(), *ds, want[fi.Name()])
+		}
+		if fi.Size() != ds.Size {
+			t.Errorf("%s: Size() = %d want %d", fi.Name(), fi.Size(), ds.Size)
+		}
+		delete(want, fi.Name())
+	}
+	if len(want) > 0 {
+		t.Errorf("missing directories %v", want)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	keys := []string{"a", "b/c", "d"}
+	ts := listServer(keys, 1000)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := f.ReadDir(0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	var g []string
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if e.IsDir() != info.IsDir() || e.Type() != info.Mode().Type() {
+			t.Errorf("%s: entry and info disagree", e.Name())
+		}
+		g = append(g, e.Name())
+	}
+	if w := "a d b"; strings.Join(g, " ") != w {
+		t.Errorf("ReadDir(0) = %v want %s", g, w)
+	}
+
+	f, _ = NewFile(ts.URL+"/", nil)
+	names, err := f.Readdirnames(0)
+	if err != nil || strings.Join(names, " ") != "a d b" {
+		t.Errorf("Readdirnames(0) = %v, %v", names, err)
+	}
+}
+
+func TestReaddirSeek(t *testing.T) {
+	keys := []string{"a", "b", "c/1", "d", "e"}
+	ts := listServer(keys, 1000)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := names(f, 2)
+	if err != nil || strings.Join(g, " ") != "a b" {
+		t.Fatalf("Readdir(2) = %v, %v want a b", g, err)
+	}
+	marker := f.Marker()
+	if marker != "b" {
+		t.Errorf("Marker() = %q want b", marker)
+	}
+
+	// Resume in a new File from the saved marker.
+	f2, _ := NewFile(ts.URL+"/", nil)
+	f2.SeekTo(marker)
+	g, err = names(f2, 0)
+	if err != nil || strings.Join(g, " ") != "d e c" {
+		t.Errorf("after SeekTo: Readdir(0) = %v, %v want d e c", g, err)
+	}
+
+	f.Rewind()
+	g, err = names(f, 0)
+	if err != nil || strings.Join(g, " ") != "a b d e c" {
+		t.Errorf("after Rewind: Readdir(0) = %v, %v want all", g, err)
+	}
+}
+
+func TestSubdir(t *testing.T) {
+	keys := []string{"d/a", "d/e/1", "d/e/f/2", "d/g/3", "x"}
+	ts := listServer(keys, 10)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Walk the tree, descending into each directory.
+	var walk func(f *File) []string
+	walk = func(f *File) []string {
+		fis, err := f.Readdir(0)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		var a []string
+		for _, fi := range fis {
+			if fi.IsDir() {
+				a = append(a, walk(f.Subdir(fi.Name()))...)
+			} else {
+				a = append(a, fi.Name())
+			}
+		}
+		return a
+	}
+	g := walk(f)
+	if w := "d/a d/e/1 d/e/f/2 d/g/3"; strings.Join(g, " ") != w {
+		t.Errorf("walk = %q want %q", g, w)
+	}
+
+	g, err = names(f.Subdir("e"), 0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if w := "d/e/1 d/e/f"; strings.Join(g, " ") != w {
+		t.Errorf("relative Subdir = %q want %q", g, w)
+	}
+}
+
+func TestReaddirClient(t *testing.T) {
+	var hosts []string
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			hosts = append(hosts, req.URL.Host)
+			body := `<ListBucketResult><Contents><Key>a</Key></Contents></ListBucketResult>`
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+	f, err := NewFile("https://mybucket.s3.amazonaws.com/", &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := f.Readdirnames(0)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if len(g) != 1 || g[0] != "a" {
+		t.Errorf("names = %q want [a]", g)
+	}
+	if len(hosts) != 1 || hosts[0] != "mybucket.s3.amazonaws.com" {
+		t.Errorf("requests = %q, want one through c.Client", hosts)
+	}
+}
+
+func TestStatOwner(t *testing.T) {
+	f := &File{}
+	fis, err := f.parseResponse(strings.NewReader(`<ListBucketResult>
+		<Contents>
+			<Key>k</Key>
+			<Owner><ID>75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a</ID><DisplayName>mtd@amazon.com</DisplayName></Owner>
+		</Contents>
+	</ListBucketResult>`))
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	w := Owner{ID: "75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a", DisplayName: "mtd@amazon.com"}
+	if g := fis[0].Sys().(*Stat).Owner; g != w {
+		t.Errorf("Owner = %+v want %+v", g, w)
+	}
+}
+
+func TestListState(t *testing.T) {
+	keys := []string{"d/a", "d/b", "d/c", "d/e/1", "d/f"}
+	ts := listServer(keys, 2)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, err := names(f, 2); err != nil || strings.Join(g, " ") != "d/a d/b" {
+		t.Fatalf("first page = %q, %v", g, err)
+	}
+	st := f.State()
+	if w := (ListState{Prefix: "d/", Delimiter: "/", Marker: "d/b"}); st != w {
+		t.Errorf("State = %+v want %+v", st, w)
+	}
+
+	// Continue in a new File, as after a restart.
+	f2, err := NewFile(ts.URL+"/d", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f2.Restore(st); err != nil {
+		t.Fatal(err)
+	}
+	var all []string
+	for {
+		g, err := names(f2, 2)
+		all = append(all, g...)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+	}
+	if w := "d/c d/e d/f"; strings.Join(all, " ") != w {
+		t.Errorf("rest = %q want %q", all, w)
+	}
+	if !f2.State().Done {
+		t.Errorf("State = %+v want Done", f2.State())
+	}
+
+	f3, _ := NewFile(ts.URL+"/d", nil)
+	f3.Restore(f2.State())
+	if g, err := names(f3, 2); err != io.EOF || len(g) != 0 {
+		t.Errorf("after restoring a finished listing: %q, %v want EOF", g, err)
+	}
+	if err := f3.Restore(ListState{Prefix: "other/"}); err == nil {
+		t.Error("Restore accepted another prefix")
+	}
+}
@@ -0,0 +1,207 @@
+package s3util
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const (
+	defaultBlockSize   = 1 << 20
+	defaultCacheBlocks = 16
+)
+
+// A ReaderAt reads an S3 object at arbitrary offsets, for formats
+// such as zip and Parquet that are read out of order. It fetches
+// the object in blocks with HTTP range requests, combining runs of
+// adjacent blocks into one request, and keeps recently read blocks
+// in memory, so that many small reads near each other cost few
+// requests. It is safe for concurrent use.
+//
+// Range requests are conditional on the object's ETag when the
+// ReaderAt was made; if the object changes, reads fail with
+// ErrPreconditionFailed.
+type ReaderAt struct {
+	// BlockSize is the unit in which the object is fetched.
+	// If zero, 1MiB is used.
+	BlockSize int64
+
+	// CacheBlocks is the number of blocks kept in memory.
+	// If zero, 16 are kept.
+	CacheBlocks int
+
+	url  string
+	c    *Config
+	size int64
+	etag string
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element // by block number
+	lru    list.List               // of *block, most recently used first
+}
+
+type block struct {
+	n int64
+	b []byte
+}
+
+// NewReaderAt returns a ReaderAt for the S3 object at url.
+// It sends a HEAD request to learn the object's size and ETag.
+// Set the BlockSize and CacheBlocks fields, if desired,
+// before calling ReadAt.
+//
+// If c is nil, NewReaderAt uses DefaultConfig.
+func NewReaderAt(url string, c *Config) (*ReaderAt, error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	resp, err := head(url, c)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ContentLength < 0 {
+		return nil, errors.New("s3util: object size unknown")
+	}
+	return &ReaderAt{
+		url:    url,
+		c:      c,
+		size:   resp.ContentLength,
+		etag:   resp.Header.Get("Etag"),
+		blocks: make(map[int64]*list.Element),
+	}, nil
+}
+
+// Size returns the size of the object.
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *ReaderAt) blockSize() int64 {
+	if r.BlockSize > 0 {
+		return r.BlockSize
+	}
+	return defaultBlockSize
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("s3util: negative offset")
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	var eof error
+	if end > r.size {
+		end, eof = r.size, io.EOF
+	}
+	if end == off {
+		return 0, eof
+	}
+	bs := r.blockSize()
+	first, last := off/bs, (end-1)/bs
+
+	// Find the blocks we have, then fetch each run of
+	// missing blocks with a single request.
+	have := make(map[int64][]byte)
+	r.mu.Lock()
+	for n := first; n <= last; n++ {
+		if e := r.blocks[n]; e != nil {
+			r.lru.MoveToFront(e)
+			have[n] = e.Value.(*block).b
+		}
+	}
+	r.mu.Unlock()
+	for n := first; n <= last; {
+		if have[n] != nil {
+			n++
+			continue
+		}
+		m := n
+		for m+1 <= last && have[m+1] == nil {
+			m++
+		}
+		if err := r.fetch(n, m, have); err != nil {
+			return 0, err
+		}
+		n = m + 1
+	}
+
+	w := 0
+	for n := first; n <= last; n++ {
+		b := have[n]
+		start := int64(0)
+		if n == first {
+			start = off - n*bs
+		}
+		w += copy(p[w:end-off], b[start:])
+	}
+	return w, eof
+}
+
+// fetch reads blocks first through last, adds them to the
+// cache, and stores them in have.
+func (r *ReaderAt) fetch(first, last int64, have map[int64][]byte) error {
+	bs := r.blockSize()
+	start, end := first*bs, (last+1)*bs
+	if end > r.size {
+		end = r.size
+	}
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if r.etag != "" {
+		req.Header.Set("If-Match", r.etag)
+	}
+	resp, err := r.c.do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 412 {
+		closeBody(resp.Body)
+		return ErrPreconditionFailed
+	}
+	// A server that ignores Range sends the whole object,
+	// which will do only if we wanted the beginning.
+	if resp.StatusCode != 206 && !(resp.StatusCode == 200 && start == 0) {
+		return newRespError(resp)
+	}
+	defer closeBody(resp.Body)
+	buf := make([]byte, end-start)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		return err
+	}
+
+	max := r.CacheBlocks
+	if max <= 0 {
+		max = defaultCacheBlocks
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n := first; n <= last; n++ {
+		i := (n - first) * bs
+		j := i + bs
+		if j > int64(len(buf)) {
+			j = int64(len(buf))
+		}
+		b := buf[i:j:j]
+		have[n] = b
+		if e := r.blocks[n]; e != nil {
+			r.lru.MoveToFront(e)
+			continue
+		}
+		r.blocks[n] = r.lru.PushFront(&block{n, b})
+	}
+	for r.lru.Len() > max {
+		e := r.lru.Back()
+		delete(r.blocks, e.Value.(*block).n)
+		r.lru.Remove(e)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package s3util
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// rangeServer returns a client serving data with range requests,
+// and a function returning the ranges requested so far.
+func rangeServer(t *testing.T, data []byte) (*http.Client, func() []string) {
+	var ranges []string
+	return &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{
+				StatusCode:    200,
+				ContentLength: int64(len(data)),
+				Header:        http.Header{"Etag": {`"v1"`}},
+				Body:          ioutil.NopCloser(bytes.NewReader(nil)),
+			}
+			if req.Method == "HEAD" {
+				return resp, nil
+			}
+			rng := req.Header.Get("Range")
+			ranges = append(ranges, rng)
+			if req.Header.Get("If-Match") != `"v1"` {
+				t.Errorf("If-Match = %q want %q", req.Header.Get("If-Match"), `"v1"`)
+			}
+			a, b, _ := strings.Cut(strings.TrimPrefix(rng, "bytes="), "-")
+			i, _ := strconv.Atoi(a)
+			j, _ := strconv.Atoi(b)
+			resp.StatusCode = 206
+			resp.Body = ioutil.NopCloser(bytes.NewReader(data[i : j+1]))
+			return resp, nil
+		}),
+	}, func() []string { r := ranges; ranges = nil; return r }
+}
+
+func TestReaderAt(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	c := *DefaultConfig
+	var ranges func() []string
+	c.Client, ranges = rangeServer(t, data)
+	r, err := NewReaderAt("https://s3.amazonaws.com/foo/bar", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	r.BlockSize = 10
+	r.CacheBlocks = 4
+	if r.Size() != 100 {
+		t.Errorf("Size = %d want 100", r.Size())
+	}
+	for _, test := range []struct {
+		off    int64
+		n      int
+		eof    bool
+		ranges string
+	}{
+		{12, 20, false, "bytes=10-39"},
+		{15, 5, false, ""},             // cached
+		{25, 30, false, "bytes=40-59"}, // blocks 2 and 3 cached
+		{95, 10, true, "bytes=90-99"},  // short read at the end
+		{5, 30, false, "bytes=0-29"},   // 3 cached; 1 and 2 evicted
+	} {
+		p := make([]byte, test.n)
+		n, err := r.ReadAt(p, test.off)
+		want := data[test.off:]
+		if len(want) > test.n {
+			want = want[:test.n]
+		}
+		if n != len(want) || !bytes.Equal(p[:n], want) {
+			t.Errorf("ReadAt(%d, %d) = %d bytes %v want %v", test.n, test.off, n, p[:n], want)
+		}
+		if (err == io.EOF) != test.eof || err != nil && err != io.EOF {
+			t.Errorf("ReadAt(%d, %d) err = %v", test.n, test.off, err)
+		}
+		if g := strings.Join(ranges(), ","); g != test.ranges {
+			t.Errorf("ReadAt(%d, %d) requested %q want %q", test.n, test.off, g, test.ranges)
+		}
+	}
+}
+
+func TestReaderAtZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < 20; i++ {
+		w, _ := zw.Create(fmt.Sprintf("f%d.txt", i))
+		io.WriteString(w, strings.Repeat("x", 5000))
+	}
+	zw.Close()
+	c := *DefaultConfig
+	var ranges func() []string
+	c.Client, ranges = rangeServer(t, buf.Bytes())
+	r, err := NewReaderAt("https://s3.amazonaws.com/foo/bar.zip", &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	r.BlockSize = 4096
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if len(zr.File) != 20 {
+		t.Errorf("got %d files want 20", len(zr.File))
+	}
+	// The directory is read in many small reads near the end.
+	if n := len(ranges()); n > 2 {
+		t.Errorf("reading the zip directory took %d requests", n)
+	}
+}
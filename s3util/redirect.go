@@ -0,0 +1,80 @@
+package s3util
+
+import (
+	"net/http"
+)
+
+// A RedirectPolicy says what to do when S3 answers a request
+// with a redirect. See Config.Redirects.
+type RedirectPolicy int
+
+const (
+	// RedirectFollow leaves redirects to the Config's Client,
+	// which by default follows them without signing the new
+	// requests again.
+	RedirectFollow RedirectPolicy = iota
+
+	// RedirectNone returns the redirect response itself, which
+	// the functions of this package report as an error whose
+	// message includes the body, naming the right endpoint.
+	RedirectNone
+
+	// RedirectResign follows redirects that give a Location,
+	// signing each new request for its destination. A request
+	// whose body can't be sent again, one without GetBody,
+	// gets the redirect response instead.
+	RedirectResign
+)
+
+// maxRedirects is the most redirects followed for one request,
+// as by http.Client.
+const maxRedirects = 10
+
+// redirectClient returns client, or a copy of it that leaves
+// redirects to c, if c's policy is other than RedirectFollow.
+func (c *Config) redirectClient(client *http.Client) *http.Client {
+	if c.Redirects == RedirectFollow {
+		return client
+	}
+	cl := *client
+	cl.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &cl
+}
+
+// redirect returns a request to send in place of r, signed for
+// the location given in resp, or nil if resp is not a redirect
+// that c follows. If it returns a request, it closes resp's body.
+func (c *Config) redirect(r *http.Request, resp *http.Response) (*http.Request, error) {
+	if c.Redirects != RedirectResign {
+		return nil, nil
+	}
+	switch resp.StatusCode {
+	case 301, 302, 307, 308:
+	default:
+		return nil, nil
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		// No Location, as in S3's 301 PermanentRedirect;
+		// its body names the bucket's endpoint.
+		return nil, nil
+	}
+	nr := r.Clone(r.Context())
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.GetBody == nil {
+			return nil, nil
+		}
+		if nr.Body, err = r.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	nr.URL = loc
+	nr.Host = loc.Host
+	nr.Header.Del("Authorization")
+	closeBody(resp.Body)
+	c.resolve(nr)
+	c.sign(nr)
+	return nr, nil
+}
@@ -0,0 +1,59 @@
+package s3util
+
+import (
+	"github.com/kr/s3"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	auth := map[string]string{}
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth[r.URL.Path] = r.Header.Get("Authorization")
+		if r.URL.Path == "/bucket/old" {
+			w.Header().Set("Location", "/bucket/new")
+			w.WriteHeader(307)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("Etag", `"e"`)
+	}))
+	defer ts.Close()
+
+	c := *DefaultConfig
+	c.Keys = &s3.Keys{AccessKey: "id", SecretKey: "secret"}
+	c.Redirects = RedirectNone
+	_, err := Put(ts.URL+"/bucket/old", strings.NewReader("data"), nil, &c)
+	if err == nil || !strings.Contains(err.Error(), "307") {
+		t.Errorf("RedirectNone: err = %v, want status 307", err)
+	}
+	if _, ok := auth["/bucket/new"]; ok {
+		t.Error("RedirectNone: followed the redirect")
+	}
+
+	c.Redirects = RedirectResign
+	if _, err := Put(ts.URL+"/bucket/old", strings.NewReader("data"), nil, &c); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if body != "data" {
+		t.Errorf("body = %q want %q", body, "data")
+	}
+	if a := auth["/bucket/new"]; a == "" || a == auth["/bucket/old"] {
+		t.Errorf("redirected request not signed again: %q", a)
+	}
+
+	// A body that can't be sent again stops at the redirect.
+	delete(auth, "/bucket/new")
+	r := ioutil.NopCloser(strings.NewReader("data"))
+	if _, err := Put(ts.URL+"/bucket/old", r, nil, &c); err == nil {
+		t.Error("expected error for unreplayable body")
+	}
+	if _, ok := auth["/bucket/new"]; ok {
+		t.Error("followed the redirect without the body")
+	}
+}
@@ -0,0 +1,88 @@
+package s3util
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+// shardChars are the default split points of ReaddirSharded,
+// appended to the File's prefix.
+const shardChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// ReaddirSharded lists every object under f's prefix, as
+// ReaddirRecursive(0) does, but faster for very large listings:
+// it divides the key space at the given split points, which are
+// keys, and lists the ranges between them concurrently. If splits
+// is nil, the key space is divided at each letter and digit
+// following the prefix. The merged result is in key order.
+//
+// Split points work best where the keys are dense; a range that
+// holds most of the keys takes as long as listing it alone.
+// ReaddirSharded applies f's Filter, but neither uses nor changes
+// f's position in its listing.
+func (f *File) ReaddirSharded(splits []string) ([]os.FileInfo, error) {
+	if splits == nil {
+		for _, c := range shardChars {
+			splits = append(splits, f.prefix+string(c))
+		}
+	}
+	splits = append([]string(nil), splits...)
+	sort.Strings(splits)
+
+	// Shard i holds the keys after bounds[i] up to and including
+	// bounds[i+1]; the last shard is unbounded above.
+	bounds := append([]string{""}, splits...)
+	shards := make([][]os.FileInfo, len(bounds))
+	errs := make([]error, len(bounds))
+	sem := make(chan bool, concurrency)
+	var wg sync.WaitGroup
+	for i := range bounds {
+		upper := ""
+		if i+1 < len(bounds) {
+			upper = bounds[i+1]
+		}
+		wg.Add(1)
+		sem <- true
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			shards[i], errs[i] = f.listRange(bounds[i], upper)
+		}(i)
+	}
+	wg.Wait()
+	var infos []os.FileInfo
+	for i, shard := range shards {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		infos = append(infos, shard...)
+	}
+	return infos, nil
+}
+
+// listRange lists the objects under f's prefix with keys after
+// lower and, unless upper is empty, no greater than upper.
+func (f *File) listRange(lower, upper string) ([]os.FileInfo, error) {
+	if upper != "" && upper <= lower {
+		return nil, nil
+	}
+	sub := &File{url: f.url, prefix: f.prefix, config: f.config, Filter: f.Filter, marker: lower}
+	var infos []os.FileInfo
+	for sub.result == nil || sub.result.IsTruncated {
+		page, err := sub.readPage(0, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range page {
+			if upper != "" && fi.(*fileInfo).sys.Key > upper {
+				return infos, nil
+			}
+			infos = append(infos, fi)
+		}
+		if upper != "" && sub.marker > upper {
+			break
+		}
+	}
+	return infos, nil
+}
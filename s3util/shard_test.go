@@ -0,0 +1,30 @@
+package s3util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReaddirSharded(t *testing.T) {
+	keys := []string{"p/!", "p/0", "p/1x", "p/A", "p/a", "p/a/b", "p/ab", "p/z", "p/~", "q"}
+	ts := listServer(keys, 2)
+	defer ts.Close()
+	f, err := NewFile(ts.URL+"/p", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := "p/! p/0 p/1x p/A p/a p/a/b p/ab p/z p/~"
+	for _, splits := range [][]string{nil, {"p/a/b", "p/1"}, {}} {
+		fis, err := f.ReaddirSharded(splits)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		var g []string
+		for _, fi := range fis {
+			g = append(g, fi.Name())
+		}
+		if strings.Join(g, " ") != w {
+			t.Errorf("splits %q: got %v want %s", splits, g, w)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package s3util
+
+import (
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// StatKey returns a FileInfo for the object with the given key in
+// the bucket at bucketURL, such as "https://mybucket.s3.amazonaws.com/".
+// It finds the object by listing the bucket, which, unlike a HEAD
+// request, reports the object's storage class and owner in the
+// *Stat returned by the FileInfo's Sys method.
+//
+// If the listing is not permitted, StatKey falls back to a HEAD
+// request, and the Stat has only the key, size, ETag, and
+// modification time. If the object does not exist, the error
+// satisfies os.IsNotExist.
+//
+// If c is nil, StatKey uses DefaultConfig.
+func StatKey(bucketURL, key string, c *Config) (os.FileInfo, error) {
+	f, err := NewFile(bucketURL, c)
+	if err != nil {
+		return nil, err
+	}
+	f.prefix = key
+	infos, err := f.readPage(1, false)
+	if errors.Is(err, fs.ErrPermission) {
+		return statHead(f.url, key, c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 || infos[0].(*fileInfo).sys.Key != key {
+		return nil, &os.PathError{Op: "stat", Path: key, Err: os.ErrNotExist}
+	}
+	return infos[0], nil
+}
+
+// statHead is StatKey using a HEAD request.
+func statHead(bucketURL, key string, c *Config) (os.FileInfo, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + key
+	info, err := Head(u.String(), c)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{
+		name:    key,
+		size:    info.Size,
+		modTime: info.LastModified,
+		sys: &Stat{
+			Key:          key,
+			LastModified: info.LastModified.UTC().Format(time.RFC3339),
+			ETag:         info.ETag,
+			Size:         strconv.FormatInt(info.Size, 10),
+		},
+	}, nil
+}
@@ -0,0 +1,57 @@
+package s3util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestStatKey(t *testing.T) {
+	ts := listServer([]string{"a", "ab", "b/c"}, 1000)
+	defer ts.Close()
+	fi, err := StatKey(ts.URL+"/", "a", nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if fi.Name() != "a" || fi.Size() != 1 {
+		t.Errorf("got %s size %d want a size 1", fi.Name(), fi.Size())
+	}
+	if st := fi.Sys().(*Stat); st.Key != "a" || st.ETag != "e" {
+		t.Errorf("Stat = %+v", st)
+	}
+	for _, key := range []string{"b", "aa", "b/"} {
+		if _, err := StatKey(ts.URL+"/", key, nil); !os.IsNotExist(err) {
+			t.Errorf("StatKey(%q): err = %v want not exist", key, err)
+		}
+	}
+}
+
+func TestStatKeyHead(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET":
+			w.WriteHeader(403)
+		case r.URL.Path == "/x/y":
+			w.Header().Set("Etag", `"abc"`)
+			w.Header().Set("Content-Length", "42")
+			w.Header().Set("Last-Modified", "Tue, 27 Mar 2007 19:36:42 GMT")
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	fi, err := StatKey(ts.URL+"/", "x/y", nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if fi.Name() != "x/y" || fi.Size() != 42 || fi.ModTime().Year() != 2007 {
+		t.Errorf("got %s size %d modtime %v", fi.Name(), fi.Size(), fi.ModTime())
+	}
+	if st := fi.Sys().(*Stat); st.ETag != "abc" || st.Size != "42" {
+		t.Errorf("Stat = %+v", st)
+	}
+	if _, err := StatKey(ts.URL+"/", "nope", nil); !os.IsNotExist(err) {
+		t.Errorf("err = %v want not exist", err)
+	}
+}
@@ -0,0 +1,50 @@
+package s3util
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// statConcurrency is the number of HEAD requests StatMany
+// has in flight at once.
+const statConcurrency = 4 * concurrency
+
+// StatMany returns information about each of the S3 objects at
+// urls, as Head does, sending many HEAD requests at once. The
+// results are in the order of urls: for each, either the info or
+// the error is set. The requests share c.ListRate, if set, and a
+// request that fails with a network error or a 5xx response is
+// retried with backoff, so that a pipeline asking about thousands
+// of objects neither floods S3 nor gives up on a transient error.
+//
+// If c is nil, StatMany uses DefaultConfig.
+func StatMany(urls []string, c *Config) ([]*ObjectInfo, []error) {
+	if c == nil {
+		c = DefaultConfig
+	}
+	infos := make([]*ObjectInfo, len(urls))
+	errs := make([]error, len(urls))
+	forEachN(len(urls), statConcurrency, func(i int) error {
+		for try := 1; ; try++ {
+			c.ListRate.wait()
+			infos[i], errs[i] = Head(urls[i], c)
+			if errs[i] == nil || !retryable(errs[i]) || try == slowDownTries {
+				return nil
+			}
+			time.Sleep(slowDownDelay(try - 1))
+		}
+	})
+	return infos, errs
+}
+
+// retryable reports whether err, from a request, is a network
+// error or a 5xx response, which may succeed if tried again.
+func retryable(err error) bool {
+	var re *respError
+	if errors.As(err, &re) {
+		return re.r.StatusCode >= 500
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
@@ -0,0 +1,69 @@
+package s3util
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStatMany(t *testing.T) {
+	defer func(d time.Duration) { slowDownBase = d }(slowDownBase)
+	slowDownBase = time.Millisecond
+	var (
+		mu    sync.Mutex
+		tries = map[string]int{}
+	)
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			tries[req.URL.Path]++
+			n := tries[req.URL.Path]
+			mu.Unlock()
+			code := 200
+			switch req.URL.Path {
+			case "/missing":
+				code = 404
+			case "/flaky":
+				if n < 3 {
+					code = 503
+				}
+			case "/broken":
+				return nil, errors.New("connection reset")
+			}
+			return &http.Response{
+				StatusCode:    code,
+				ContentLength: int64(len(req.URL.Path)),
+				Header:        http.Header{"Etag": {`"e"`}},
+				Body:          ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+	}
+	urls := []string{
+		"https://b.s3.amazonaws.com/a",
+		"https://b.s3.amazonaws.com/missing",
+		"https://b.s3.amazonaws.com/flaky",
+		"https://b.s3.amazonaws.com/broken",
+	}
+	infos, errs := StatMany(urls, &c)
+	if errs[0] != nil || infos[0] == nil || infos[0].Size != 2 {
+		t.Errorf("a: %+v, %v", infos[0], errs[0])
+	}
+	if !os.IsNotExist(errs[1]) || infos[1] != nil {
+		t.Errorf("missing: %+v, %v want not exist", infos[1], errs[1])
+	}
+	if errs[2] != nil || infos[2] == nil || tries["/flaky"] != 3 {
+		t.Errorf("flaky: %+v, %v after %d tries", infos[2], errs[2], tries["/flaky"])
+	}
+	if errs[3] == nil || tries["/broken"] != slowDownTries {
+		t.Errorf("broken: %v after %d tries want error after %d", errs[3], tries["/broken"], slowDownTries)
+	}
+	if tries["/missing"] != 1 {
+		t.Errorf("missing tried %d times want 1", tries["/missing"])
+	}
+}
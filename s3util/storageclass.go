@@ -0,0 +1,41 @@
+package s3util
+
+import "net/http"
+
+// Storage classes, for the X-Amz-Storage-Class header of Create,
+// Put, and Copy, ObjectOptions.StorageClass, and Transition, and
+// as reported in listings. S3 reports objects in the default
+// class as StorageClassStandard.
+// See https://docs.aws.amazon.com/AmazonS3/latest/userguide/storage-class-intro.html.
+const (
+	StorageClassStandard           = "STANDARD"
+	StorageClassReducedRedundancy  = "REDUCED_REDUNDANCY"
+	StorageClassStandardIA         = "STANDARD_IA"
+	StorageClassOnezoneIA          = "ONEZONE_IA"
+	StorageClassIntelligentTiering = "INTELLIGENT_TIERING"
+	StorageClassGlacierIR          = "GLACIER_IR"
+	StorageClassGlacier            = "GLACIER"
+	StorageClassDeepArchive        = "DEEP_ARCHIVE"
+	StorageClassExpressOnezone     = "EXPRESS_ONEZONE"
+	StorageClassOutposts           = "OUTPOSTS"
+	StorageClassSnow               = "SNOW"
+)
+
+// Transition moves the S3 object at url to the given storage class,
+// such as StorageClassStandardIA or StorageClassGlacierIR, by
+// copying it onto itself, so that archival jobs can change an
+// object's class directly rather than through lifecycle rules.
+// The object's metadata and tags are kept. In a versioned bucket
+// the copy is a new version, and the old one stays in its class.
+//
+// As with Copy, objects over 5 GiB can't be moved this way, and
+// objects in the Glacier and Deep Archive classes must be restored
+// first.
+//
+// If c is nil, Transition uses DefaultConfig.
+func Transition(url, class string, c *Config) error {
+	h := http.Header{}
+	h.Set("X-Amz-Storage-Class", class)
+	h.Set("X-Amz-Metadata-Directive", "COPY")
+	return Copy(url, url, h, c)
+}
@@ -0,0 +1,38 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransition(t *testing.T) {
+	var got *http.Request
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			got = req
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("<CopyObjectResult></CopyObjectResult>")),
+			}, nil
+		}),
+	}
+	err := Transition("https://b.s3.amazonaws.com/a%20b", StorageClassGlacierIR, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if got.Method != "PUT" || got.URL.Path != "/a b" {
+		t.Errorf("request = %s %s want PUT /a b", got.Method, got.URL.Path)
+	}
+	for k, w := range map[string]string{
+		"X-Amz-Copy-Source":        "/b/a%20b",
+		"X-Amz-Storage-Class":      "GLACIER_IR",
+		"X-Amz-Metadata-Directive": "COPY",
+	} {
+		if g := got.Header.Get(k); g != w {
+			t.Errorf("%s = %q want %q", k, g, w)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package s3util
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// SyncBuckets makes the objects under dstURL match those under
+// srcURL, where each URL names a bucket or a directory in one,
+// such as "https://mybucket.s3.amazonaws.com/backup/". Each object
+// under srcURL that is missing under dstURL, or differs from its
+// counterpart in size or ETag, is copied on the server, up to 5 at
+// a time, with a TransferManager. Objects under dstURL that are not
+// under srcURL are left alone.
+//
+// Objects are compared as by Diff.
+//
+// The progress function, if not nil, is called as copies finish.
+// SyncBuckets returns an error if either listing fails or if any
+// copy fails; in the latter case the other copies are still made.
+// Both URLs must be reachable with c. If c is nil, SyncBuckets uses
+// DefaultConfig.
+func SyncBuckets(srcURL, dstURL string, progress func(Progress), c *Config) error {
+	for _, u := range []string{srcURL, dstURL} {
+		if _, ok := localDir(u); ok {
+			return fmt.Errorf("s3util: sync: %s is not an S3 URL", u)
+		}
+	}
+	src, err := treeEntries(srcURL, c)
+	if err != nil {
+		return err
+	}
+	dst, err := treeEntries(dstURL, c)
+	if err != nil {
+		return err
+	}
+	d, err := diffTrees(dst, src)
+	if err != nil {
+		return err
+	}
+	f, err := NewFile(dstURL, c)
+	if err != nil {
+		return err
+	}
+	var jobs []Job
+	for _, keys := range [][]string{d.Added, d.Changed} {
+		for _, k := range keys {
+			jobs = append(jobs, Job{
+				Kind: JobCopy,
+				Src:  src[k].key,
+				Dst:  keyURL(f.url, f.prefix+k),
+			})
+		}
+	}
+	m := &TransferManager{Config: c, Progress: progress}
+	var (
+		failed   int
+		firstErr error
+	)
+	for i, err := range m.Run(jobs) {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("s3util: sync %s: %w", jobs[i].Src, err)
+			}
+			failed++
+		}
+	}
+	if failed > 1 {
+		return fmt.Errorf("%w (and %d other failures)", firstErr, failed-1)
+	}
+	return firstErr
+}
+
+// keyURL returns the URL of the object with the given key in
+// the bucket at bucketURL, which has no path.
+func keyURL(bucketURL, key string) string {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return bucketURL + "/" + key
+	}
+	u.Path = "/" + key
+	return u.String()
+}
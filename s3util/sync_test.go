@@ -0,0 +1,70 @@
+package s3util
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncBuckets(t *testing.T) {
+	type obj struct{ size, etag string }
+	bucket := map[string]obj{
+		"src/a":   {"1", "a1"},
+		"src/b":   {"2", "b1"},
+		"src/c":   {"3", "c1-2"},
+		"src/d/e": {"4", "e1"},
+		"dst/b":   {"2", "b2"},
+		"dst/c":   {"3", "c2-3"},
+		"dst/z":   {"9", "z"},
+	}
+	var (
+		mu     sync.Mutex
+		copies []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			prefix := r.URL.Query().Get("prefix")
+			var keys []string
+			for k := range bucket {
+				if strings.HasPrefix(k, prefix) {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			io.WriteString(w, "<ListBucketResult>")
+			for _, k := range keys {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%s</Size><ETag>&quot;%s&quot;</ETag></Contents>", k, bucket[k].size, bucket[k].etag)
+			}
+			io.WriteString(w, "</ListBucketResult>")
+		case "PUT":
+			mu.Lock()
+			copies = append(copies, r.Header.Get("X-Amz-Copy-Source")+" "+r.URL.Path)
+			mu.Unlock()
+		}
+	}))
+	defer ts.Close()
+
+	var last Progress
+	err := SyncBuckets(ts.URL+"/src", ts.URL+"/dst/", func(p Progress) { last = p }, nil)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	sort.Strings(copies)
+	w := []string{
+		"/src/a /dst/a", // an IP host is path-style; the bucket is in the path
+		"/src/b /dst/b",
+		"/src/d/e /dst/d/e",
+	}
+	if strings.Join(copies, "\n") != strings.Join(w, "\n") {
+		t.Errorf("copies = %q want %q", copies, w)
+	}
+	if last.Jobs != 3 || last.JobsDone != 3 {
+		t.Errorf("progress = %+v want 3 of 3 jobs", last)
+	}
+}
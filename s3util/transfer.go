@@ -0,0 +1,176 @@
+package s3util
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// JobKind says what a Job transfers.
+type JobKind int
+
+const (
+	JobUpload   JobKind = iota // local file Src to S3 URL Dst
+	JobDownload                // S3 URL Src to local file Dst
+	JobCopy                    // S3 URL Src to S3 URL Dst, on the server
+)
+
+// Job is one transfer to be run by a TransferManager.
+type Job struct {
+	Kind JobKind
+	Src  string
+	Dst  string
+}
+
+// Progress reports the aggregate state of the jobs
+// passed to TransferManager.Run.
+type Progress struct {
+	Jobs     int   // total number of jobs
+	JobsDone int   // jobs finished, successfully or not
+	Bytes    int64 // data uploaded and downloaded so far
+}
+
+// TransferManager runs many transfer jobs concurrently,
+// under a shared concurrency and bandwidth limit.
+type TransferManager struct {
+	// Config is used for all jobs. If nil, DefaultConfig is used.
+	Config *Config
+
+	// Concurrency is the maximum number of jobs run at once.
+	// If zero, 5 jobs are run at once. Each upload additionally
+	// sends several parts concurrently.
+	Concurrency int
+
+	// BytesPerSecond, if positive, limits the combined rate at
+	// which all jobs upload and download data. Server-side copies
+	// are not limited.
+	BytesPerSecond int64
+
+	// Progress, if not nil, is called as jobs make progress.
+	// Calls are serialized.
+	Progress func(Progress)
+
+	mu   sync.Mutex
+	p    Progress
+	next time.Time // when the rate limiter next allows data
+}
+
+// Run runs jobs and returns a slice holding the error,
+// or nil, from each job, in the same order as jobs.
+func (m *TransferManager) Run(jobs []Job) []error {
+	n := m.Concurrency
+	if n <= 0 {
+		n = concurrency
+	}
+	m.mu.Lock()
+	m.p = Progress{Jobs: len(jobs)}
+	m.mu.Unlock()
+	errs := make([]error, len(jobs))
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				errs[i] = m.run(jobs[i])
+				m.update(0, 1)
+			}
+		}()
+	}
+	for i := range jobs {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	return errs
+}
+
+func (m *TransferManager) run(j Job) error {
+	c := m.Config
+	if c == nil {
+		c = DefaultConfig
+	}
+	switch j.Kind {
+	case JobUpload:
+		f, err := os.Open(j.Src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		u, err := newUploader(context.Background(), j.Dst, nil, c)
+		if err != nil {
+			return err
+		}
+		w := c.wrap(u)
+		if _, err = io.Copy(w, &meteredReader{f, m}); err != nil {
+			// Abort first, so that closing w can't
+			// complete the upload with partial data.
+			u.Abort()
+			w.Close()
+			return err
+		}
+		return w.Close()
+	case JobDownload:
+		r, err := Open(j.Src, c)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		f, err := os.Create(j.Dst)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(f, &meteredReader{r, m}); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	case JobCopy:
+		return Copy(j.Dst, j.Src, nil, c)
+	}
+	return os.ErrInvalid
+}
+
+// update records progress and reports it.
+func (m *TransferManager) update(bytes int64, jobs int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.p.Bytes += bytes
+	m.p.JobsDone += jobs
+	if m.Progress != nil {
+		m.Progress(m.p)
+	}
+}
+
+// wait blocks until the bandwidth limit allows n more bytes.
+func (m *TransferManager) wait(n int) {
+	if m.BytesPerSecond <= 0 {
+		return
+	}
+	m.mu.Lock()
+	now := time.Now()
+	if m.next.Before(now) {
+		m.next = now
+	}
+	t := m.next
+	m.next = m.next.Add(time.Duration(int64(n) * int64(time.Second) / m.BytesPerSecond))
+	m.mu.Unlock()
+	time.Sleep(t.Sub(now))
+}
+
+type meteredReader struct {
+	r io.Reader
+	m *TransferManager
+}
+
+func (r *meteredReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.m.wait(n)
+		r.m.update(int64(n), 0)
+	}
+	return n, err
+}
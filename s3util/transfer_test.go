@@ -0,0 +1,77 @@
+package s3util
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransferManager(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			status, body := 200, "hello"
+			if req.URL.Path == "/missing" {
+				status = 404
+			}
+			if req.Header.Get("X-Amz-Copy-Source") != "" {
+				body = `<CopyObjectResult><ETag>"e"</ETag></CopyObjectResult>`
+			}
+			return &http.Response{
+				StatusCode: status,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+	dir := t.TempDir()
+	var last Progress
+	m := &TransferManager{
+		Config:   &c,
+		Progress: func(p Progress) { last = p },
+	}
+	errs := m.Run([]Job{
+		{JobDownload, "https://b.s3.amazonaws.com/a", filepath.Join(dir, "a")},
+		{JobDownload, "https://b.s3.amazonaws.com/missing", filepath.Join(dir, "b")},
+		{JobCopy, "https://b.s3.amazonaws.com/a", "https://b.s3.amazonaws.com/c"},
+	})
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("errs = %v", errs)
+	}
+	if w := (Progress{Jobs: 3, JobsDone: 3, Bytes: 5}); last != w {
+		t.Errorf("progress = %+v want %+v", last, w)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "a"))
+	if err != nil || string(b) != "hello" {
+		t.Errorf("downloaded %q, %v", b, err)
+	}
+}
+
+func TestTransferManagerUploadError(t *testing.T) {
+	var reqs []string
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			reqs = append(reqs, req.Method)
+			body := ""
+			if req.Method == "POST" {
+				body = "<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>"
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+	m := &TransferManager{Config: &c}
+	// Reading a directory fails.
+	errs := m.Run([]Job{{JobUpload, t.TempDir(), "https://b.s3.amazonaws.com/a"}})
+	if errs[0] == nil {
+		t.Error("expected error")
+	}
+	// The upload is aborted, not completed.
+	if g := strings.Join(reqs, " "); g != "POST DELETE" {
+		t.Errorf("requests = %q, want POST DELETE", g)
+	}
+}
@@ -0,0 +1,98 @@
+package s3util
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+// NewTransport returns an http.Transport tuned for S3 and the
+// settings in c. To use it, set
+//
+//   c.Client = &http.Client{Transport: c.NewTransport()}
+//
+// Unlike http.DefaultTransport, which keeps only two idle
+// connections per host, it keeps enough to serve several
+// concurrent multipart uploads without reconnecting.
+func (c *Config) NewTransport() *http.Transport {
+	d := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           d.DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   4 * concurrency, // a few uploads at once
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     !c.DisableHTTP2,
+	}
+	if c.Proxy != nil {
+		t.Proxy = http.ProxyURL(c.Proxy)
+	}
+	if c.RootCAs != nil || c.Certificates != nil {
+		t.TLSClientConfig = &tls.Config{
+			RootCAs:      c.RootCAs,
+			Certificates: c.Certificates,
+		}
+	}
+	if c.DisableHTTP2 {
+		// A non-nil, empty map turns off HTTP/2.
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return t
+}
+
+// Warmup opens n connections to the host of url, by sending n
+// concurrent HEAD requests for url, so that a large transfer
+// that follows doesn't wait on TLS handshakes made one at a time.
+// The connections stay open only if c's transport keeps at least
+// n idle connections per host, as one from NewTransport does for
+// n up to a few times the upload concurrency. Over HTTP/2, a
+// single connection is shared by all requests, so only one is
+// opened.
+//
+// The status of the responses is ignored; Warmup returns an error
+// only if a connection could not be made.
+func (c *Config) Warmup(url string, n int) error {
+	errc := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			r, err := http.NewRequest("HEAD", url, nil)
+			if err != nil {
+				errc <- err
+				return
+			}
+			resp, err := c.do(r)
+			if err == nil {
+				closeBody(resp.Body)
+			}
+			errc <- err
+		}()
+	}
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-errc; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// maxDrain is the most of a response body that closeBody reads.
+// A connection with more left to read is not worth keeping.
+const maxDrain = 256 << 10
+
+// closeBody reads the rest of a response body, up to maxDrain
+// bytes, and closes it, so that the connection it arrived on can
+// be reused. A body closed while unread, as after a decoding
+// error, would have its connection closed instead.
+func closeBody(rc io.ReadCloser) {
+	io.CopyN(ioutil.Discard, rc, maxDrain)
+	rc.Close()
+}
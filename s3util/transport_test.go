@@ -0,0 +1,164 @@
+package s3util
+
+import (
+	"bytes"
+	"crypto/x509"
+	"github.com/kr/s3"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	c := *DefaultConfig
+	tr := c.NewTransport()
+	if tr.MaxIdleConnsPerHost < concurrency {
+		t.Errorf("MaxIdleConnsPerHost = %d want >= %d", tr.MaxIdleConnsPerHost, concurrency)
+	}
+	if tr.TLSNextProto != nil {
+		t.Errorf("TLSNextProto = %v want nil", tr.TLSNextProto)
+	}
+	c.DisableHTTP2 = true
+	tr = c.NewTransport()
+	if tr.TLSNextProto == nil || tr.ForceAttemptHTTP2 {
+		t.Errorf("HTTP/2 not disabled")
+	}
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	c := *DefaultConfig
+	c.Proxy, _ = url.Parse("http://proxy.example.com:3128")
+	c.RootCAs = x509.NewCertPool()
+	tr := c.NewTransport()
+	req, _ := http.NewRequest("GET", "https://b.s3.amazonaws.com/", nil)
+	u, err := tr.Proxy(req)
+	if err != nil || u.String() != "http://proxy.example.com:3128" {
+		t.Errorf("proxy = %v, %v", u, err)
+	}
+	if tr.TLSClientConfig == nil || tr.TLSClientConfig.RootCAs != c.RootCAs {
+		t.Errorf("RootCAs not set")
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	const n = 4
+	var (
+		conns   int32
+		arrived sync.WaitGroup
+	)
+	arrived.Add(n)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			// Hold each warm-up request until all have arrived,
+			// so none can reuse another's connection.
+			arrived.Done()
+			arrived.Wait()
+		}
+		w.WriteHeader(403)
+	}))
+	ts.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := *DefaultConfig
+	tr := ts.Client().Transport.(*http.Transport)
+	tr.MaxIdleConnsPerHost = n
+	c.Client = &http.Client{Transport: tr}
+	if err := c.Warmup(ts.URL+"/", n); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if g := atomic.LoadInt32(&conns); g != n {
+		t.Errorf("conns = %d want %d", g, n)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Client.Get(ts.URL + "/")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if g := atomic.LoadInt32(&conns); g > n {
+		t.Errorf("conns after warm-up = %d want %d", g, n)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	var hosts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.Host)
+		io.WriteString(w, "ok")
+	}))
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	c := *DefaultConfig
+	c.Keys = &s3.Keys{AccessKey: "id", SecretKey: "secret"}
+	var signed string
+	c.Service = &s3.Service{
+		Domain: "amazonaws.com",
+		Audit:  func(r *http.Request, sts, sig string) { signed = sts },
+	}
+	c.Resolve = func(r *http.Request) string {
+		if r.URL.Host != "b.s3.amazonaws.com" {
+			t.Errorf("Resolve got host %q", r.URL.Host)
+		}
+		return addr
+	}
+	var buf bytes.Buffer
+	if _, err := Get("http://b.s3.amazonaws.com/k", &buf, &c); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ok" {
+		t.Errorf("body = %q want ok", buf.String())
+	}
+	if len(hosts) != 1 || hosts[0] != "b.s3.amazonaws.com" {
+		t.Errorf("server saw Host %q want b.s3.amazonaws.com", hosts)
+	}
+	if !strings.HasSuffix(signed, "\n/b/k") {
+		t.Errorf("signed %q, want resource /b/k", signed)
+	}
+}
+
+// trackBody is a response body that records how it was consumed.
+type trackBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestCloseBodyOnDecodeError(t *testing.T) {
+	var body *trackBody
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body = &trackBody{Reader: strings.NewReader("<bad></x>" + strings.Repeat(" ", 100<<10))}
+			return &http.Response{StatusCode: 200, Body: body}, nil
+		}),
+	}
+	if _, err := GetBucketAccelerate("https://foo.s3.amazonaws.com/", &c); err == nil {
+		t.Fatal("expected decode error")
+	}
+	if n, _ := io.Copy(io.Discard, body); n != 0 || !body.closed {
+		t.Errorf("body has %d bytes left, closed %v; want drained and closed", n, body.closed)
+	}
+}
@@ -2,16 +2,19 @@ package s3util
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
 	"encoding/xml"
-	"github.com/kr/s3"
 	"fmt"
+	"github.com/kr/s3"
+	"hash"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
-	"syscall"
-	"time"
 )
 
 // defined by amazon
@@ -20,37 +23,61 @@ const (
 	maxPartSize = 1<<31 - 1 // for 32-bit use; amz max is 5GiB
 	maxObjSize  = 5 * 1024 * 1024 * 1024 * 1024
 	maxNPart    = 10000
+	maxCopySize = 5 * 1024 * 1024 * 1024 // largest object for a single copy
 )
 
 const (
 	concurrency = 5
 	nTry        = 2
+	sniffLen    = 512 // used by http.DetectContentType
 )
 
 type part struct {
 	r   io.ReadSeeker
 	len int64
+	buf []byte // backing r, returned to the pool when done
 
 	// read by xml encoder
 	PartNumber int
 	ETag       string
 }
 
-type uploader struct {
-	s3       s3.Service
-	keys     s3.Keys
-	url      string
-	client   *http.Client
+// Uploader is an io.WriteCloser that uploads an S3 object with
+// a multipart upload, as returned by Create.
+type Uploader struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	s3     s3.Service
+	keys   s3.Keys
+	url    string
+	c      *Config // for sending requests
+	pool   *BufferPool
+
+	// UploadId identifies the multipart upload on S3.
+	// It is set once the upload has been initiated.
 	UploadId string // written by xml decoder
 
-	bufsz  int64
-	buf    []byte
-	off    int
-	ch     chan *part
-	part   int
-	closed bool
-	err    error
-	wg     sync.WaitGroup
+	result *Result
+
+	h        http.Header    // for the initiation request
+	ifMatch  string         // ETag the object must have when the upload completes
+	started  bool           // upload has been initiated
+	sniff    []byte         // start of data, if Content-Type is to be sniffed
+	zw       io.WriteCloser // compressor, if any
+	hashes   map[string]hash.Hash
+	size     int64 // bytes sent in parts
+	bufsz    int64
+	fixed    bool // bufsz does not grow
+	buf      []byte
+	off      int
+	ch       chan *part
+	part     int
+	closed   bool
+	closeErr error      // returned by Close
+	mu       sync.Mutex // held by Write, Close, and Abort
+	errMu    sync.Mutex // guards err and part ETags, which are set by the workers
+	err      error
+	wg       sync.WaitGroup
 
 	xml struct {
 		XMLName string `xml:"CompleteMultipartUpload"`
@@ -58,205 +85,661 @@ type uploader struct {
 	}
 }
 
+// Result describes an object created by an upload.
+// Location, Bucket, and Key are set only for multipart uploads.
+// For the meaning of these fields, see
+// http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadComplete.html.
+type Result struct {
+	Location  string
+	Bucket    string
+	Key       string
+	ETag      string // ETag value, without double quotes.
+	VersionId string // set only in versioned buckets
+
+	// Digests holds the hex digests of the data written,
+	// keyed by name, for each of Config.Hashes.
+	Digests map[string]string
+}
+
 // Create creates an S3 object at url and sends multipart upload requests as
-// data is written.
+// data is written. The returned io.WriteCloser is an *Uploader, unless
+// c.WrapWriters is set; AsUploader finds the Uploader in either case.
 //
-// If h is not nil, each of its entries is added to the HTTP request header.
+// If h is not nil, each of its entries is added to the HTTP request header,
+// along with any defaults in c.Header.
 // If c is nil, Create uses DefaultConfig.
+//
+// If c.DetectContentType is set and h has no Content-Type, the type is
+// taken from the extension of the object's key or, failing that, sniffed
+// from the first 512 bytes written. In the latter case the upload is not
+// initiated until the first part is sent, so errors initiating it are
+// reported by Write or Close.
 func Create(url string, h http.Header, c *Config) (io.WriteCloser, error) {
+	return CreateWithOptions(url, &ObjectOptions{Extra: h}, c)
+}
+
+// CreateWithOptions is like Create, but takes the settings of the
+// new object as typed options.
+func CreateWithOptions(url string, o *ObjectOptions, c *Config) (io.WriteCloser, error) {
+	h, err := o.Header()
+	if err != nil {
+		return nil, err
+	}
+	return CreateContext(context.Background(), url, h, c)
+}
+
+// CreateContext is like Create, but sends its requests with ctx.
+// If ctx is canceled before the upload completes, in-flight part
+// uploads are stopped, the multipart upload is aborted, and Write
+// and Close return ctx.Err().
+func CreateContext(ctx context.Context, url string, h http.Header, c *Config) (io.WriteCloser, error) {
 	if c == nil {
 		c = DefaultConfig
 	}
-	return newUploader(url, h, c)
+	u, err := newUploader(ctx, url, h, c)
+	if err != nil {
+		return nil, err
+	}
+	return c.wrap(u), nil
 }
 
-// Sends an S3 multipart upload initiation request.
-// See http://docs.amazonwebservices.com/AmazonS3/latest/dev/mpuoverview.html.
-// This initial request returns an UploadId that we use to identify
-// subsequent PUT requests.
-func newUploader(url string, h http.Header, c *Config) (u *uploader, err error) {
-	u = new(uploader)
-	u.s3 = *c.Service
-	u.url = url
-	u.keys = *c.Keys
-	u.client = c.Client
-	if u.client == nil {
-		u.client = http.DefaultClient
-	}
-	u.bufsz = minPartSize
-	r, err := http.NewRequest("POST", url+"?uploads", nil)
+// CreateSized is like Create, but for an object whose total size is
+// known in advance. Every part except the last has the same size,
+// the smallest that keeps the upload within S3's limit of 10,000
+// parts, so the object's multipart ETag is determined by size alone.
+// Writing more than size bytes may exceed that limit.
+func CreateSized(url string, size int64, h http.Header, c *Config) (io.WriteCloser, error) {
+	return CreateSizedWithOptions(url, size, &ObjectOptions{Extra: h}, c)
+}
+
+// CreateSizedWithOptions is like CreateSized, but takes the
+// settings of the new object as typed options.
+func CreateSizedWithOptions(url string, size int64, o *ObjectOptions, c *Config) (io.WriteCloser, error) {
+	h, err := o.Header()
 	if err != nil {
 		return nil, err
 	}
-	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-	for k := range h {
-		for _, v := range h[k] {
-			r.Header.Add(k, v)
-		}
+	if c == nil {
+		c = DefaultConfig
 	}
-	u.s3.Sign(r, u.keys)
-	resp, err := u.client.Do(r)
+	u, err := newSizedUploader(url, size, h, c)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, newRespError(resp)
+	return c.wrap(u), nil
+}
+
+// newSizedUploader returns an Uploader whose part size suits
+// an object of the given size, as for CreateSized.
+func newSizedUploader(url string, size int64, h http.Header, c *Config) (*Uploader, error) {
+	n, err := partSize(size)
+	if err != nil {
+		return nil, err
 	}
-	err = xml.NewDecoder(resp.Body).Decode(u)
+	u, err := newUploader(context.Background(), url, h, c)
 	if err != nil {
 		return nil, err
 	}
+	u.bufsz = n
+	u.fixed = true
+	return u, nil
+}
+
+// partSize returns the part size to use for an object of the given
+// total size.
+func partSize(size int64) (int64, error) {
+	if size < 0 || size > maxObjSize {
+		return 0, fmt.Errorf("s3util: invalid object size %d", size)
+	}
+	n := (size + maxNPart - 1) / maxNPart
+	if n < minPartSize {
+		n = minPartSize
+	}
+	if n > maxPartSize {
+		return 0, fmt.Errorf("s3util: object size %d needs parts larger than %d bytes", size, int64(maxPartSize))
+	}
+	return n, nil
+}
+
+// config returns a copy of c that signs with u's service and keys.
+func (u *Uploader) config(c *Config) *Config {
+	cc := *c
+	cc.Service = &u.s3
+	cc.Keys = &u.keys
+	return &cc
+}
+
+func newUploader(ctx context.Context, url string, h http.Header, c *Config) (u *Uploader, err error) {
+	u = new(Uploader)
+	u.ctx, u.cancel = context.WithCancelCause(ctx)
+	u.s3 = *c.Service
+	u.url = url
+	u.keys = *c.Keys
+	u.c = u.config(c)
+	u.pool = c.BufferPool
+	u.bufsz = minPartSize
+	u.h = c.objectHeader(h)
+	if c.Compressor != nil {
+		u.h.Set("Content-Encoding", c.Compressor.Encoding())
+	}
+	if c.DetectContentType && u.h.Get("Content-Type") == "" {
+		if t := typeByExtension(url); t != "" {
+			u.h.Set("Content-Type", t)
+		} else {
+			u.sniff = make([]byte, 0, sniffLen)
+		}
+	}
+	if u.sniff == nil {
+		if err := u.initiate(); err != nil {
+			u.cancel(nil)
+			return nil, err
+		}
+	}
+	u.start(c)
+	return u, nil
+}
+
+// start starts u's workers and sets up its compressor
+// and hashes, as configured in c.
+func (u *Uploader) start(c *Config) {
 	u.ch = make(chan *part)
 	for i := 0; i < concurrency; i++ {
 		go u.worker()
 	}
-	return u, nil
+	if c.Compressor != nil {
+		u.zw = c.Compressor.NewWriter((*partWriter)(u))
+	}
+	if len(c.Hashes) > 0 {
+		u.hashes = make(map[string]hash.Hash)
+		for name, f := range c.Hashes {
+			u.hashes[name] = f()
+		}
+	}
+}
+
+// Sends an S3 multipart upload initiation request.
+// See http://docs.amazonwebservices.com/AmazonS3/latest/dev/mpuoverview.html.
+// This initial request returns an UploadId that we use to identify
+// subsequent PUT requests.
+func (u *Uploader) initiate() error {
+	if u.sniff != nil {
+		u.h.Set("Content-Type", http.DetectContentType(u.sniff))
+		u.sniff = nil
+	}
+	r, err := http.NewRequestWithContext(u.ctx, "POST", u.url+"?uploads", nil)
+	if err != nil {
+		return err
+	}
+	r.Header = u.h
+	resp, err := u.c.do(r)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	err = u.c.decodeXML(resp.Body, u)
+	if err != nil {
+		return err
+	}
+	u.started = true
+	return nil
 }
 
-func (u *uploader) Write(p []byte) (n int, err error) {
+func (u *Uploader) Write(p []byte) (n int, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
 	if u.closed {
-		return 0, syscall.EINVAL
+		if u.closeErr == ErrAborted {
+			return 0, ErrAborted
+		}
+		return 0, &os.PathError{Op: "write", Path: u.url, Err: os.ErrClosed}
+	}
+	if u.ctx.Err() != nil {
+		return 0, context.Cause(u.ctx)
+	}
+	if err := u.getErr(); err != nil {
+		return 0, err
+	}
+	if u.sniff != nil {
+		m := sniffLen - len(u.sniff)
+		if m > len(p) {
+			m = len(p)
+		}
+		u.sniff = append(u.sniff, p[:m]...)
 	}
-	if u.err != nil {
-		return 0, u.err
+	if u.zw != nil {
+		n, err = u.zw.Write(p)
+	} else {
+		n, err = u.write(p)
 	}
+	for _, h := range u.hashes {
+		h.Write(p[:n])
+	}
+	return n, err
+}
+
+// partWriter writes data, already compressed if necessary,
+// to the uploader's part buffers.
+type partWriter Uploader
+
+func (w *partWriter) Write(p []byte) (int, error) {
+	u := (*Uploader)(w)
+	if err := u.getErr(); err != nil {
+		return 0, err
+	}
+	return u.write(p)
+}
+
+func (u *Uploader) write(p []byte) (n int, err error) {
 	for n < len(p) {
 		if cap(u.buf) == 0 {
-			u.buf = make([]byte, int(u.bufsz))
-			// Increase part size (1.001x).
-			// This lets us reach the max object size (5TiB) while
-			// still doing minimal buffering for small objects.
-			u.bufsz = min(u.bufsz+u.bufsz/1000, maxPartSize)
+			u.buf = u.pool.get(u.bufsz)
+			if !u.fixed {
+				// Increase part size (1.001x).
+				// This lets us reach the max object size (5TiB) while
+				// still doing minimal buffering for small objects.
+				u.bufsz = min(u.bufsz+u.bufsz/1000, maxPartSize)
+			}
 		}
 		r := copy(u.buf[u.off:], p[n:])
 		u.off += r
 		n += r
 		if u.off == len(u.buf) {
-			u.flush()
+			if err := u.flush(); err != nil {
+				return n, err
+			}
 		}
 	}
 	return n, nil
 }
 
-func (u *uploader) flush() {
+func (u *Uploader) flush() error {
+	if !u.started {
+		if err := u.initiate(); err != nil {
+			u.setErr(err)
+			u.pool.put(u.buf)
+			u.buf, u.off = nil, 0
+			return err
+		}
+	}
 	u.wg.Add(1)
 	u.part++
-	p := &part{bytes.NewReader(u.buf[:u.off]), int64(u.off), u.part, ""}
+	p := &part{bytes.NewReader(u.buf[:u.off]), int64(u.off), u.buf, u.part, ""}
+	u.size += p.len
 	u.xml.Part = append(u.xml.Part, p)
-	u.ch <- p
 	u.buf, u.off = nil, 0
+	select {
+	case u.ch <- p:
+	case <-u.ctx.Done():
+		u.pool.put(p.buf)
+		p.r, p.buf = nil, nil
+		u.wg.Done()
+		return context.Cause(u.ctx)
+	}
+	return nil
 }
 
-func (u *uploader) worker() {
+func (u *Uploader) worker() {
 	for p := range u.ch {
 		u.retryUploadPart(p)
 	}
 }
 
 // Calls putPart up to nTry times to recover from transient errors.
-func (u *uploader) retryUploadPart(p *part) {
+func (u *Uploader) retryUploadPart(p *part) {
 	defer u.wg.Done()
-	defer func() { p.r = nil }() // free the large buffer
+	defer func() {
+		// free the large buffer
+		u.pool.put(p.buf)
+		p.r, p.buf = nil, nil
+	}()
 	var err error
-	for i := 0; i < nTry; i++ {
+	for i := 0; i < nTry && u.ctx.Err() == nil; i++ {
 		p.r.Seek(0, 0)
 		err = u.putPart(p)
 		if err == nil {
 			return
 		}
 	}
-	u.err = err
+	if u.ctx.Err() != nil {
+		err = context.Cause(u.ctx)
+	}
+	u.setErr(err)
+}
+
+// setErr records err as the reason the upload failed,
+// unless a failure has already been recorded.
+func (u *Uploader) setErr(err error) {
+	u.errMu.Lock()
+	defer u.errMu.Unlock()
+	if u.err == nil {
+		u.err = err
+	}
+}
+
+func (u *Uploader) getErr() error {
+	u.errMu.Lock()
+	defer u.errMu.Unlock()
+	return u.err
 }
 
 // Uploads part p, reading its contents from p.r.
 // Stores the ETag in p.ETag.
-func (u *uploader) putPart(p *part) error {
+func (u *Uploader) putPart(p *part) error {
 	v := url.Values{}
 	v.Set("partNumber", strconv.Itoa(p.PartNumber))
 	v.Set("uploadId", u.UploadId)
-	req, err := http.NewRequest("PUT", u.url+"?"+v.Encode(), p.r)
+	req, err := http.NewRequestWithContext(u.ctx, "PUT", u.url+"?"+v.Encode(), p.r)
 	if err != nil {
 		return err
 	}
 	req.ContentLength = p.len
-	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-	u.s3.Sign(req, u.keys)
-	resp, err := u.client.Do(req)
+	resp, err := u.c.do(req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer closeBody(resp.Body)
 	if resp.StatusCode != 200 {
 		return newRespError(resp)
 	}
-	s := resp.Header.Get("etag") // includes quote chars for some reason
-	if len(s) < 2 {
+	s := resp.Header.Get("etag")
+	etag := etagValue(s)
+	if etag == "" {
 		return fmt.Errorf("received invalid etag %q", s)
 	}
-	p.ETag = s[1 : len(s)-1]
+	u.errMu.Lock()
+	p.ETag = etag
+	u.errMu.Unlock()
 	return nil
 }
 
-func (u *uploader) Close() error {
-	if u.closed {
-		return syscall.EINVAL
+// copyObject adds the object at src, of the given size, to the
+// upload, copied on the server. An object too large to copy in
+// one request is copied in ranges of equal size, each a part.
+// If etag is not empty, the copies fail if src no longer has it.
+func (u *Uploader) copyObject(src, etag string, size int64) error {
+	if size <= maxCopySize {
+		return u.copyPart(src, etag, 0, -1)
+	}
+	n := (size + maxCopySize - 1) / maxCopySize
+	psize := (size + n - 1) / n
+	for off := int64(0); off < size; off += psize {
+		if err := u.copyPart(src, etag, off, min(off+psize, size)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPart adds a part to the upload, copied on the server
+// from the object at src. It must be called before any data
+// is written. If last is not negative, only bytes first through
+// last of src are copied. If etag is not empty, the copy fails
+// if src no longer has it.
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadUploadPartCopy.html.
+func (u *Uploader) copyPart(src, etag string, first, last int64) error {
+	su, err := url.Parse(src)
+	if err != nil {
+		return err
+	}
+	if !u.started {
+		if err := u.initiate(); err != nil {
+			return err
+		}
+	}
+	u.part++
+	v := url.Values{}
+	v.Set("partNumber", strconv.Itoa(u.part))
+	v.Set("uploadId", u.UploadId)
+	req, err := http.NewRequestWithContext(u.ctx, "PUT", u.url+"?"+v.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Copy-Source", u.s3.ObjectPath(su))
+	if last >= 0 {
+		req.Header.Set("X-Amz-Copy-Source-Range", fmt.Sprintf("bytes=%d-%d", first, last))
+	}
+	if etag != "" {
+		req.Header.Set("X-Amz-Copy-Source-If-Match", etag)
 	}
-	if cap(u.buf) > 0 {
-		u.flush()
+	resp, err := u.c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode != 200 {
+		return newRespError(resp)
+	}
+	var result struct{ ETag string } // CopyPartResult
+	if err := u.c.decodeXML(resp.Body, &result); err != nil {
+		return err
+	}
+	p := &part{PartNumber: u.part, ETag: etagValue(result.ETag)}
+	u.xml.Part = append(u.xml.Part, p)
+	return nil
+}
+
+// Close flushes any buffered data and completes the upload,
+// or aborts it if the upload has failed. Calling Close again
+// has no effect and returns the same error as the first call.
+func (u *Uploader) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.closed {
+		u.closeErr = u.close()
+	}
+	return u.closeErr
+}
+
+func (u *Uploader) close() error {
+	defer u.cancel(nil)
+	if u.zw != nil {
+		if err := u.zw.Close(); err != nil {
+			u.setErr(err)
+		}
+	}
+	if cap(u.buf) > 0 || !u.started {
+		if err := u.flush(); err != nil && !u.started {
+			close(u.ch)
+			u.closed = true
+			return err
+		}
 	}
 	u.wg.Wait()
 	close(u.ch)
 	u.closed = true
-	if u.err != nil {
+	if u.ctx.Err() != nil {
+		u.setErr(context.Cause(u.ctx))
+	}
+	if err := u.getErr(); err != nil {
 		u.abort()
-		return u.err
+		return err
 	}
 
+	if err := checkParts(u.xml.Part); err != nil {
+		u.abort()
+		return err
+	}
 	body, err := xml.Marshal(u.xml)
 	if err != nil {
 		return err
 	}
-	b := bytes.NewBuffer(body)
+	// S3 may report a failure to complete the upload in
+	// the body of a 200 response; transient ones are retried.
+	for i := 0; i < nTry; i++ {
+		err = u.complete(body)
+		if e, ok := err.(*xmlError); !ok || !e.transient() {
+			break
+		}
+	}
+	if err != nil {
+		// Don't leave the parts stored, and billed, on S3.
+		u.abort()
+		return err
+	}
+	return u.recordHashes()
+}
+
+// recordHashes stores the digests of the data written to u in
+// u.result and, by copying the object onto itself, in the object's
+// metadata, updating u.result to describe the copy. Objects too
+// large to copy in one request keep their metadata unchanged.
+func (u *Uploader) recordHashes() error {
+	if len(u.hashes) == 0 {
+		return nil
+	}
+	m := make(map[string]string)
+	for name, h := range u.hashes {
+		m[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	u.result.Digests = m
+	if u.size > maxCopySize {
+		return nil
+	}
+	h := u.h.Clone()
+	h.Del("X-Amz-Tagging") // tags are copied
+	for k, v := range u.h {
+		if strings.HasPrefix(k, "X-Amz-Server-Side-Encryption-Customer-") {
+			h["X-Amz-Copy-Source-"+k[len("X-Amz-"):]] = v
+		}
+	}
+	if err := SetMetadata(h, m); err != nil {
+		return err
+	}
+	h.Set("X-Amz-Metadata-Directive", "REPLACE")
+	res, err := copyObject(u.url, u.url, h, u.c)
+	if err != nil {
+		return err
+	}
+	u.result.ETag = res.ETag
+	u.result.VersionId = res.VersionId
+	return nil
+}
+
+// complete sends a request to complete the multipart upload,
+// with the given list of parts.
+// See http://docs.aws.amazon.com/AmazonS3/latest/API/mpUploadComplete.html.
+func (u *Uploader) complete(body []byte) error {
 	v := url.Values{}
 	v.Set("uploadId", u.UploadId)
-	req, err := http.NewRequest("POST", u.url+"?"+v.Encode(), b)
+	req, err := http.NewRequestWithContext(u.ctx, "POST", u.url+"?"+v.Encode(), bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-	u.s3.Sign(req, u.keys)
-	resp, err := u.client.Do(req)
+	if u.ifMatch != "" {
+		req.Header.Set("If-Match", u.ifMatch)
+	}
+	resp, err := u.c.do(req)
 	if err != nil {
 		return err
 	}
+	defer closeBody(resp.Body)
 	if resp.StatusCode != 200 {
 		return newRespError(resp)
 	}
-	resp.Body.Close()
+	// S3 may stream whitespace to keep the connection alive
+	// before sending the result, so read the whole body.
+	b, err := u.c.readXML(resp.Body)
+	if err != nil {
+		return err
+	}
+	res := new(Result)
+	if len(bytes.TrimSpace(b)) > 0 {
+		if err := u.c.decodeXML(bytes.NewReader(b), res); err != nil {
+			return err
+		}
+	}
+	res.ETag = etagValue(res.ETag)
+	res.VersionId = resp.Header.Get("X-Amz-Version-Id")
+	u.result = res
 	return nil
 }
 
-func (u *uploader) abort() {
-	// TODO(kr): devise a reasonable way to report an error here in addition
-	// to the error that caused the abort.
+// Result returns a description of the object created by u,
+// or nil if Close has not returned successfully.
+func (u *Uploader) Result() *Result {
+	return u.result
+}
+
+// checkParts reports an error naming any parts missing from a,
+// or lacking an ETag, that would make S3 reject the request to
+// complete the upload with a less helpful InvalidPart error.
+// Part numbers in a must be contiguous and start at 1.
+func checkParts(a []*part) error {
+	var missing, noETag []string
+	next := 1
+	for _, p := range a {
+		for ; next < p.PartNumber; next++ {
+			missing = append(missing, strconv.Itoa(next))
+		}
+		if p.PartNumber == next {
+			next++
+		}
+		if p.ETag == "" {
+			noETag = append(noETag, strconv.Itoa(p.PartNumber))
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		return fmt.Errorf("s3util: cannot complete upload: missing parts %s", strings.Join(missing, ", "))
+	case len(noETag) > 0:
+		return fmt.Errorf("s3util: cannot complete upload: no ETag for parts %s", strings.Join(noETag, ", "))
+	}
+	return nil
+}
+
+// Abort stops the upload and discards the data written so far.
+// It cancels any part uploads in progress and waits for them
+// to stop, aborts the multipart upload on S3, and releases the
+// uploader's buffers. Abort may be called from another goroutine,
+// for example on receipt of a signal; a Write in progress then
+// returns ErrAborted. After Abort, Write and Close return ErrAborted.
+//
+// Abort returns any error from the request to abort the upload.
+// It does nothing if the uploader has already been closed.
+func (u *Uploader) Abort() error {
+	u.cancel(ErrAborted)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return nil
+	}
+	u.closed = true
+	u.closeErr = ErrAborted
+	u.wg.Wait()
+	close(u.ch)
+	u.setErr(ErrAborted)
+	u.pool.put(u.buf)
+	u.buf, u.off, u.sniff = nil, 0, nil
+	return u.abort()
+}
+
+// abort aborts the multipart upload. It doesn't use u.ctx,
+// which may already be canceled.
+func (u *Uploader) abort() error {
+	// TODO(kr): devise a reasonable way for Close to report an error
+	// here in addition to the error that caused the abort.
+	if u.UploadId == "" {
+		return nil
+	}
 	v := url.Values{}
 	v.Set("uploadId", u.UploadId)
 	s := u.url + "?" + v.Encode()
 	req, err := http.NewRequest("DELETE", s, nil)
 	if err != nil {
-		return
+		return err
 	}
-	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
-	u.s3.Sign(req, u.keys)
-	resp, err := u.client.Do(req)
+	resp, err := u.c.do(req)
 	if err != nil {
-		return
+		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return
+	if resp.StatusCode != 200 && resp.StatusCode != 204 {
+		return newRespError(resp)
 	}
+	closeBody(resp.Body)
+	return nil
 }
 
 func min(a, b int64) int64 {
@@ -1,15 +1,24 @@
 package s3util
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 )
 
-func runUpload(t *testing.T, makeCloser func(io.Reader) io.ReadCloser) *uploader {
+func runUpload(t *testing.T, makeCloser func(io.Reader) io.ReadCloser) *Uploader {
 	c := *DefaultConfig
 	c.Client = &http.Client{
 		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
@@ -36,7 +45,7 @@ func runUpload(t *testing.T, makeCloser func(io.Reader) io.ReadCloser) *uploader
 			return resp, nil
 		}),
 	}
-	u, err := newUploader("https://s3.amazonaws.com/foo/bar", nil, &c)
+	u, err := newUploader(context.Background(), "https://s3.amazonaws.com/foo/bar", nil, &c)
 	if err != nil {
 		t.Fatal("unexpected err", err)
 	}
@@ -46,12 +55,18 @@ func runUpload(t *testing.T, makeCloser func(io.Reader) io.ReadCloser) *uploader
 		t.Fatal("unexpected err", err)
 	}
 	if n != size {
-		t.Fatal("wrote %d bytes want %d", n, size)
+		t.Fatalf("wrote %d bytes want %d", n, size)
 	}
 	err = u.Close()
 	if err != nil {
 		t.Fatal("unexpected err", err)
 	}
+	if err = u.Close(); err != nil {
+		t.Fatal("second Close err", err)
+	}
+	if _, err = u.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Write after Close err = %v want %v", err, os.ErrClosed)
+	}
 	return u
 }
 
@@ -69,7 +84,7 @@ func TestUploaderCloseRespBody(t *testing.T) {
 }
 
 // Used in TestUploaderFreesBuffers to force liveness.
-var DummyUploader *uploader
+var DummyUploader *Uploader
 
 func TestUploaderFreesBuffers(t *testing.T) {
 	var m0, m1 runtime.MemStats
@@ -136,7 +151,7 @@ func TestEmptyEtag(t *testing.T) {
 		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
 			resp := &http.Response{
 				StatusCode: 200,
-				Body: body,
+				Body:       body,
 				Header: http.Header{
 					"Etag": {""},
 				},
@@ -144,7 +159,7 @@ func TestEmptyEtag(t *testing.T) {
 			return resp, nil
 		}),
 	}
-	u, err := newUploader("https://s3.amazonaws.com/foo/bar", nil, &c)
+	u, err := newUploader(context.Background(), "https://s3.amazonaws.com/foo/bar", nil, &c)
 	if err != nil {
 		t.Fatal("unexpected err", err)
 	}
@@ -156,8 +171,473 @@ func TestEmptyEtag(t *testing.T) {
 	if n != minPartSize {
 		t.Fatalf("wrote %d bytes want %d", n, minPartSize)
 	}
-	err = u.Close()
-	if err == nil || err.Error() != `received invalid etag ""` {
-		t.Fatalf("expected err: %q", err)
+	for i := 0; i < 2; i++ {
+		err = u.Close()
+		if err == nil || err.Error() != `received invalid etag ""` {
+			t.Fatalf("Close #%d: expected err: %q", i+1, err)
+		}
+	}
+}
+
+func TestUploaderCompress(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		encoding string
+		parts    = make(map[string][]byte)
+	)
+	c := *DefaultConfig
+	c.Compressor = Gzip
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			switch q := req.URL.Query(); {
+			case req.Method == "PUT":
+				b, _ := ioutil.ReadAll(req.Body)
+				mu.Lock()
+				parts[q.Get("partNumber")] = b
+				mu.Unlock()
+			case req.Method == "POST" && q["uploads"] != nil:
+				encoding = req.Header.Get("Content-Encoding")
+				s = `<UploadId>foo</UploadId>`
+			}
+			resp := &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+				Header: http.Header{
+					"Etag": {`"foo"`},
+				},
+			}
+			return resp, nil
+		}),
+	}
+	u, err := newUploader(context.Background(), "https://s3.amazonaws.com/foo/bar", nil, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	const data = "hello, world\n"
+	for i := 0; i < 3; i++ {
+		if _, err := io.WriteString(u, data); err != nil {
+			t.Fatal("unexpected err", err)
+		}
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if encoding != "gzip" {
+		t.Errorf("Content-Encoding = %q want gzip", encoding)
+	}
+	if len(parts) != 1 {
+		t.Fatalf("uploaded %d parts want 1", len(parts))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(parts["1"]))
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if g, w := string(b), strings.Repeat(data, 3); g != w {
+		t.Errorf("uploaded %q want %q", g, w)
+	}
+}
+
+func TestUploaderCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	aborted := make(chan bool, 1)
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			switch q := req.URL.Query(); {
+			case req.Method == "PUT":
+				cancel()
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case req.Method == "DELETE" && q.Get("uploadId") == "foo":
+				aborted <- true
+			default:
+				t.Error("unexpected request", req.Method, req.URL)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+			}, nil
+		}),
+	}
+	w, err := CreateContext(ctx, "https://s3.amazonaws.com/foo/bar", nil, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	_, err = io.Copy(w, devZero) // stops when the upload is canceled
+	if err != context.Canceled {
+		t.Errorf("Write err = %v want %v", err, context.Canceled)
+	}
+	if err = w.Close(); err != context.Canceled {
+		t.Errorf("Close err = %v want %v", err, context.Canceled)
+	}
+	select {
+	case <-aborted:
+	default:
+		t.Error("upload not aborted")
+	}
+}
+
+func TestUploaderAbort(t *testing.T) {
+	putStarted := make(chan bool, 1)
+	aborted := make(chan bool, 1)
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			switch q := req.URL.Query(); {
+			case req.Method == "PUT":
+				select {
+				case putStarted <- true:
+				default:
+				}
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case req.Method == "DELETE" && q.Get("uploadId") == "foo":
+				aborted <- true
+				return &http.Response{
+					StatusCode: 204,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}, nil
+			default:
+				t.Error("unexpected request", req.Method, req.URL)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+			}, nil
+		}),
+	}
+	w, err := Create("https://s3.amazonaws.com/foo/bar", nil, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	u := w.(*Uploader)
+	errc := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(u, devZero)
+		errc <- err
+	}()
+	<-putStarted
+	if err := u.Abort(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if err := <-errc; err != ErrAborted {
+		t.Errorf("Write err = %v want %v", err, ErrAborted)
+	}
+	select {
+	case <-aborted:
+	default:
+		t.Error("upload not aborted")
+	}
+	if err := u.Close(); err != ErrAborted {
+		t.Errorf("Close err = %v want %v", err, ErrAborted)
+	}
+	if err := u.Abort(); err != nil {
+		t.Error("second Abort err", err)
+	}
+}
+
+func TestCheckParts(t *testing.T) {
+	mk := func(etags ...string) []*part {
+		var a []*part
+		for i, s := range etags {
+			if s != "-" {
+				a = append(a, &part{PartNumber: i + 1, ETag: s})
+			}
+		}
+		return a
+	}
+	for _, test := range []struct {
+		parts []*part
+		w     string
+	}{
+		{mk("a", "b", "c"), ""},
+		{mk("a", "-", "c", "-", "e"), "s3util: cannot complete upload: missing parts 2, 4"},
+		{mk("a", "", "c", ""), "s3util: cannot complete upload: no ETag for parts 2, 4"},
+		{mk("-", "b"), "s3util: cannot complete upload: missing parts 1"},
+	} {
+		var g string
+		if err := checkParts(test.parts); err != nil {
+			g = err.Error()
+		}
+		if g != test.w {
+			t.Errorf("checkParts = %q want %q", g, test.w)
+		}
+	}
+}
+
+func TestPartSize(t *testing.T) {
+	for _, test := range []struct {
+		size int64
+		w    int64
+	}{
+		{0, minPartSize},
+		{minPartSize * maxNPart, minPartSize},
+		{minPartSize*maxNPart + 1, minPartSize + 1},
+		{maxObjSize, maxObjSize/maxNPart + 1},
+		{-1, 0},
+		{maxObjSize + 1, 0},
+	} {
+		g, err := partSize(test.size)
+		if test.w == 0 {
+			if err == nil {
+				t.Errorf("partSize(%d) = %d want error", test.size, g)
+			}
+			continue
+		}
+		if err != nil || g != test.w {
+			t.Errorf("partSize(%d) = %d, %v want %d", test.size, g, err, test.w)
+		}
+	}
+}
+
+func TestUploaderCompleteErrorBody(t *testing.T) {
+	for _, test := range []struct {
+		code string
+		n    int // complete requests
+	}{
+		{"InternalError", nTry},
+		{"InvalidPart", 1}, // not retried
+	} {
+		var ncomplete, nabort int
+		c := *DefaultConfig
+		c.Client = &http.Client{
+			Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				var s string
+				switch q := req.URL.Query(); {
+				case req.Method == "POST" && q["uploads"] != nil:
+					s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+				case req.Method == "POST":
+					ncomplete++
+					// S3 sends whitespace to keep the connection alive.
+					s = "  \n <Error><Code>" + test.code + "</Code><Message>oops</Message></Error>"
+				case req.Method == "DELETE":
+					nabort++
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(strings.NewReader(s)),
+					Header:     http.Header{"Etag": {`"foo"`}},
+				}, nil
+			}),
+		}
+		w, err := Create("https://s3.amazonaws.com/foo/bar", nil, &c)
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		io.WriteString(w, "hello")
+		err = w.Close()
+		if err == nil || err.Error() != "s3 error "+test.code+": oops" {
+			t.Errorf("%s: err = %v", test.code, err)
+		}
+		if ncomplete != test.n {
+			t.Errorf("%s: tried to complete %d times want %d", test.code, ncomplete, test.n)
+		}
+		if nabort != 1 {
+			t.Errorf("%s: aborted %d times want 1", test.code, nabort)
+		}
+	}
+}
+
+func TestUploaderResult(t *testing.T) {
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			h := http.Header{"Etag": {`"foo"`}}
+			switch q := req.URL.Query(); {
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<UploadId>foo</UploadId>`
+			case req.Method == "POST":
+				s = "\n\n\n" + `<CompleteMultipartUploadResult>
+					<Location>https://b.s3.amazonaws.com/k</Location>
+					<Bucket>b</Bucket>
+					<Key>k</Key>
+					<ETag>"3858f62230ac3c915f300c664312c11f-9"</ETag>
+				</CompleteMultipartUploadResult>`
+				h.Set("X-Amz-Version-Id", "v1")
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+				Header:     h,
+			}, nil
+		}),
+	}
+	w, err := Create("https://b.s3.amazonaws.com/k", nil, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	u := w.(*Uploader)
+	if u.Result() != nil {
+		t.Errorf("Result before Close = %+v want nil", u.Result())
+	}
+	io.WriteString(w, "hello")
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	want := Result{
+		Location:  "https://b.s3.amazonaws.com/k",
+		Bucket:    "b",
+		Key:       "k",
+		ETag:      "3858f62230ac3c915f300c664312c11f-9",
+		VersionId: "v1",
+	}
+	if g := u.Result(); g == nil || !reflect.DeepEqual(*g, want) {
+		t.Errorf("Result = %+v want %+v", g, want)
+	}
+}
+
+func TestUploaderHashes(t *testing.T) {
+	const data = "hello, world"
+	const sum = "09ca7e4eaa6e8ae9c7d261167129184883644d07dfba7cbfbc4c8a2e08360d5b"
+	var copied bool
+	c := *DefaultConfig
+	c.Hashes = map[string]func() hash.Hash{"sha256": sha256.New}
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			h := http.Header{"Etag": {`"foo"`}, "X-Amz-Version-Id": {"v1"}}
+			switch q := req.URL.Query(); {
+			case req.Method == "PUT" && req.Header.Get("X-Amz-Copy-Source") != "":
+				copied = true
+				if g := req.Header.Get("X-Amz-Copy-Source"); g != "/foo/bar" {
+					t.Errorf("copy source = %q want /foo/bar", g)
+				}
+				if g := req.Header.Get("X-Amz-Meta-Sha256"); g != sum {
+					t.Errorf("X-Amz-Meta-Sha256 = %q want %q", g, sum)
+				}
+				if g := req.Header.Get("X-Amz-Metadata-Directive"); g != "REPLACE" {
+					t.Errorf("X-Amz-Metadata-Directive = %q want REPLACE", g)
+				}
+				if g := req.Header.Get("Content-Type"); g != "text/plain" {
+					t.Errorf("Content-Type = %q want text/plain", g)
+				}
+				s = `<CopyObjectResult><ETag>"copied"</ETag></CopyObjectResult>`
+				h.Set("X-Amz-Version-Id", "v2")
+			case req.Method == "PUT":
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case req.Method == "POST" && q["uploadId"] != nil:
+				s = `<CompleteMultipartUploadResult><ETag>"foo-1"</ETag></CompleteMultipartUploadResult>`
+			default:
+				t.Error("unexpected request", req.Method, req.URL)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     h,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+			}, nil
+		}),
+	}
+	h := http.Header{"Content-Type": {"text/plain"}}
+	w, err := Create("https://s3.amazonaws.com/foo/bar", h, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	io.WriteString(w, data)
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if !copied {
+		t.Error("digest not recorded in metadata")
+	}
+	res := w.(*Uploader).Result()
+	if g := res.Digests["sha256"]; g != sum {
+		t.Errorf("Digests[sha256] = %q want %q", g, sum)
+	}
+	// The result describes the copy, not the upload.
+	if res.ETag != "copied" || res.VersionId != "v2" {
+		t.Errorf("ETag, VersionId = %q, %q want copied, v2", res.ETag, res.VersionId)
+	}
+}
+
+// funcWriter is a WrapWriter layer that applies f to each write.
+type funcWriter struct {
+	io.WriteCloser
+	f func([]byte) []byte
+}
+
+func (w *funcWriter) Write(p []byte) (int, error) {
+	if _, err := w.WriteCloser.Write(w.f(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func TestWrapWriters(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got string
+	)
+	c := mirrorConfig(&mu, &got, false)
+	c.WrapWriters = []WrapWriter{
+		func(w io.WriteCloser) io.WriteCloser {
+			return &funcWriter{w, bytes.ToUpper}
+		},
+		func(w io.WriteCloser) io.WriteCloser {
+			return &funcWriter{w, func(p []byte) []byte { return append([]byte(">"), p...) }}
+		},
+	}
+	w, err := Create("https://b.s3.amazonaws.com/k", nil, c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if _, ok := w.(*Uploader); ok {
+		t.Error("writer is not wrapped")
+	}
+	if AsUploader(w) == nil {
+		t.Error("AsUploader of wrapped writer = nil")
+	}
+	io.WriteString(w, "ab")
+	io.WriteString(w, "c")
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if got != ">AB>C" {
+		t.Errorf("uploaded %q want >AB>C", got)
+	}
+}
+
+func TestUnquotedEtag(t *testing.T) {
+	var complete string
+	c := *DefaultConfig
+	c.Client = &http.Client{
+		Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			var s string
+			switch q := req.URL.Query(); {
+			case req.Method == "POST" && q["uploads"] != nil:
+				s = `<InitiateMultipartUploadResult><UploadId>foo</UploadId></InitiateMultipartUploadResult>`
+			case req.Method == "POST":
+				b, _ := ioutil.ReadAll(req.Body)
+				complete = string(b)
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(s)),
+				Header:     http.Header{"Etag": {"abcdef"}},
+			}, nil
+		}),
+	}
+	w, err := Create("https://b.s3.amazonaws.com/k", nil, &c)
+	if err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	io.WriteString(w, "hello")
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected err", err)
+	}
+	if !strings.Contains(complete, "<ETag>abcdef</ETag>") {
+		t.Errorf("complete body = %s, want ETag abcdef", complete)
 	}
 }
@@ -0,0 +1,90 @@
+package s3util
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// ErrXMLTooLarge is returned when an XML response exceeds
+// the Config's MaxXMLBytes or MaxXMLElements.
+var ErrXMLTooLarge = errors.New("s3util: XML response too large")
+
+const (
+	defaultMaxXMLBytes    = 32 << 20
+	defaultMaxXMLElements = 1 << 18
+
+	// maxErrorBody is the most of an error response's body
+	// kept for its error message.
+	maxErrorBody = 64 << 10
+)
+
+func (c *Config) maxXMLBytes() int64 {
+	if c != nil && c.MaxXMLBytes > 0 {
+		return c.MaxXMLBytes
+	}
+	return defaultMaxXMLBytes
+}
+
+func (c *Config) maxXMLElements() int {
+	if c != nil && c.MaxXMLElements > 0 {
+		return c.MaxXMLElements
+	}
+	return defaultMaxXMLElements
+}
+
+// decodeXML decodes the XML document read from r into v,
+// within c's limits.
+func (c *Config) decodeXML(r io.Reader, v interface{}) error {
+	return c.xmlDecoder(r).Decode(v)
+}
+
+// xmlDecoder returns a decoder for the XML document read
+// from r, within c's limits.
+func (c *Config) xmlDecoder(r io.Reader) *xml.Decoder {
+	lr := &limitReader{r: r, n: c.maxXMLBytes()}
+	t := &limitTokens{d: xml.NewDecoder(lr), n: c.maxXMLElements()}
+	return xml.NewTokenDecoder(t)
+}
+
+// readXML reads an XML response body from r, up to c's
+// MaxXMLBytes.
+func (c *Config) readXML(r io.Reader) ([]byte, error) {
+	return io.ReadAll(&limitReader{r: r, n: c.maxXMLBytes()})
+}
+
+// limitReader reads from r and fails with ErrXMLTooLarge
+// once more than n bytes have been read.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return 0, ErrXMLTooLarge
+	}
+	return n, err
+}
+
+// limitTokens passes on the raw tokens of d, failing with
+// ErrXMLTooLarge after n start elements.
+type limitTokens struct {
+	d *xml.Decoder
+	n int
+}
+
+func (t *limitTokens) Token() (xml.Token, error) {
+	tok, err := t.d.RawToken()
+	if _, ok := tok.(xml.StartElement); ok {
+		if t.n--; t.n < 0 {
+			return nil, ErrXMLTooLarge
+		}
+	}
+	return tok, err
+}
@@ -0,0 +1,54 @@
+package s3util
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeXMLLimits(t *testing.T) {
+	const doc = "<R><A>1</A><A>2</A><A>3</A></R>"
+	var v struct{ A []int }
+	cases := []struct {
+		c   *Config
+		err error
+	}{
+		{nil, nil},
+		{&Config{MaxXMLBytes: int64(len(doc))}, nil},
+		{&Config{MaxXMLBytes: int64(len(doc)) - 1}, ErrXMLTooLarge},
+		{&Config{MaxXMLElements: 4}, nil},
+		{&Config{MaxXMLElements: 3}, ErrXMLTooLarge},
+	}
+	for _, test := range cases {
+		v.A = nil
+		err := test.c.decodeXML(strings.NewReader(doc), &v)
+		if err != test.err {
+			t.Errorf("decodeXML with %+v = %v want %v", test.c, err, test.err)
+		}
+		if err == nil && len(v.A) != 3 {
+			t.Errorf("decoded %v want [1 2 3]", v.A)
+		}
+	}
+}
+
+func TestReaddirXMLLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<ListBucketResult>")
+		for i := 0; i < 100; i++ {
+			io.WriteString(w, "<Contents><Key>k</Key></Contents>")
+		}
+		io.WriteString(w, "</ListBucketResult>")
+	}))
+	defer ts.Close()
+	c := *DefaultConfig
+	c.MaxXMLElements = 50
+	f, err := NewFile(ts.URL+"/", &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Readdir(0); err != ErrXMLTooLarge {
+		t.Errorf("Readdir err = %v want ErrXMLTooLarge", err)
+	}
+}
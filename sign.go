@@ -5,24 +5,34 @@ package s3
 // http://docs.amazonwebservices.com/AmazonS3/2006-03-01/dev/RESTAuthentication.html.
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/base64"
-	"io"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
-	"sort"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var signParams = map[string]bool{
+	"accelerate":                   true,
 	"acl":                          true,
 	"delete":                       true,
+	"encryption":                   true,
 	"lifecycle":                    true,
 	"location":                     true,
 	"logging":                      true,
 	"notification":                 true,
 	"partNumber":                   true,
 	"policy":                       true,
+	"publicAccessBlock":            true,
 	"requestPayment":               true,
 	"response-cache-control":       true,
 	"response-content-disposition": true,
@@ -31,6 +41,7 @@ var signParams = map[string]bool{
 	"response-content-type":        true,
 	"response-expires":             true,
 	"restore":                      true,
+	"tagging":                      true,
 	"torrent":                      true,
 	"uploadId":                     true,
 	"uploads":                      true,
@@ -52,6 +63,47 @@ type Keys struct {
 	// See http://docs.aws.amazon.com/AmazonS3/latest/dev/MakingRequests.html#TypesofSecurityCredentials
 }
 
+// String returns a description of k with the secret key and
+// security token masked, so that keys printed with %v or %s,
+// for instance in a log message, don't leak.
+func (k Keys) String() string {
+	return fmt.Sprintf("{AccessKey:%s SecretKey:%s SecurityToken:%s}",
+		k.AccessKey, redact(k.SecretKey), redact(k.SecurityToken))
+}
+
+// GoString is like String, for the %#v verb.
+func (k Keys) GoString() string {
+	return fmt.Sprintf("s3.Keys{AccessKey:%q, SecretKey:%q, SecurityToken:%q}",
+		k.AccessKey, redact(k.SecretKey), redact(k.SecurityToken))
+}
+
+// RedactHeader returns a copy of h with the values of
+// fields that carry credentials, Authorization and
+// X-Amz-Security-Token, masked. Use it before logging
+// or otherwise exposing a signed request's header.
+func RedactHeader(h http.Header) http.Header {
+	rh := make(http.Header, len(h))
+	for k, vs := range h {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "X-Amz-Security-Token":
+			masked := make([]string, len(vs))
+			for i, v := range vs {
+				masked[i] = redact(v)
+			}
+			vs = masked
+		}
+		rh[k] = vs
+	}
+	return rh
+}
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
 // IdentityBucket returns subdomain.
 // It is designed to be used with S3-compatible services that
 // treat the entire subdomain as the bucket name, for example
@@ -60,12 +112,34 @@ func IdentityBucket(subdomain string) string {
 	return subdomain
 }
 
-// AmazonBucket returns everything up to the last '.' in subdomain.
+// AmazonBucket returns everything before the last label in
+// subdomain that names an S3 endpoint, "s3" or "s3-*", or, if there
+// is no such label, everything up to the last '.'.
 // It is designed to be used with the Amazon service.
-//   "johnsmith.s3"           becomes "johnsmith"
-//   "johnsmith.s3-eu-west-1" becomes "johnsmith"
-//   "www.example.com.s3"     becomes "www.example.com"
+//   "johnsmith.s3"                                 becomes "johnsmith"
+//   "johnsmith.s3-eu-west-1"                       becomes "johnsmith"
+//   "johnsmith.s3.dualstack.us-east-1"             becomes "johnsmith"
+//   "www.example.com.s3"                           becomes "www.example.com"
+//   "myap-123456789012.s3-accesspoint.us-west-2"   becomes "myap-123456789012"
+//   "myol-123456789012.s3-object-lambda.us-west-2" becomes "myol-123456789012"
+// Access point aliases, such as "myap-abc123-s3alias.s3", are
+// bucket names and need no special treatment.
+//
+// Only the extraction of the name is supported for access point
+// and Object Lambda hosts: they accept only Signature Version 4,
+// so the requests signed by this package are refused by them.
 func AmazonBucket(subdomain string) string {
+	for i := len(subdomain); i > 0; {
+		j := strings.LastIndex(subdomain[:i], ".")
+		if j == -1 {
+			break
+		}
+		label := subdomain[j+1 : i]
+		if label == "s3" || strings.HasPrefix(label, "s3-") {
+			return subdomain[:j]
+		}
+		i = j
+	}
 	if i := strings.LastIndex(subdomain, "."); i != -1 {
 		return subdomain[:i]
 	}
@@ -82,6 +156,15 @@ func Sign(r *http.Request, k Keys) {
 	DefaultService.Sign(r, k)
 }
 
+// Presign adds query string authentication parameters to r.URL
+// for the given S3 keys, so that the URL can be used without
+// an Authorization header until expires.
+//
+// This function is a wrapper around DefaultService.Presign.
+func Presign(r *http.Request, k Keys, expires time.Time) {
+	DefaultService.Presign(r, k, expires)
+}
+
 // Service represents an S3-compatible service.
 type Service struct {
 	// Domain is the service's root domain. It is used to extract
@@ -92,6 +175,27 @@ type Service struct {
 	// Bucket derives the bucket name from a subdomain.
 	// If nil, AmazonBucket is used.
 	Bucket func(subdomain string) string
+
+	// Endpoint, if set, is a template for the base URL of a
+	// bucket, for services whose host names Domain and Bucket
+	// can't describe. The placeholders {bucket} and {region} are
+	// replaced with the bucket name and region, as in
+	// "https://{bucket}.{region}.example.com/" or, for a gateway
+	// with buckets in the path, "https://gw.example.com/{bucket}/".
+	// It is used by BucketURL and, when Bucket is nil, to find
+	// the bucket in the host of a request to sign.
+	Endpoint string
+
+	// Audit, if not nil, is called by Sign and Presign with a copy
+	// of each request they sign, the string to sign, and the
+	// resulting base64 signature, so that what is authorized can be
+	// logged and signature mismatches, for instance with a proxy
+	// that rewrites requests, can be diagnosed. The copy's
+	// credentials are redacted, as by RedactHeader: its
+	// Authorization header, security token, and presigned
+	// signature. A security token is also masked in the string to
+	// sign. The secret key is not passed.
+	Audit func(r *http.Request, stringToSign, signature string)
 }
 
 // Sign signs an HTTP request with the given S3 keys for use on service s.
@@ -99,45 +203,221 @@ func (s *Service) Sign(r *http.Request, k Keys) {
 	if k.SecurityToken != "" {
 		r.Header.Set("X-Amz-Security-Token", k.SecurityToken)
 	}
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	buf.Reset()
+	s.writeSigData(buf, r)
 	h := hmac.New(sha1.New, []byte(k.SecretKey))
-	s.writeSigData(h, r)
-	sig := make([]byte, base64.StdEncoding.EncodedLen(h.Size()))
-	base64.StdEncoding.Encode(sig, h.Sum(nil))
-	r.Header.Set("Authorization", "AWS "+k.AccessKey+":"+string(sig))
-}
-
-func (s *Service) writeSigData(w io.Writer, r *http.Request) {
-	w.Write([]byte(r.Method))
-	w.Write([]byte{'\n'})
-	w.Write([]byte(r.Header.Get("content-md5")))
-	w.Write([]byte{'\n'})
-	w.Write([]byte(r.Header.Get("content-type")))
-	w.Write([]byte{'\n'})
+	h.Write(buf.Bytes())
+	var stringToSign string
+	if s.Audit != nil {
+		stringToSign = buf.String()
+	}
+	var sum [sha1.Size]byte
+	var sig [28]byte // base64.StdEncoding.EncodedLen(sha1.Size)
+	base64.StdEncoding.Encode(sig[:], h.Sum(sum[:0]))
+	buf.Reset()
+	buf.WriteString("AWS ")
+	buf.WriteString(k.AccessKey)
+	buf.WriteByte(':')
+	buf.Write(sig[:])
+	r.Header.Set("Authorization", buf.String())
+	if s.Audit != nil {
+		s.audit(r, stringToSign, string(sig[:]), k)
+	}
+}
+
+// audit passes a copy of r with its credentials redacted,
+// stringToSign with the security token of k masked, and sig
+// to s.Audit.
+func (s *Service) audit(r *http.Request, stringToSign, sig string, k Keys) {
+	if t := k.SecurityToken; t != "" {
+		stringToSign = strings.ReplaceAll(stringToSign, t, redact(t))
+	}
+	r = r.Clone(r.Context())
+	r.Header = RedactHeader(r.Header)
+	q := r.URL.Query()
+	masked := false
+	for _, name := range []string{"Signature", "x-amz-security-token"} {
+		if v := q.Get(name); v != "" {
+			q.Set(name, redact(v))
+			masked = true
+		}
+	}
+	if masked {
+		r.URL.RawQuery = q.Encode()
+	}
+	s.Audit(r, stringToSign, sig)
+}
+
+// bufPool holds buffers for building strings to sign.
+var bufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Presign adds query string authentication parameters to r.URL
+// for the given S3 keys, for use on service s until expires.
+// See
+// http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#RESTAuthenticationQueryStringAuth.
+func (s *Service) Presign(r *http.Request, k Keys, expires time.Time) {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	h := r.Header
+	if k.SecurityToken != "" {
+		h = make(http.Header)
+		for name, vs := range r.Header {
+			h[name] = vs
+		}
+		h.Set("X-Amz-Security-Token", k.SecurityToken)
+	}
+	var buf bytes.Buffer
+	s.writeStringToSign(&buf, r, h, exp)
+	m := hmac.New(sha1.New, []byte(k.SecretKey))
+	m.Write(buf.Bytes())
+	sig := base64.StdEncoding.EncodeToString(m.Sum(nil))
+	q := r.URL.Query()
+	q.Set("AWSAccessKeyId", k.AccessKey)
+	q.Set("Expires", exp)
+	q.Set("Signature", sig)
+	if k.SecurityToken != "" {
+		q.Set("x-amz-security-token", k.SecurityToken)
+	}
+	r.URL.RawQuery = q.Encode()
+	if s.Audit != nil {
+		s.audit(r, buf.String(), sig, k)
+	}
+}
+
+// Errors returned by ValidatePresigned.
+var (
+	ErrNotPresigned = errors.New("s3: URL is not presigned")
+	ErrExpired      = errors.New("s3: presigned URL has expired")
+	ErrUnknownKey   = errors.New("s3: unknown access key")
+	ErrBadSignature = errors.New("s3: signature does not match")
+)
+
+// ValidatePresigned checks a URL made by Presign for a GET
+// request, as received from a client, before the request is
+// passed on to S3. It looks up the secret key for the URL's
+// access key with lookup, which returns "" if there is none.
+// It reports an error if the URL expired before now or if its
+// signature does not match.
+//
+// This function is a wrapper around DefaultService.ValidatePresigned.
+func ValidatePresigned(u *url.URL, lookup func(accessKey string) string, now time.Time) error {
+	return DefaultService.ValidatePresigned(u, lookup, now)
+}
+
+// ValidatePresigned checks a URL made by Presign, for service s,
+// for a GET request. See the ValidatePresigned function.
+func (s *Service) ValidatePresigned(u *url.URL, lookup func(accessKey string) string, now time.Time) error {
+	q := u.Query()
+	id, exp, sig := q.Get("AWSAccessKeyId"), q.Get("Expires"), q.Get("Signature")
+	if id == "" || exp == "" || sig == "" {
+		return ErrNotPresigned
+	}
+	t, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("s3: bad Expires %q", exp)
+	}
+	if now.Unix() > t {
+		return ErrExpired
+	}
+	secret := lookup(id)
+	if secret == "" {
+		return ErrUnknownKey
+	}
+	r := &http.Request{Method: "GET", URL: u, Host: u.Host, Header: make(http.Header)}
+	if tok := q.Get("x-amz-security-token"); tok != "" {
+		r.Header.Set("X-Amz-Security-Token", tok)
+	}
+	var buf bytes.Buffer
+	s.writeStringToSign(&buf, r, r.Header, exp)
+	m := hmac.New(sha1.New, []byte(secret))
+	m.Write(buf.Bytes())
+	want := base64.StdEncoding.EncodeToString(m.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func (s *Service) writeSigData(w *bytes.Buffer, r *http.Request) {
+	var date string
 	if _, ok := r.Header["X-Amz-Date"]; !ok {
-		w.Write([]byte(r.Header.Get("date")))
+		date = r.Header.Get("date")
 	}
-	w.Write([]byte{'\n'})
-	writeAmzHeaders(w, r)
+	s.writeStringToSign(w, r, r.Header, date)
+}
+
+// writeStringToSign writes the string to sign for r, using h for
+// its x-amz- headers and date in place of the Date header.
+func (s *Service) writeStringToSign(w *bytes.Buffer, r *http.Request, h http.Header, date string) {
+	w.WriteString(r.Method)
+	w.WriteByte('\n')
+	w.WriteString(r.Header.Get("content-md5"))
+	w.WriteByte('\n')
+	w.WriteString(r.Header.Get("content-type"))
+	w.WriteByte('\n')
+	w.WriteString(date)
+	w.WriteByte('\n')
+	writeAmzHeaders(w, h)
 	s.writeResource(w, r)
 }
 
-func (s *Service) writeResource(w io.Writer, r *http.Request) {
+func (s *Service) writeResource(w *bytes.Buffer, r *http.Request) {
 	s.writeVhostBucket(w, strings.ToLower(r.Host))
-	path := r.URL.RequestURI()
-	if r.URL.RawQuery != "" {
-		path = path[:len(path)-len(r.URL.RawQuery)-1]
-	}
-	w.Write([]byte(path))
+	w.WriteString(escapedPath(r.URL))
 	s.writeSubResource(w, r)
 }
 
-func (s *Service) writeVhostBucket(w io.Writer, host string) {
-	if i := strings.Index(host, ":"); i != -1 {
-		host = host[:i]
+// ObjectPath returns the path-style location, "/bucket/key",
+// of the object or bucket addressed by u on service s. The key
+// is escaped as in the URL.
+func (s *Service) ObjectPath(u *url.URL) string {
+	var buf bytes.Buffer
+	s.writeVhostBucket(&buf, strings.ToLower(u.Host))
+	buf.WriteString(escapedPath(u))
+	return buf.String()
+}
+
+// escapedPath returns the escaped path of u, as sent in the
+// request line, without any query.
+func escapedPath(u *url.URL) string {
+	if u.Opaque != "" {
+		// As in URL.RequestURI; a leading "//host" is not part of the path.
+		p := u.Opaque
+		if strings.HasPrefix(p, "//") {
+			if i := strings.Index(p[2:], "/"); i != -1 {
+				return p[2+i:]
+			}
+			return "/"
+		}
+		return p
+	}
+	p := u.EscapedPath()
+	if p == "" {
+		p = "/"
 	}
+	return p
+}
+
+func (s *Service) writeVhostBucket(w *bytes.Buffer, host string) {
+	host = hostname(host)
 
-	if host == s.Domain {
+	if s.Endpoint != "" && s.Bucket == nil {
+		if bucket, ok := s.endpointBucket(host); ok {
+			if bucket != "" {
+				w.WriteByte('/')
+				w.WriteString(bucket)
+			}
+			return
+		}
+	}
+
+	if host == s.Domain || net.ParseIP(host) != nil {
 		// no vhost - do nothing
+		// (an IP address is never a bucket name, so the
+		// bucket is in the path, as with local servers)
 	} else if strings.HasSuffix(host, "."+s.Domain) {
 		// vhost - bucket may be in prefix
 		b := s.Bucket
@@ -147,54 +427,196 @@ func (s *Service) writeVhostBucket(w io.Writer, host string) {
 		bucket := b(host[:len(host)-len(s.Domain)-1])
 
 		if bucket != "" {
-			w.Write([]byte{'/'})
-			w.Write([]byte(bucket))
+			w.WriteByte('/')
+			w.WriteString(bucket)
 		}
 	} else {
 		// cname - bucket is host
-		w.Write([]byte{'/'})
-		w.Write([]byte(host))
+		w.WriteByte('/')
+		w.WriteString(host)
 	}
 }
 
-func (s *Service) writeSubResource(w io.Writer, r *http.Request) {
-	var a []string
-	for k, vs := range r.URL.Query() {
-		if signParams[k] {
-			for _, v := range vs {
-				if v == "" {
-					a = append(a, k)
-				} else {
-					a = append(a, k+"="+v)
-				}
-			}
+// hostname returns host without any port, and without
+// the brackets around an IPv6 address, as in "[::1]:9000".
+func hostname(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if i := strings.IndexByte(host, ']'); i != -1 {
+			return host[1:i]
 		}
+		return host
 	}
-	sort.Strings(a)
+	if i := strings.IndexByte(host, ':'); i != -1 {
+		host = host[:i]
+	}
+	return host
+}
+
+// writeSubResource writes the query parameters of r that
+// identify a subresource or override response headers.
+// As the S3 documentation requires, their values are written
+// decoded, not URL-encoded as they appear in the query string;
+// for example, response-content-disposition=attachment%3B%20filename%3D%22a%20b.txt%22
+// is written as response-content-disposition=attachment; filename="a b.txt".
+// It parses r.URL.RawQuery itself, rather than allocating a map
+// with r.URL.Query.
+//
+// A parameter that appears more than once is written once, with its
+// values joined by commas in the order they appear in the query, as
+// for header fields. Parameters are sorted by name.
+func (s *Service) writeSubResource(w *bytes.Buffer, r *http.Request) {
+	a := make([]param, 0, 8)
+	for q := r.URL.RawQuery; q != ""; {
+		var kv string
+		kv, q, _ = strings.Cut(q, "&")
+		k, v, _ := strings.Cut(kv, "=")
+		if strings.ContainsAny(k, "%+") {
+			k, _ = url.QueryUnescape(k)
+		}
+		if !signParams[k] {
+			continue
+		}
+		if strings.ContainsAny(v, "%+") {
+			v, _ = url.QueryUnescape(v)
+		}
+		a = append(a, param{k, v})
+	}
+	slices.SortStableFunc(a, func(x, y param) int {
+		return strings.Compare(x.k, y.k)
+	})
 	var p byte = '?'
-	for _, s := range a {
-		w.Write([]byte{p})
-		w.Write([]byte(s))
+	for i := 0; i < len(a); {
+		w.WriteByte(p)
+		w.WriteString(a[i].k)
+		j := i
+		for ; j < len(a) && a[j].k == a[i].k; j++ {
+		}
+		if hasValue(a[i:j]) {
+			w.WriteByte('=')
+			for n, x := range a[i:j] {
+				if n > 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(x.v)
+			}
+		}
 		p = '&'
+		i = j
 	}
 }
 
-func writeAmzHeaders(w io.Writer, r *http.Request) {
-	var keys []string
-	for k, _ := range r.Header {
-		if strings.HasPrefix(strings.ToLower(k), "x-amz-") {
+// param is a decoded query parameter.
+type param struct {
+	k, v string
+}
+
+func hasValue(a []param) bool {
+	for _, x := range a {
+		if x.v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAmzHeaders writes the canonicalized x-amz- headers in h:
+// names in lower case and sorted, the values of fields whose
+// names differ only in case combined, values separated by commas,
+// and whitespace around values trimmed and folded.
+// See http://docs.aws.amazon.com/AmazonS3/latest/dev/RESTAuthentication.html#RESTAuthenticationConstructingCanonicalizedAmzHeaders.
+func writeAmzHeaders(w *bytes.Buffer, h http.Header) {
+	p := keysPool.Get().(*[]string)
+	keys := (*p)[:0]
+	for k := range h {
+		if len(k) >= 6 && strings.EqualFold(k[:6], "x-amz-") {
 			keys = append(keys, k)
 		}
 	}
+	slices.SortFunc(keys, compareHeaderKeys)
+
+	for i := 0; i < len(keys); {
+		writeLower(w, keys[i])
+		w.WriteByte(':')
+		sep := false
+		j := i
+		for ; j < len(keys) && strings.EqualFold(keys[j], keys[i]); j++ {
+			for _, v := range h[keys[j]] {
+				if sep {
+					w.WriteByte(',')
+				}
+				writeHeaderValue(w, v)
+				sep = true
+			}
+		}
+		w.WriteByte('\n')
+		i = j
+	}
+
+	*p = keys[:0]
+	keysPool.Put(p)
+}
+
+// keysPool holds slices for sorting header names.
+var keysPool = sync.Pool{
+	New: func() interface{} { return new([]string) },
+}
+
+// compareHeaderKeys orders header names case-insensitively,
+// breaking ties by the names' exact bytes so that the order of
+// fields whose names differ only in case is deterministic.
+func compareHeaderKeys(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := lower(a[i]), lower(b[i])
+		if ca != cb {
+			return int(ca) - int(cb)
+		}
+	}
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// writeHeaderValue writes v with leading and trailing whitespace
+// removed and each run of folding whitespace (whitespace containing
+// a line break) replaced by a single space.
+func writeHeaderValue(w *bytes.Buffer, v string) {
+	v = strings.Trim(v, " \t\r\n")
+	for len(v) > 0 {
+		i := strings.IndexAny(v, " \t\r\n")
+		if i == -1 {
+			w.WriteString(v)
+			return
+		}
+		w.WriteString(v[:i])
+		j := i
+		for j < len(v) && isSpace(v[j]) {
+			j++
+		}
+		if ws := v[i:j]; strings.ContainsAny(ws, "\r\n") {
+			w.WriteByte(' ')
+		} else {
+			w.WriteString(ws)
+		}
+		v = v[j:]
+	}
+}
 
-	sort.Strings(keys)
-	var a []string
-	for _, k := range keys {
-		v := r.Header[k]
-		a = append(a, strings.ToLower(k)+":"+strings.Join(v, ","))
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		c += 'a' - 'A'
 	}
-	for _, h := range a {
-		w.Write([]byte(h))
-		w.Write([]byte{'\n'})
+	return c
+}
+
+// writeLower writes s to w in lower case,
+// without allocating a new string.
+func writeLower(w *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		w.WriteByte(lower(s[i]))
 	}
 }